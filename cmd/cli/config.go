@@ -4,8 +4,10 @@ import (
 	"log/slog"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+	"github.com/ytjohn/toolmin/pkg/engine"
 )
 
 // Config holds all configuration for the application
@@ -17,6 +19,18 @@ type Config struct {
 		Host string
 		Port int
 	}
+	Secrets struct {
+		Passphrase string
+		KeyFile    string
+	}
+	Scripts struct {
+		Timeout     time.Duration
+		MaxCommands int
+		MaxOutput   int
+		MaxValue    int
+		// RunRetention is how long run history is kept; zero keeps it forever
+		RunRetention time.Duration
+	}
 	Debug bool
 }
 
@@ -32,6 +46,13 @@ func initConfig() {
 	viper.SetDefault("database.path", "data/toolmin.db")
 	viper.SetDefault("server.host", "127.0.0.1")
 	viper.SetDefault("server.port", 8080)
+	viper.SetDefault("secrets.passphrase", "")
+	viper.SetDefault("secrets.keyfile", "")
+	viper.SetDefault("scripts.timeout", engine.DefaultLimits.Timeout)
+	viper.SetDefault("scripts.maxcommands", engine.DefaultLimits.MaxCommands)
+	viper.SetDefault("scripts.maxoutput", engine.DefaultLimits.MaxOutput)
+	viper.SetDefault("scripts.maxvalue", engine.DefaultLimits.MaxValue)
+	viper.SetDefault("scripts.runretention", 30*24*time.Hour)
 	viper.SetDefault("debug", false)
 
 	// Environment variables
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ytjohn/toolmin/pkg/appdb"
+)
+
+var runsLimit int64
+
+func init() {
+	rootCmd.AddCommand(scriptCmd)
+	scriptCmd.AddCommand(scriptRunsCmd)
+	scriptCmd.AddCommand(scriptHistoryCmd)
+	scriptCmd.AddCommand(scriptRollbackCmd)
+
+	scriptRunsCmd.Flags().Int64VarP(&runsLimit, "limit", "n", 20, "Number of runs to show")
+}
+
+var scriptCmd = &cobra.Command{
+	Use:   "script",
+	Short: "Script management commands",
+}
+
+var scriptRunsCmd = &cobra.Command{
+	Use:   "runs NAME",
+	Short: "List recent runs of a script",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		Log.Debug("opening database", "path", GlobalConfig.Database.Path)
+		db, err := sql.Open(sqliteDriver, GlobalConfig.Database.Path)
+		if err != nil {
+			Log.Error("failed to open database", "error", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		queries := appdb.New(db)
+		script := getScript(cmd, queries, args[0])
+
+		Log.Debug("listing script runs", "script", script.Name, "limit", runsLimit)
+		runs, err := queries.ListScriptRuns(cmd.Context(), appdb.ListScriptRunsParams{
+			ScriptID: script.ID,
+			Limit:    runsLimit,
+		})
+		if err != nil {
+			Log.Error("failed to list script runs", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%-20s %-10s %-14s %-20s %s\n", "STARTED", "DURATION", "STATUS", "USER", "ERROR")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, run := range runs {
+			user := "-"
+			if run.Username.Valid {
+				user = run.Username.String
+			}
+			errorLine, _, _ := strings.Cut(run.Error, "\n")
+			fmt.Printf("%-20s %-10s %-14s %-20s %s\n",
+				run.Started.Local().Format("2006-01-02 15:04:05"),
+				run.Finished.Sub(run.Started).Round(time.Millisecond),
+				run.Status,
+				user,
+				errorLine,
+			)
+		}
+		Log.Debug("listed script runs", "count", len(runs))
+	},
+}
+
+var scriptHistoryCmd = &cobra.Command{
+	Use:   "history NAME",
+	Short: "List the saved versions of a script",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		Log.Debug("opening database", "path", GlobalConfig.Database.Path)
+		db, err := sql.Open(sqliteDriver, GlobalConfig.Database.Path)
+		if err != nil {
+			Log.Error("failed to open database", "error", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		queries := appdb.New(db)
+		script := getScript(cmd, queries, args[0])
+
+		Log.Debug("listing script versions", "script", script.Name)
+		versions, err := queries.ListScriptVersions(cmd.Context(), script.ID)
+		if err != nil {
+			Log.Error("failed to list script versions", "error", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%-8s %-20s %-8s %-20s %s\n", "VERSION", "SAVED", "ACCESS", "USER", "LINES")
+		fmt.Println(strings.Repeat("-", 70))
+		found := false
+		for _, v := range versions {
+			user := "-"
+			if v.Username.Valid {
+				user = v.Username.String
+			}
+			// Mark the newest version matching what's saved now
+			current := ""
+			if !found && v.Content == script.Content && v.AccessLevel == script.AccessLevel {
+				current = " (current)"
+				found = true
+			}
+			fmt.Printf("%-8d %-20s %-8s %-20s %d%s\n",
+				v.Version,
+				v.Created.Local().Format("2006-01-02 15:04:05"),
+				v.AccessLevel,
+				user,
+				strings.Count(v.Content, "\n")+1,
+				current,
+			)
+		}
+		Log.Debug("listed script versions", "count", len(versions))
+	},
+}
+
+var scriptRollbackCmd = &cobra.Command{
+	Use:   "rollback NAME VERSION",
+	Short: "Restore an earlier version of a script",
+	Long:  "Restore an earlier version of a script. The restored content is saved as a new version, so the rollback can be undone.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			Log.Error("invalid version", "version", args[1])
+			os.Exit(1)
+		}
+
+		Log.Debug("opening database", "path", GlobalConfig.Database.Path)
+		db, err := sql.Open(sqliteDriver, GlobalConfig.Database.Path)
+		if err != nil {
+			Log.Error("failed to open database", "error", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		ctx := cmd.Context()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			Log.Error("failed to begin transaction", "error", err)
+			os.Exit(1)
+		}
+		defer tx.Rollback()
+		queries := appdb.New(db).WithTx(tx)
+
+		script := getScript(cmd, queries, args[0])
+		v, err := queries.GetScriptVersion(ctx, appdb.GetScriptVersionParams{ScriptID: script.ID, Version: version})
+		if errors.Is(err, sql.ErrNoRows) {
+			Log.Error("version not found", "script", script.Name, "version", version)
+			os.Exit(1)
+		}
+		if err != nil {
+			Log.Error("failed to get script version", "error", err)
+			os.Exit(1)
+		}
+
+		// Keep the current content if it was never versioned
+		if _, err := queries.SaveScriptVersion(ctx, script, sql.NullInt64{}); err != nil {
+			Log.Error("failed to save script version", "error", err)
+			os.Exit(1)
+		}
+		script, err = queries.UpdateScript(ctx, appdb.UpdateScriptParams{
+			Name:        script.Name,
+			Content:     v.Content,
+			AccessLevel: v.AccessLevel,
+		})
+		if err != nil {
+			Log.Error("failed to update script", "error", err)
+			os.Exit(1)
+		}
+		latest, err := queries.SaveScriptVersion(ctx, script, sql.NullInt64{})
+		if err != nil {
+			Log.Error("failed to save script version", "error", err)
+			os.Exit(1)
+		}
+		if err := tx.Commit(); err != nil {
+			Log.Error("failed to commit rollback", "error", err)
+			os.Exit(1)
+		}
+
+		Log.Info("script rolled back", "script", script.Name, "version", version)
+		fmt.Printf("Restored %s to version %d (saved as version %d)\n", script.Name, version, latest)
+	},
+}
+
+// getScript loads a script by name, exiting if it doesn't exist
+func getScript(cmd *cobra.Command, queries *appdb.Queries, name string) appdb.Script {
+	script, err := queries.GetScript(cmd.Context(), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		Log.Error("script not found", "name", name)
+		os.Exit(1)
+	}
+	if err != nil {
+		Log.Error("failed to get script", "error", err)
+		os.Exit(1)
+	}
+	return script
+}
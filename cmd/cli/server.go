@@ -7,6 +7,9 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	appsql "github.com/ytjohn/toolmin/pkg/appdb/sql"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/secrets"
 	"github.com/ytjohn/toolmin/pkg/server"
 )
 
@@ -16,6 +19,10 @@ func init() {
 	if err := viper.BindPFlag("server.webdir", serverCmd.Flags().Lookup("webdir")); err != nil {
 		panic(fmt.Sprintf("failed to bind webdir flag: %v", err))
 	}
+	serverCmd.Flags().String("secrets-key-file", "", "File containing the secrets master passphrase (or set TOOLMIN_SECRETS_PASSPHRASE)")
+	if err := viper.BindPFlag("secrets.keyfile", serverCmd.Flags().Lookup("secrets-key-file")); err != nil {
+		panic(fmt.Sprintf("failed to bind secrets-key-file flag: %v", err))
+	}
 }
 
 var serverCmd = &cobra.Command{
@@ -31,11 +38,31 @@ var serverCmd = &cobra.Command{
 		}
 		defer db.Close()
 
+		// Bring a database created by an older version up to date
+		if err := appsql.Migrate(db); err != nil {
+			Log.Error("failed to migrate database", "error", err)
+			os.Exit(1)
+		}
+
+		passphrase, err := secrets.LoadPassphrase(GlobalConfig.Secrets.Passphrase, viper.GetString("secrets.keyfile"))
+		if err != nil {
+			Log.Error("failed to load secrets passphrase", "error", err)
+			os.Exit(1)
+		}
+
 		config := &server.Config{
-			Host:          GlobalConfig.Server.Host,
-			Port:          GlobalConfig.Server.Port,
-			Debug:         GlobalConfig.Debug,
-			WebContentDir: viper.GetString("server.webdir"),
+			Host:              GlobalConfig.Server.Host,
+			Port:              GlobalConfig.Server.Port,
+			Debug:             GlobalConfig.Debug,
+			WebContentDir:     viper.GetString("server.webdir"),
+			SecretsPassphrase: passphrase,
+			ScriptLimits: engine.Limits{
+				Timeout:     GlobalConfig.Scripts.Timeout,
+				MaxCommands: GlobalConfig.Scripts.MaxCommands,
+				MaxOutput:   GlobalConfig.Scripts.MaxOutput,
+				MaxValue:    GlobalConfig.Scripts.MaxValue,
+			},
+			RunRetention: GlobalConfig.Scripts.RunRetention,
 		}
 
 		srv := server.New(config, Log, db)
@@ -1,7 +1,9 @@
 package appdb
 
 import (
+	"context"
 	"database/sql"
+	"strings"
 	"time"
 )
 
@@ -43,3 +45,38 @@ func BoolToNullBool(b bool) sql.NullBool {
 func TimeToNullTime(t time.Time) sql.NullTime {
 	return sql.NullTime{Time: t, Valid: true}
 }
+
+// IsUniqueViolation reports whether err is a SQLite UNIQUE constraint failure
+func IsUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// SaveScriptVersion records script's content and access level as its next
+// version, unless they match the latest version. It returns the number of
+// the latest version afterwards.
+func (q *Queries) SaveScriptVersion(ctx context.Context, script Script, userID sql.NullInt64) (int64, error) {
+	latest, err := q.GetLatestScriptVersion(ctx, script.ID)
+	if err != nil {
+		return 0, err
+	}
+	if latest > 0 {
+		v, err := q.GetScriptVersion(ctx, GetScriptVersionParams{ScriptID: script.ID, Version: latest})
+		if err != nil {
+			return 0, err
+		}
+		if v.Content == script.Content && v.AccessLevel == script.AccessLevel {
+			return latest, nil
+		}
+	}
+	v, err := q.CreateScriptVersion(ctx, CreateScriptVersionParams{
+		ScriptID:    script.ID,
+		Version:     latest + 1,
+		Content:     script.Content,
+		AccessLevel: script.AccessLevel,
+		UserID:      userID,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return v.Version, nil
+}
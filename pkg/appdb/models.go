@@ -9,6 +9,77 @@ import (
 	"time"
 )
 
+type AuditLog struct {
+	ID      int64     `json:"id"`
+	UserID  int64     `json:"user_id"`
+	Action  string    `json:"action"`
+	Target  string    `json:"target"`
+	Created time.Time `json:"created"`
+}
+
+type ExecCommand struct {
+	ID          int64     `json:"id"`
+	Path        string    `json:"path"`
+	ArgPattern  string    `json:"arg_pattern"`
+	Description string    `json:"description"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+type Kv struct {
+	ScriptID int64        `json:"script_id"`
+	Key      string       `json:"key"`
+	Value    string       `json:"value"`
+	Expires  sql.NullTime `json:"expires"`
+	Updated  time.Time    `json:"updated"`
+}
+
+type Library struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	Content     string    `json:"content"`
+	Description string    `json:"description"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+type MasterKey struct {
+	ID       int64     `json:"id"`
+	Salt     []byte    `json:"salt"`
+	Verifier []byte    `json:"verifier"`
+	Created  time.Time `json:"created"`
+}
+
+type Page struct {
+	ID      int64     `json:"id"`
+	Slug    string    `json:"slug"`
+	Title   string    `json:"title"`
+	Columns int64     `json:"columns"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+type PageSlot struct {
+	PageID    int64  `json:"page_id"`
+	Position  int64  `json:"position"`
+	Script    string `json:"script"`
+	HxTrigger string `json:"hx_trigger"`
+	Title     string `json:"title"`
+}
+
+type Schedule struct {
+	ID       int64        `json:"id"`
+	Name     string       `json:"name"`
+	ScriptID int64        `json:"script_id"`
+	Cron     string       `json:"cron"`
+	Vars     string       `json:"vars"`
+	Enabled  bool         `json:"enabled"`
+	LastTick sql.NullTime `json:"last_tick"`
+	Created  time.Time    `json:"created"`
+	Updated  time.Time    `json:"updated"`
+}
+
 type Script struct {
 	ID          int64     `json:"id"`
 	Name        string    `json:"name"`
@@ -18,6 +89,70 @@ type Script struct {
 	Updated     time.Time `json:"updated"`
 }
 
+type ScriptConcurrency struct {
+	ScriptID      int64 `json:"script_id"`
+	MaxConcurrent int64 `json:"max_concurrent"`
+	Queue         bool  `json:"queue"`
+	Coalesce      bool  `json:"coalesce"`
+}
+
+type ScriptLimit struct {
+	ScriptID       int64 `json:"script_id"`
+	TimeoutMs      int64 `json:"timeout_ms"`
+	MaxCommands    int64 `json:"max_commands"`
+	MaxOutputBytes int64 `json:"max_output_bytes"`
+}
+
+type ScriptParam struct {
+	ScriptID     int64          `json:"script_id"`
+	Position     int64          `json:"position"`
+	Name         string         `json:"name"`
+	Type         string         `json:"type"`
+	Required     bool           `json:"required"`
+	DefaultValue sql.NullString `json:"default_value"`
+	Pattern      string         `json:"pattern"`
+	EnumValues   string         `json:"enum_values"`
+	Description  string         `json:"description"`
+}
+
+type ScriptRun struct {
+	ID       int64         `json:"id"`
+	ScriptID int64         `json:"script_id"`
+	UserID   sql.NullInt64 `json:"user_id"`
+	Started  time.Time     `json:"started"`
+	Finished time.Time     `json:"finished"`
+	Status   string        `json:"status"`
+	Output   string        `json:"output"`
+	Error    string        `json:"error"`
+	Vars     string        `json:"vars"`
+	Source   string        `json:"source"`
+}
+
+type ScriptRunExec struct {
+	RunID      int64  `json:"run_id"`
+	Position   int64  `json:"position"`
+	Path       string `json:"path"`
+	Args       string `json:"args"`
+	ExitCode   int64  `json:"exit_code"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error"`
+}
+
+type ScriptSecret struct {
+	ScriptID  int64  `json:"script_id"`
+	SecretKey string `json:"secret_key"`
+}
+
+type ScriptVersion struct {
+	ID          int64         `json:"id"`
+	ScriptID    int64         `json:"script_id"`
+	Version     int64         `json:"version"`
+	Content     string        `json:"content"`
+	AccessLevel string        `json:"access_level"`
+	UserID      sql.NullInt64 `json:"user_id"`
+	Created     time.Time     `json:"created"`
+}
+
 type Secret struct {
 	ID      int64     `json:"id"`
 	Key     string    `json:"key"`
@@ -35,6 +170,14 @@ type SigningKey struct {
 	IsActive  bool      `json:"is_active"`
 }
 
+type Template struct {
+	ID      int64     `json:"id"`
+	Name    string    `json:"name"`
+	Content string    `json:"content"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
 type User struct {
 	ID        int64        `json:"id"`
 	Username  string       `json:"username"`
@@ -53,3 +196,16 @@ type Var struct {
 	Created time.Time `json:"created"`
 	Updated time.Time `json:"updated"`
 }
+
+type Webhook struct {
+	ID        string    `json:"id"`
+	ScriptID  int64     `json:"script_id"`
+	SecretKey string    `json:"secret_key"`
+	Created   time.Time `json:"created"`
+}
+
+type WebhookDelivery struct {
+	WebhookID string    `json:"webhook_id"`
+	Signature string    `json:"signature"`
+	Expires   time.Time `json:"expires"`
+}
@@ -7,39 +7,116 @@ package appdb
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 type Querier interface {
+	AddPageSlot(ctx context.Context, arg AddPageSlotParams) error
+	AddScriptParam(ctx context.Context, arg AddScriptParamParams) error
+	AddScriptRunExec(ctx context.Context, arg AddScriptRunExecParams) error
+	AddScriptSecret(ctx context.Context, arg AddScriptSecretParams) error
+	ClaimScheduleTick(ctx context.Context, arg ClaimScheduleTickParams) (int64, error)
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) error
+	CreateExecCommand(ctx context.Context, arg CreateExecCommandParams) (ExecCommand, error)
+	CreateMasterKey(ctx context.Context, arg CreateMasterKeyParams) (MasterKey, error)
+	CreateLibrary(ctx context.Context, arg CreateLibraryParams) (Library, error)
+	CreatePage(ctx context.Context, arg CreatePageParams) (Page, error)
+	CreateSchedule(ctx context.Context, arg CreateScheduleParams) (Schedule, error)
 	CreateScript(ctx context.Context, arg CreateScriptParams) (Script, error)
+	CreateScriptRun(ctx context.Context, arg CreateScriptRunParams) (ScriptRun, error)
+	CreateScriptVersion(ctx context.Context, arg CreateScriptVersionParams) (ScriptVersion, error)
 	CreateSecret(ctx context.Context, arg CreateSecretParams) (Secret, error)
 	CreateSigningKey(ctx context.Context, keyData string) (SigningKey, error)
+	CreateTemplate(ctx context.Context, arg CreateTemplateParams) (Template, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	CreateVar(ctx context.Context, arg CreateVarParams) (Var, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) error
+	DeleteExecCommand(ctx context.Context, id int64) error
+	DeleteExpiredKV(ctx context.Context, now sql.NullTime) (int64, error)
 	DeleteExpiredKeys(ctx context.Context, days sql.NullString) error
+	DeleteExpiredWebhookDeliveries(ctx context.Context, now time.Time) error
+	DeleteKV(ctx context.Context, arg DeleteKVParams) (int64, error)
+	DeleteKVIfExpired(ctx context.Context, arg DeleteKVIfExpiredParams) error
+	DeleteLibrary(ctx context.Context, arg DeleteLibraryParams) error
+	DeletePage(ctx context.Context, slug string) error
+	DeletePageSlots(ctx context.Context, pageID int64) error
+	DeleteSchedule(ctx context.Context, name string) error
+	DeleteSchedulesForScript(ctx context.Context, scriptID int64) error
 	DeleteScript(ctx context.Context, name string) error
+	DeleteScriptConcurrency(ctx context.Context, scriptID int64) error
+	DeleteScriptKV(ctx context.Context, scriptID int64) error
+	DeleteScriptLimits(ctx context.Context, scriptID int64) error
+	DeleteScriptParams(ctx context.Context, scriptID int64) error
+	DeleteScriptRunExecs(ctx context.Context, scriptID int64) error
+	DeleteScriptRunExecsBefore(ctx context.Context, started time.Time) error
+	DeleteScriptRuns(ctx context.Context, scriptID int64) error
+	DeleteScriptRunsBefore(ctx context.Context, started time.Time) (int64, error)
+	DeleteScriptSecrets(ctx context.Context, scriptID int64) error
+	DeleteScriptVersions(ctx context.Context, scriptID int64) error
+	DeleteScriptWebhook(ctx context.Context, scriptID int64) error
 	DeleteSecret(ctx context.Context, key string) error
+	DeleteTemplate(ctx context.Context, name string) error
 	DeleteUser(ctx context.Context, email string) error
 	DeleteVar(ctx context.Context, key string) error
 	GetActiveSigningKey(ctx context.Context) (SigningKey, error)
 	GetAllValidSigningKeys(ctx context.Context) ([]SigningKey, error)
+	GetExecCommand(ctx context.Context, id int64) (ExecCommand, error)
+	GetExecCommandByPath(ctx context.Context, path string) (ExecCommand, error)
+	GetKV(ctx context.Context, arg GetKVParams) (Kv, error)
+	GetLatestScriptVersion(ctx context.Context, scriptID int64) (int64, error)
+	GetLibrary(ctx context.Context, arg GetLibraryParams) (Library, error)
+	GetMasterKey(ctx context.Context) (MasterKey, error)
+	GetPage(ctx context.Context, slug string) (Page, error)
+	GetSchedule(ctx context.Context, name string) (GetScheduleRow, error)
 	GetScript(ctx context.Context, name string) (Script, error)
+	GetScriptConcurrency(ctx context.Context, scriptID int64) (ScriptConcurrency, error)
+	GetScriptLimits(ctx context.Context, scriptID int64) (ScriptLimit, error)
+	GetScriptVersion(ctx context.Context, arg GetScriptVersionParams) (ScriptVersion, error)
+	GetScriptWebhook(ctx context.Context, scriptID int64) (Webhook, error)
 	GetSecret(ctx context.Context, key string) (Secret, error)
+	GetTemplate(ctx context.Context, name string) (Template, error)
 	GetUser(ctx context.Context, id int64) (User, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
 	GetVar(ctx context.Context, key string) (Var, error)
+	GetWebhook(ctx context.Context, id string) (GetWebhookRow, error)
+	ListExecCommands(ctx context.Context) ([]ExecCommand, error)
+	ListKV(ctx context.Context, arg ListKVParams) ([]Kv, error)
+	ListLibraries(ctx context.Context) ([]Library, error)
+	ListLibraryVersions(ctx context.Context, name string) ([]Library, error)
+	ListPageSlots(ctx context.Context, pageID int64) ([]PageSlot, error)
+	ListPages(ctx context.Context) ([]Page, error)
+	ListSchedules(ctx context.Context) ([]ListSchedulesRow, error)
+	ListScriptParams(ctx context.Context, scriptID int64) ([]ScriptParam, error)
+	ListScriptRunExecs(ctx context.Context, runID int64) ([]ScriptRunExec, error)
+	ListScriptRuns(ctx context.Context, arg ListScriptRunsParams) ([]ListScriptRunsRow, error)
+	ListScriptSecrets(ctx context.Context, scriptID int64) ([]string, error)
+	ListScriptVersions(ctx context.Context, scriptID int64) ([]ListScriptVersionsRow, error)
 	ListScripts(ctx context.Context) ([]Script, error)
 	ListScriptsByAccess(ctx context.Context, accessLevel string) ([]Script, error)
+	ListScriptsExcludingAccess(ctx context.Context, accessLevel string) ([]Script, error)
+	ListSecretKeys(ctx context.Context) ([]ListSecretKeysRow, error)
 	ListSecrets(ctx context.Context) ([]Secret, error)
+	ListTemplates(ctx context.Context) ([]Template, error)
 	ListUsers(ctx context.Context) ([]User, error)
 	ListVars(ctx context.Context) ([]Var, error)
 	MarkExpiredKeysInactive(ctx context.Context) error
+	SetKV(ctx context.Context, arg SetKVParams) error
+	UpdateExecCommand(ctx context.Context, arg UpdateExecCommandParams) (ExecCommand, error)
+	UpdateLibrary(ctx context.Context, arg UpdateLibraryParams) (Library, error)
+	UpdatePage(ctx context.Context, arg UpdatePageParams) (Page, error)
+	UpdateSchedule(ctx context.Context, arg UpdateScheduleParams) (Schedule, error)
 	UpdateScript(ctx context.Context, arg UpdateScriptParams) (Script, error)
 	UpdateSecret(ctx context.Context, arg UpdateSecretParams) (Secret, error)
 	UpdateSigningKeyData(ctx context.Context, arg UpdateSigningKeyDataParams) error
+	UpdateTemplate(ctx context.Context, arg UpdateTemplateParams) (Template, error)
 	UpdateUserLastLogin(ctx context.Context, id int64) error
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
 	UpdateVar(ctx context.Context, arg UpdateVarParams) (Var, error)
+	UpsertScriptConcurrency(ctx context.Context, arg UpsertScriptConcurrencyParams) error
+	UpsertScriptLimits(ctx context.Context, arg UpsertScriptLimitsParams) error
+	UpsertVar(ctx context.Context, arg UpsertVarParams) (Var, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -7,8 +7,58 @@ package appdb
 
 import (
 	"context"
+	"database/sql"
 )
 
+const addScriptParam = `-- name: AddScriptParam :exec
+INSERT INTO script_params (
+    script_id, position, name, type, required, default_value, pattern, enum_values, description
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type AddScriptParamParams struct {
+	ScriptID     int64          `json:"script_id"`
+	Position     int64          `json:"position"`
+	Name         string         `json:"name"`
+	Type         string         `json:"type"`
+	Required     bool           `json:"required"`
+	DefaultValue sql.NullString `json:"default_value"`
+	Pattern      string         `json:"pattern"`
+	EnumValues   string         `json:"enum_values"`
+	Description  string         `json:"description"`
+}
+
+func (q *Queries) AddScriptParam(ctx context.Context, arg AddScriptParamParams) error {
+	_, err := q.db.ExecContext(ctx, addScriptParam,
+		arg.ScriptID,
+		arg.Position,
+		arg.Name,
+		arg.Type,
+		arg.Required,
+		arg.DefaultValue,
+		arg.Pattern,
+		arg.EnumValues,
+		arg.Description,
+	)
+	return err
+}
+
+const addScriptSecret = `-- name: AddScriptSecret :exec
+INSERT INTO script_secrets (
+    script_id, secret_key
+) VALUES (?, ?)
+`
+
+type AddScriptSecretParams struct {
+	ScriptID  int64  `json:"script_id"`
+	SecretKey string `json:"secret_key"`
+}
+
+func (q *Queries) AddScriptSecret(ctx context.Context, arg AddScriptSecretParams) error {
+	_, err := q.db.ExecContext(ctx, addScriptSecret, arg.ScriptID, arg.SecretKey)
+	return err
+}
+
 const createScript = `-- name: CreateScript :one
 INSERT INTO scripts (
     name, content, access_level
@@ -46,6 +96,46 @@ func (q *Queries) DeleteScript(ctx context.Context, name string) error {
 	return err
 }
 
+const deleteScriptConcurrency = `-- name: DeleteScriptConcurrency :exec
+DELETE FROM script_concurrency
+WHERE script_id = ?
+`
+
+func (q *Queries) DeleteScriptConcurrency(ctx context.Context, scriptID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteScriptConcurrency, scriptID)
+	return err
+}
+
+const deleteScriptLimits = `-- name: DeleteScriptLimits :exec
+DELETE FROM script_limits
+WHERE script_id = ?
+`
+
+func (q *Queries) DeleteScriptLimits(ctx context.Context, scriptID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteScriptLimits, scriptID)
+	return err
+}
+
+const deleteScriptParams = `-- name: DeleteScriptParams :exec
+DELETE FROM script_params
+WHERE script_id = ?
+`
+
+func (q *Queries) DeleteScriptParams(ctx context.Context, scriptID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteScriptParams, scriptID)
+	return err
+}
+
+const deleteScriptSecrets = `-- name: DeleteScriptSecrets :exec
+DELETE FROM script_secrets
+WHERE script_id = ?
+`
+
+func (q *Queries) DeleteScriptSecrets(ctx context.Context, scriptID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteScriptSecrets, scriptID)
+	return err
+}
+
 const getScript = `-- name: GetScript :one
 SELECT id, name, content, access_level, created, updated FROM scripts
 WHERE name = ? LIMIT 1
@@ -65,6 +155,108 @@ func (q *Queries) GetScript(ctx context.Context, name string) (Script, error) {
 	return i, err
 }
 
+const getScriptConcurrency = `-- name: GetScriptConcurrency :one
+SELECT script_id, max_concurrent, queue, coalesce FROM script_concurrency
+WHERE script_id = ?
+`
+
+func (q *Queries) GetScriptConcurrency(ctx context.Context, scriptID int64) (ScriptConcurrency, error) {
+	row := q.db.QueryRowContext(ctx, getScriptConcurrency, scriptID)
+	var i ScriptConcurrency
+	err := row.Scan(
+		&i.ScriptID,
+		&i.MaxConcurrent,
+		&i.Queue,
+		&i.Coalesce,
+	)
+	return i, err
+}
+
+const getScriptLimits = `-- name: GetScriptLimits :one
+SELECT script_id, timeout_ms, max_commands, max_output_bytes FROM script_limits
+WHERE script_id = ?
+`
+
+func (q *Queries) GetScriptLimits(ctx context.Context, scriptID int64) (ScriptLimit, error) {
+	row := q.db.QueryRowContext(ctx, getScriptLimits, scriptID)
+	var i ScriptLimit
+	err := row.Scan(
+		&i.ScriptID,
+		&i.TimeoutMs,
+		&i.MaxCommands,
+		&i.MaxOutputBytes,
+	)
+	return i, err
+}
+
+const listScriptParams = `-- name: ListScriptParams :many
+SELECT script_id, position, name, type, required, default_value, pattern, enum_values, description FROM script_params
+WHERE script_id = ?
+ORDER BY position
+`
+
+func (q *Queries) ListScriptParams(ctx context.Context, scriptID int64) ([]ScriptParam, error) {
+	rows, err := q.db.QueryContext(ctx, listScriptParams, scriptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ScriptParam{}
+	for rows.Next() {
+		var i ScriptParam
+		if err := rows.Scan(
+			&i.ScriptID,
+			&i.Position,
+			&i.Name,
+			&i.Type,
+			&i.Required,
+			&i.DefaultValue,
+			&i.Pattern,
+			&i.EnumValues,
+			&i.Description,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listScriptSecrets = `-- name: ListScriptSecrets :many
+SELECT secret_key FROM script_secrets
+WHERE script_id = ?
+ORDER BY secret_key
+`
+
+func (q *Queries) ListScriptSecrets(ctx context.Context, scriptID int64) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listScriptSecrets, scriptID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var secret_key string
+		if err := rows.Scan(&secret_key); err != nil {
+			return nil, err
+		}
+		items = append(items, secret_key)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listScripts = `-- name: ListScripts :many
 SELECT id, name, content, access_level, created, updated FROM scripts
 ORDER BY name
@@ -136,6 +328,42 @@ func (q *Queries) ListScriptsByAccess(ctx context.Context, accessLevel string) (
 	return items, nil
 }
 
+const listScriptsExcludingAccess = `-- name: ListScriptsExcludingAccess :many
+SELECT id, name, content, access_level, created, updated FROM scripts
+WHERE access_level != ?
+ORDER BY name
+`
+
+func (q *Queries) ListScriptsExcludingAccess(ctx context.Context, accessLevel string) ([]Script, error) {
+	rows, err := q.db.QueryContext(ctx, listScriptsExcludingAccess, accessLevel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Script{}
+	for rows.Next() {
+		var i Script
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Content,
+			&i.AccessLevel,
+			&i.Created,
+			&i.Updated,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateScript = `-- name: UpdateScript :one
 UPDATE scripts
 SET content = ?, 
@@ -164,3 +392,57 @@ func (q *Queries) UpdateScript(ctx context.Context, arg UpdateScriptParams) (Scr
 	)
 	return i, err
 }
+
+const upsertScriptConcurrency = `-- name: UpsertScriptConcurrency :exec
+INSERT INTO script_concurrency (
+    script_id, max_concurrent, queue, coalesce
+) VALUES (?, ?, ?, ?)
+ON CONFLICT (script_id) DO UPDATE
+SET max_concurrent = excluded.max_concurrent,
+    queue = excluded.queue,
+    coalesce = excluded.coalesce
+`
+
+type UpsertScriptConcurrencyParams struct {
+	ScriptID      int64 `json:"script_id"`
+	MaxConcurrent int64 `json:"max_concurrent"`
+	Queue         bool  `json:"queue"`
+	Coalesce      bool  `json:"coalesce"`
+}
+
+func (q *Queries) UpsertScriptConcurrency(ctx context.Context, arg UpsertScriptConcurrencyParams) error {
+	_, err := q.db.ExecContext(ctx, upsertScriptConcurrency,
+		arg.ScriptID,
+		arg.MaxConcurrent,
+		arg.Queue,
+		arg.Coalesce,
+	)
+	return err
+}
+
+const upsertScriptLimits = `-- name: UpsertScriptLimits :exec
+INSERT INTO script_limits (
+    script_id, timeout_ms, max_commands, max_output_bytes
+) VALUES (?, ?, ?, ?)
+ON CONFLICT (script_id) DO UPDATE
+SET timeout_ms = excluded.timeout_ms,
+    max_commands = excluded.max_commands,
+    max_output_bytes = excluded.max_output_bytes
+`
+
+type UpsertScriptLimitsParams struct {
+	ScriptID       int64 `json:"script_id"`
+	TimeoutMs      int64 `json:"timeout_ms"`
+	MaxCommands    int64 `json:"max_commands"`
+	MaxOutputBytes int64 `json:"max_output_bytes"`
+}
+
+func (q *Queries) UpsertScriptLimits(ctx context.Context, arg UpsertScriptLimitsParams) error {
+	_, err := q.db.ExecContext(ctx, upsertScriptLimits,
+		arg.ScriptID,
+		arg.TimeoutMs,
+		arg.MaxCommands,
+		arg.MaxOutputBytes,
+	)
+	return err
+}
@@ -7,8 +7,33 @@ package appdb
 
 import (
 	"context"
+	"time"
 )
 
+const createMasterKey = `-- name: CreateMasterKey :one
+INSERT INTO master_key (
+    id, salt, verifier
+) VALUES (1, ?, ?)
+RETURNING id, salt, verifier, created
+`
+
+type CreateMasterKeyParams struct {
+	Salt     []byte `json:"salt"`
+	Verifier []byte `json:"verifier"`
+}
+
+func (q *Queries) CreateMasterKey(ctx context.Context, arg CreateMasterKeyParams) (MasterKey, error) {
+	row := q.db.QueryRowContext(ctx, createMasterKey, arg.Salt, arg.Verifier)
+	var i MasterKey
+	err := row.Scan(
+		&i.ID,
+		&i.Salt,
+		&i.Verifier,
+		&i.Created,
+	)
+	return i, err
+}
+
 const createSecret = `-- name: CreateSecret :one
 INSERT INTO secrets (
     key, value
@@ -44,6 +69,23 @@ func (q *Queries) DeleteSecret(ctx context.Context, key string) error {
 	return err
 }
 
+const getMasterKey = `-- name: GetMasterKey :one
+SELECT id, salt, verifier, created FROM master_key
+WHERE id = 1
+`
+
+func (q *Queries) GetMasterKey(ctx context.Context) (MasterKey, error) {
+	row := q.db.QueryRowContext(ctx, getMasterKey)
+	var i MasterKey
+	err := row.Scan(
+		&i.ID,
+		&i.Salt,
+		&i.Verifier,
+		&i.Created,
+	)
+	return i, err
+}
+
 const getSecret = `-- name: GetSecret :one
 SELECT id, "key", value, created, updated FROM secrets
 WHERE key = ? LIMIT 1
@@ -62,6 +104,46 @@ func (q *Queries) GetSecret(ctx context.Context, key string) (Secret, error) {
 	return i, err
 }
 
+const listSecretKeys = `-- name: ListSecretKeys :many
+SELECT id, key, created, updated FROM secrets
+ORDER BY key
+`
+
+type ListSecretKeysRow struct {
+	ID      int64     `json:"id"`
+	Key     string    `json:"key"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+func (q *Queries) ListSecretKeys(ctx context.Context) ([]ListSecretKeysRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSecretKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSecretKeysRow{}
+	for rows.Next() {
+		var i ListSecretKeysRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Key,
+			&i.Created,
+			&i.Updated,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listSecrets = `-- name: ListSecrets :many
 SELECT id, "key", value, created, updated FROM secrets
 ORDER BY key
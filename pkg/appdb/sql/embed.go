@@ -9,14 +9,17 @@ import (
 //go:embed schema/schema.sql
 var schemaFS embed.FS
 
-// InitializeDatabase creates and initializes a new database with the schema
-func InitializeDatabase(dbPath string) error {
-	// Read schema
+// Schema returns the embedded database schema
+func Schema() (string, error) {
 	schema, err := schemaFS.ReadFile("schema/schema.sql")
 	if err != nil {
-		return fmt.Errorf("failed to read schema: %w", err)
+		return "", fmt.Errorf("failed to read schema: %w", err)
 	}
+	return string(schema), nil
+}
 
+// InitializeDatabase creates and initializes a new database with the schema
+func InitializeDatabase(dbPath string) error {
 	// Open database
 	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
@@ -24,10 +27,44 @@ func InitializeDatabase(dbPath string) error {
 	}
 	defer db.Close()
 
-	// Execute schema
-	if _, err := db.Exec(string(schema)); err != nil {
+	return Migrate(db)
+}
+
+// addedColumns are columns added to tables after they were first created.
+// CREATE TABLE IF NOT EXISTS leaves existing tables alone, so Migrate adds
+// these to tables that lack them.
+var addedColumns = []struct {
+	table, column, definition string
+}{
+	{"script_runs", "source", "TEXT NOT NULL DEFAULT 'request'"},
+}
+
+// Migrate brings a database up to the current schema. It creates missing
+// tables and indexes and adds missing columns, and is safe to run on a
+// database that is already up to date.
+func Migrate(db *sql.DB) error {
+	schema, err := Schema()
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(schema); err != nil {
 		return fmt.Errorf("failed to execute schema: %w", err)
 	}
 
+	for _, c := range addedColumns {
+		var n int
+		err := db.QueryRow(
+			"SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", c.table, c.column,
+		).Scan(&n)
+		if err != nil {
+			return fmt.Errorf("failed to inspect %s: %w", c.table, err)
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", c.table, c.column, err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,51 @@
+package sql_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	appsql "github.com/ytjohn/toolmin/pkg/appdb/sql"
+)
+
+func TestMigrateAddsColumns(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "old.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	// script_runs as it was first created, before it had a source
+	if _, err := db.Exec(`CREATE TABLE script_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		script_id INTEGER NOT NULL,
+		user_id INTEGER,
+		started TIMESTAMP NOT NULL,
+		finished TIMESTAMP NOT NULL,
+		status TEXT NOT NULL,
+		output TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		vars TEXT NOT NULL DEFAULT '{}'
+	)`); err != nil {
+		t.Fatalf("failed to create old table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO script_runs (script_id, started, finished, status) VALUES (1, 0, 0, 'ok')`); err != nil {
+		t.Fatalf("failed to insert run: %v", err)
+	}
+
+	// Migrating twice must be harmless
+	for range 2 {
+		if err := appsql.Migrate(db); err != nil {
+			t.Fatalf("Migrate error: %v", err)
+		}
+	}
+
+	var source string
+	if err := db.QueryRow(`SELECT source FROM script_runs`).Scan(&source); err != nil {
+		t.Fatalf("failed to read source: %v", err)
+	}
+	if source != "request" {
+		t.Errorf("source = %q, want request", source)
+	}
+}
@@ -0,0 +1,4 @@
+-- name: CreateAuditLog :exec
+INSERT INTO audit_log (
+    user_id, action, target
+) VALUES (?, ?, ?);
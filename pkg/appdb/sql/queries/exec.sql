@@ -0,0 +1,30 @@
+-- name: CreateExecCommand :one
+INSERT INTO exec_commands (
+    path, arg_pattern, description
+) VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: GetExecCommand :one
+SELECT * FROM exec_commands
+WHERE id = ? LIMIT 1;
+
+-- name: GetExecCommandByPath :one
+SELECT * FROM exec_commands
+WHERE path = ? LIMIT 1;
+
+-- name: ListExecCommands :many
+SELECT * FROM exec_commands
+ORDER BY path;
+
+-- name: UpdateExecCommand :one
+UPDATE exec_commands
+SET path = ?,
+    arg_pattern = ?,
+    description = ?,
+    updated = CURRENT_TIMESTAMP
+WHERE id = ?
+RETURNING *;
+
+-- name: DeleteExecCommand :exec
+DELETE FROM exec_commands
+WHERE id = ?;
@@ -0,0 +1,34 @@
+-- name: GetKV :one
+SELECT * FROM kv
+WHERE script_id = ? AND key = ? AND (expires IS NULL OR expires > sqlc.arg(now))
+LIMIT 1;
+
+-- name: ListKV :many
+SELECT * FROM kv
+WHERE script_id = ? AND (expires IS NULL OR expires > sqlc.arg(now))
+ORDER BY key;
+
+-- name: SetKV :exec
+INSERT INTO kv (
+    script_id, key, value, expires, updated
+) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (script_id, key) DO UPDATE
+SET value = excluded.value,
+    expires = excluded.expires,
+    updated = excluded.updated;
+
+-- name: DeleteKV :execrows
+DELETE FROM kv
+WHERE script_id = ? AND key = ?;
+
+-- name: DeleteKVIfExpired :exec
+DELETE FROM kv
+WHERE script_id = ? AND key = ? AND expires IS NOT NULL AND expires <= sqlc.arg(now);
+
+-- name: DeleteScriptKV :exec
+DELETE FROM kv
+WHERE script_id = ?;
+
+-- name: DeleteExpiredKV :execrows
+DELETE FROM kv
+WHERE expires IS NOT NULL AND expires <= sqlc.arg(now);
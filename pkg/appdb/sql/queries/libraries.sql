@@ -0,0 +1,30 @@
+-- name: CreateLibrary :one
+INSERT INTO libraries (
+    name, version, content, description
+) VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetLibrary :one
+SELECT * FROM libraries
+WHERE name = ? AND version = ? LIMIT 1;
+
+-- name: ListLibraries :many
+SELECT * FROM libraries
+ORDER BY name, version;
+
+-- name: ListLibraryVersions :many
+SELECT * FROM libraries
+WHERE name = ?
+ORDER BY version;
+
+-- name: UpdateLibrary :one
+UPDATE libraries
+SET content = ?,
+    description = ?,
+    updated = CURRENT_TIMESTAMP
+WHERE name = ? AND version = ?
+RETURNING *;
+
+-- name: DeleteLibrary :exec
+DELETE FROM libraries
+WHERE name = ? AND version = ?;
@@ -0,0 +1,39 @@
+-- name: CreatePage :one
+INSERT INTO pages (
+    slug, title, columns
+) VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: GetPage :one
+SELECT * FROM pages
+WHERE slug = ? LIMIT 1;
+
+-- name: ListPages :many
+SELECT * FROM pages
+ORDER BY slug;
+
+-- name: UpdatePage :one
+UPDATE pages
+SET title = ?,
+    columns = ?,
+    updated = CURRENT_TIMESTAMP
+WHERE slug = ?
+RETURNING *;
+
+-- name: DeletePage :exec
+DELETE FROM pages
+WHERE slug = ?;
+
+-- name: ListPageSlots :many
+SELECT * FROM page_slots
+WHERE page_id = ?
+ORDER BY position;
+
+-- name: AddPageSlot :exec
+INSERT INTO page_slots (
+    page_id, position, script, hx_trigger, title
+) VALUES (?, ?, ?, ?, ?);
+
+-- name: DeletePageSlots :exec
+DELETE FROM page_slots
+WHERE page_id = ?;
@@ -0,0 +1,39 @@
+-- name: CreateScriptRun :one
+INSERT INTO script_runs (
+    script_id, user_id, started, finished, status, output, error, vars, source
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: ListScriptRuns :many
+SELECT script_runs.*, users.username
+FROM script_runs
+LEFT JOIN users ON users.id = script_runs.user_id
+WHERE script_runs.script_id = ?
+ORDER BY script_runs.started DESC, script_runs.id DESC
+LIMIT ?;
+
+-- name: DeleteScriptRuns :exec
+DELETE FROM script_runs
+WHERE script_id = ?;
+
+-- name: DeleteScriptRunsBefore :execrows
+DELETE FROM script_runs
+WHERE started < ?;
+
+-- name: AddScriptRunExec :exec
+INSERT INTO script_run_execs (
+    run_id, position, path, args, exit_code, duration_ms, error
+) VALUES (?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListScriptRunExecs :many
+SELECT * FROM script_run_execs
+WHERE run_id = ?
+ORDER BY position;
+
+-- name: DeleteScriptRunExecs :exec
+DELETE FROM script_run_execs
+WHERE run_id IN (SELECT id FROM script_runs WHERE script_id = ?);
+
+-- name: DeleteScriptRunExecsBefore :exec
+DELETE FROM script_run_execs
+WHERE run_id IN (SELECT id FROM script_runs WHERE started < ?);
@@ -0,0 +1,40 @@
+-- name: CreateSchedule :one
+INSERT INTO schedules (
+    name, script_id, cron, vars, enabled
+) VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetSchedule :one
+SELECT schedules.*, scripts.name AS script_name
+FROM schedules
+JOIN scripts ON scripts.id = schedules.script_id
+WHERE schedules.name = ? LIMIT 1;
+
+-- name: ListSchedules :many
+SELECT schedules.*, scripts.name AS script_name
+FROM schedules
+JOIN scripts ON scripts.id = schedules.script_id
+ORDER BY schedules.name;
+
+-- name: UpdateSchedule :one
+UPDATE schedules
+SET script_id = ?,
+    cron = ?,
+    vars = ?,
+    enabled = ?,
+    updated = CURRENT_TIMESTAMP
+WHERE name = ?
+RETURNING *;
+
+-- name: ClaimScheduleTick :execrows
+UPDATE schedules
+SET last_tick = ?
+WHERE id = ? AND (last_tick IS NULL OR last_tick < ?);
+
+-- name: DeleteSchedule :exec
+DELETE FROM schedules
+WHERE name = ?;
+
+-- name: DeleteSchedulesForScript :exec
+DELETE FROM schedules
+WHERE script_id = ?;
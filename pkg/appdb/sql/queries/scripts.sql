@@ -17,6 +17,11 @@ SELECT * FROM scripts
 WHERE access_level = ?
 ORDER BY name;
 
+-- name: ListScriptsExcludingAccess :many
+SELECT * FROM scripts
+WHERE access_level != ?
+ORDER BY name;
+
 -- name: UpdateScript :one
 UPDATE scripts
 SET content = ?, 
@@ -27,4 +32,66 @@ RETURNING *;
 
 -- name: DeleteScript :exec
 DELETE FROM scripts
-WHERE name = ?; 
\ No newline at end of file
+WHERE name = ?; 
+
+-- name: ListScriptSecrets :many
+SELECT secret_key FROM script_secrets
+WHERE script_id = ?
+ORDER BY secret_key;
+
+-- name: AddScriptSecret :exec
+INSERT INTO script_secrets (
+    script_id, secret_key
+) VALUES (?, ?);
+
+-- name: DeleteScriptSecrets :exec
+DELETE FROM script_secrets
+WHERE script_id = ?;
+
+-- name: GetScriptLimits :one
+SELECT * FROM script_limits
+WHERE script_id = ?;
+
+-- name: UpsertScriptLimits :exec
+INSERT INTO script_limits (
+    script_id, timeout_ms, max_commands, max_output_bytes
+) VALUES (?, ?, ?, ?)
+ON CONFLICT (script_id) DO UPDATE
+SET timeout_ms = excluded.timeout_ms,
+    max_commands = excluded.max_commands,
+    max_output_bytes = excluded.max_output_bytes;
+
+-- name: DeleteScriptLimits :exec
+DELETE FROM script_limits
+WHERE script_id = ?;
+
+-- name: GetScriptConcurrency :one
+SELECT * FROM script_concurrency
+WHERE script_id = ?;
+
+-- name: UpsertScriptConcurrency :exec
+INSERT INTO script_concurrency (
+    script_id, max_concurrent, queue, coalesce
+) VALUES (?, ?, ?, ?)
+ON CONFLICT (script_id) DO UPDATE
+SET max_concurrent = excluded.max_concurrent,
+    queue = excluded.queue,
+    coalesce = excluded.coalesce;
+
+-- name: DeleteScriptConcurrency :exec
+DELETE FROM script_concurrency
+WHERE script_id = ?;
+
+-- name: ListScriptParams :many
+SELECT * FROM script_params
+WHERE script_id = ?
+ORDER BY position;
+
+-- name: AddScriptParam :exec
+INSERT INTO script_params (
+    script_id, position, name, type, required, default_value, pattern, enum_values, description
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: DeleteScriptParams :exec
+DELETE FROM script_params
+WHERE script_id = ?;
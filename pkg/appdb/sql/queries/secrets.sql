@@ -12,6 +12,10 @@ WHERE key = ? LIMIT 1;
 SELECT * FROM secrets
 ORDER BY key;
 
+-- name: ListSecretKeys :many
+SELECT id, key, created, updated FROM secrets
+ORDER BY key;
+
 -- name: UpdateSecret :one
 UPDATE secrets
 SET value = ?,
@@ -21,4 +25,14 @@ RETURNING *;
 
 -- name: DeleteSecret :exec
 DELETE FROM secrets
-WHERE key = ?; 
\ No newline at end of file
+WHERE key = ?; 
+
+-- name: GetMasterKey :one
+SELECT * FROM master_key
+WHERE id = 1;
+
+-- name: CreateMasterKey :one
+INSERT INTO master_key (
+    id, salt, verifier
+) VALUES (1, ?, ?)
+RETURNING *;
@@ -0,0 +1,24 @@
+-- name: CreateTemplate :one
+INSERT INTO templates (
+    name, content
+) VALUES (?, ?)
+RETURNING *;
+
+-- name: GetTemplate :one
+SELECT * FROM templates
+WHERE name = ? LIMIT 1;
+
+-- name: ListTemplates :many
+SELECT * FROM templates
+ORDER BY name;
+
+-- name: UpdateTemplate :one
+UPDATE templates
+SET content = ?,
+    updated = CURRENT_TIMESTAMP
+WHERE name = ?
+RETURNING *;
+
+-- name: DeleteTemplate :exec
+DELETE FROM templates
+WHERE name = ?;
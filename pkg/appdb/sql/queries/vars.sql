@@ -19,6 +19,15 @@ SET value = ?,
 WHERE key = ?
 RETURNING *;
 
+-- name: UpsertVar :one
+INSERT INTO vars (
+    key, value
+) VALUES (?, ?)
+ON CONFLICT (key) DO UPDATE
+SET value = excluded.value,
+    updated = CURRENT_TIMESTAMP
+RETURNING *;
+
 -- name: DeleteVar :exec
 DELETE FROM vars
 WHERE key = ?; 
\ No newline at end of file
@@ -0,0 +1,25 @@
+-- name: CreateScriptVersion :one
+INSERT INTO script_versions (
+    script_id, version, content, access_level, user_id
+) VALUES (?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetLatestScriptVersion :one
+SELECT CAST(COALESCE(MAX(version), 0) AS INTEGER) AS version
+FROM script_versions
+WHERE script_id = ?;
+
+-- name: GetScriptVersion :one
+SELECT * FROM script_versions
+WHERE script_id = ? AND version = ?;
+
+-- name: ListScriptVersions :many
+SELECT script_versions.*, users.username
+FROM script_versions
+LEFT JOIN users ON users.id = script_versions.user_id
+WHERE script_versions.script_id = ?
+ORDER BY script_versions.version DESC;
+
+-- name: DeleteScriptVersions :exec
+DELETE FROM script_versions
+WHERE script_id = ?;
@@ -0,0 +1,28 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (
+    id, script_id, secret_key
+) VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: GetWebhook :one
+SELECT webhooks.*, scripts.name AS script_name
+FROM webhooks
+JOIN scripts ON scripts.id = webhooks.script_id
+WHERE webhooks.id = ? LIMIT 1;
+
+-- name: GetScriptWebhook :one
+SELECT * FROM webhooks
+WHERE script_id = ? LIMIT 1;
+
+-- name: DeleteScriptWebhook :exec
+DELETE FROM webhooks
+WHERE script_id = ?;
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (
+    webhook_id, signature, expires
+) VALUES (?, ?, ?);
+
+-- name: DeleteExpiredWebhookDeliveries :exec
+DELETE FROM webhook_deliveries
+WHERE expires <= sqlc.arg(now);
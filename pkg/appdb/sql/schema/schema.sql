@@ -20,6 +20,191 @@ CREATE TABLE IF NOT EXISTS scripts (
     updated TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Secrets each script is allowed to read with "secret get"
+CREATE TABLE IF NOT EXISTS script_secrets (
+    script_id INTEGER NOT NULL REFERENCES scripts(id) ON DELETE CASCADE,
+    secret_key TEXT NOT NULL,
+    PRIMARY KEY (script_id, secret_key)
+);
+
+-- Per-script execution limits; zero means use the server default
+CREATE TABLE IF NOT EXISTS script_limits (
+    script_id INTEGER PRIMARY KEY REFERENCES scripts(id) ON DELETE CASCADE,
+    timeout_ms INTEGER NOT NULL DEFAULT 0,
+    max_commands INTEGER NOT NULL DEFAULT 0,
+    max_output_bytes INTEGER NOT NULL DEFAULT 0
+);
+
+-- How many runs of a script may happen at once; zero means no limit. Runs
+-- over the limit wait for a free slot if queue is set and are rejected
+-- otherwise. With coalesce, concurrent runs by the same user with the same
+-- inputs share one execution.
+CREATE TABLE IF NOT EXISTS script_concurrency (
+    script_id INTEGER PRIMARY KEY REFERENCES scripts(id) ON DELETE CASCADE,
+    max_concurrent INTEGER NOT NULL DEFAULT 0,
+    queue BOOLEAN NOT NULL DEFAULT 0,
+    coalesce BOOLEAN NOT NULL DEFAULT 0
+);
+
+-- Typed input parameters a script declares. Inputs are checked against
+-- them before the script runs.
+CREATE TABLE IF NOT EXISTS script_params (
+    script_id INTEGER NOT NULL REFERENCES scripts(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL DEFAULT 'string', -- string, int, bool, enum or secret
+    required BOOLEAN NOT NULL DEFAULT 0,
+    default_value TEXT,
+    pattern TEXT NOT NULL DEFAULT '', -- regular expression string values must match
+    enum_values TEXT NOT NULL DEFAULT '[]', -- JSON array of allowed enum values
+    description TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (script_id, position)
+);
+
+-- History of script runs. Output is truncated and secret inputs are masked.
+CREATE TABLE IF NOT EXISTS script_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    script_id INTEGER NOT NULL REFERENCES scripts(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL, -- NULL for anonymous and scheduled runs
+    started TIMESTAMP NOT NULL,
+    finished TIMESTAMP NOT NULL,
+    status TEXT NOT NULL, -- ok, error, timeout, command_limit, output_limit, memory_limit or cancelled
+    output TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    vars TEXT NOT NULL DEFAULT '{}', -- JSON object of input variables
+    source TEXT NOT NULL DEFAULT 'request' -- request, webhook or schedule
+);
+
+CREATE INDEX IF NOT EXISTS idx_script_runs_script_started
+ON script_runs(script_id, started);
+
+CREATE INDEX IF NOT EXISTS idx_script_runs_started
+ON script_runs(started);
+
+-- Every saved revision of a script, numbered from 1
+CREATE TABLE IF NOT EXISTS script_versions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    script_id INTEGER NOT NULL REFERENCES scripts(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    access_level TEXT NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (script_id, version)
+);
+
+-- Scripts run on a cron schedule. last_tick is the most recent scheduled
+-- minute a scheduler claimed, so each tick runs once.
+CREATE TABLE IF NOT EXISTS schedules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    script_id INTEGER NOT NULL REFERENCES scripts(id) ON DELETE CASCADE,
+    cron TEXT NOT NULL,
+    vars TEXT NOT NULL DEFAULT '{}', -- JSON object of input variables
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    last_tick TIMESTAMP,
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Webhooks let other systems trigger a script. id is the random
+-- identifier in the /hooks/{id} URL and secret_key names the secret that
+-- signs requests.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id TEXT PRIMARY KEY,
+    script_id INTEGER NOT NULL UNIQUE REFERENCES scripts(id) ON DELETE CASCADE,
+    secret_key TEXT NOT NULL,
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Signatures of accepted webhook requests, kept until they could no
+-- longer be accepted so that a captured request can't be replayed.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    webhook_id TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    signature TEXT NOT NULL,
+    expires TIMESTAMP NOT NULL,
+    PRIMARY KEY (webhook_id, signature)
+);
+
+-- Control panel pages, served at /{slug} when no page template file
+-- matches. Each page is a grid of tool slots.
+CREATE TABLE IF NOT EXISTS pages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug TEXT NOT NULL UNIQUE,
+    title TEXT NOT NULL,
+    columns INTEGER NOT NULL DEFAULT 2,
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A tool on a page: the script loaded into the slot and the htmx trigger
+-- that loads it
+CREATE TABLE IF NOT EXISTS page_slots (
+    page_id INTEGER NOT NULL REFERENCES pages(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    script TEXT NOT NULL,
+    hx_trigger TEXT NOT NULL DEFAULT 'load',
+    title TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (page_id, position)
+);
+
+-- Go html/template snippets scripts render with the render command. They
+-- take precedence over the snippets embedded in web/templates/tools.
+CREATE TABLE IF NOT EXISTS templates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    content TEXT NOT NULL,
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Tcl libraries scripts load with package require. Each version of a
+-- library is its own row.
+CREATE TABLE IF NOT EXISTS libraries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    content TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (name, version)
+);
+
+-- Binaries scripts may run with exec, by absolute path. Every argument
+-- must match arg_pattern in full; an empty pattern allows no arguments.
+CREATE TABLE IF NOT EXISTS exec_commands (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    path TEXT NOT NULL UNIQUE,
+    arg_pattern TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Commands a run executed with exec, in order
+CREATE TABLE IF NOT EXISTS script_run_execs (
+    run_id INTEGER NOT NULL REFERENCES script_runs(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    args TEXT NOT NULL DEFAULT '[]', -- JSON array of arguments, with secrets redacted
+    exit_code INTEGER NOT NULL, -- -1 if the command didn't finish
+    duration_ms INTEGER NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (run_id, position)
+);
+
+-- Values scripts keep between runs with the kv command, namespaced by
+-- script. Rows past their expiry are ignored and pruned.
+CREATE TABLE IF NOT EXISTS kv (
+    script_id INTEGER NOT NULL REFERENCES scripts(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    expires TIMESTAMP, -- NULL for keys that don't expire
+    updated TIMESTAMP NOT NULL,
+    PRIMARY KEY (script_id, key)
+);
+
 -- Variables table for storing configuration
 CREATE TABLE IF NOT EXISTS vars (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -38,6 +223,25 @@ CREATE TABLE IF NOT EXISTS secrets (
     updated TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 ); 
 
+-- Master key verifier for secrets encryption. Holds a single row with the
+-- Argon2id salt and a known value encrypted with the derived key.
+CREATE TABLE IF NOT EXISTS master_key (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    salt BLOB NOT NULL,
+    verifier BLOB NOT NULL,
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+
+-- Audit log of sensitive operations, such as revealing a secret
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    target TEXT NOT NULL,
+    created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
 
 -- Add signing keys table
 CREATE TABLE IF NOT EXISTS signing_keys (
@@ -121,3 +121,31 @@ func (q *Queries) UpdateVar(ctx context.Context, arg UpdateVarParams) (Var, erro
 	)
 	return i, err
 }
+
+const upsertVar = `-- name: UpsertVar :one
+INSERT INTO vars (
+    key, value
+) VALUES (?, ?)
+ON CONFLICT (key) DO UPDATE
+SET value = excluded.value,
+    updated = CURRENT_TIMESTAMP
+RETURNING id, "key", value, created, updated
+`
+
+type UpsertVarParams struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func (q *Queries) UpsertVar(ctx context.Context, arg UpsertVarParams) (Var, error) {
+	row := q.db.QueryRowContext(ctx, upsertVar, arg.Key, arg.Value)
+	var i Var
+	err := row.Scan(
+		&i.ID,
+		&i.Key,
+		&i.Value,
+		&i.Created,
+		&i.Updated,
+	)
+	return i, err
+}
@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// maxCachedScripts bounds the parsed scripts and libraries an engine keeps
+const maxCachedScripts = 1024
+
+// cacheKey identifies a stored script or library
+type cacheKey struct {
+	kind string
+	id   int64
+}
+
+type cachedScript struct {
+	updated time.Time
+	script  *tcl.Script
+}
+
+// parseCache keeps saved scripts and libraries parsed across runs. An entry
+// is used while the row's updated time is unchanged. Updated times only
+// have one-second resolution, so the source is compared too and an edit
+// made within the same second still takes effect.
+type parseCache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]cachedScript
+}
+
+// parse returns content parsed, from the cache if it is current
+func (c *parseCache) parse(key cacheKey, updated time.Time, content string) (*tcl.Script, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && e.updated.Equal(updated) && e.script.Source() == content {
+		return e.script, nil
+	}
+
+	s, err := tcl.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil || len(c.entries) >= maxCachedScripts {
+		c.entries = map[cacheKey]cachedScript{}
+	}
+	c.entries[key] = cachedScript{updated: updated, script: s}
+	return s, nil
+}
+
+func (c *parseCache) forget(key cacheKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// ForgetScript drops a script from the parse cache. Handlers that change
+// or delete a script call it so the cache doesn't hold stale entries.
+func (e *Engine) ForgetScript(id int64) {
+	e.parsed.forget(cacheKey{"script", id})
+}
+
+// ForgetLibrary drops a library version from the parse cache
+func (e *Engine) ForgetLibrary(id int64) {
+	e.parsed.forget(cacheKey{"library", id})
+}
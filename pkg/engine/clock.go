@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	// Scripts can name any zone, even on hosts without a zone database
+	_ "time/tzdata"
+
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// clockLayouts are the layout names clock accepts besides Go layouts
+var clockLayouts = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+// registerClock adds the clock command, which works with times as Unix
+// seconds:
+//
+//	clock seconds
+//	clock milliseconds
+//	clock format SECONDS ?-format LAYOUT? ?-timezone ZONE?
+//	clock scan TEXT ?-format LAYOUT? ?-timezone ZONE?
+//
+// LAYOUT is a Go time layout such as "2006-01-02 15:04", or the name of
+// one of Go's layout constants such as RFC1123. It defaults to RFC3339.
+// ZONE is an IANA zone name such as America/New_York and defaults to UTC;
+// scan only uses it when the text has no offset.
+func registerClock(interp *tcl.Interp) {
+	interp.Register("clock", cmdClock)
+}
+
+func cmdClock(i *tcl.Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", tcl.WrongArgs(args[0], "subcommand ?arg ...?")
+	}
+	switch args[1] {
+	case "seconds", "milliseconds":
+		if len(args) != 2 {
+			return "", tcl.WrongArgs("clock "+args[1], "")
+		}
+		if args[1] == "seconds" {
+			return strconv.FormatInt(time.Now().Unix(), 10), nil
+		}
+		return strconv.FormatInt(time.Now().UnixMilli(), 10), nil
+	case "format", "scan":
+		if len(args) < 3 {
+			return "", tcl.WrongArgs("clock "+args[1], "value ?-format layout? ?-timezone zone?")
+		}
+		layout, loc, err := clockOptions(args[3:])
+		if err != nil {
+			return "", err
+		}
+		if args[1] == "format" {
+			seconds, ok := tcl.ParseInt(args[2])
+			if !ok {
+				return "", fmt.Errorf("expected integer but got \"%s\"", args[2])
+			}
+			return time.Unix(seconds, 0).In(loc).Format(layout), nil
+		}
+		t, err := time.ParseInLocation(layout, args[2], loc)
+		if err != nil {
+			return "", fmt.Errorf("unable to scan \"%s\": %w", args[2], err)
+		}
+		return strconv.FormatInt(t.Unix(), 10), nil
+	}
+	return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be format, milliseconds, scan or seconds", args[1])
+}
+
+// clockOptions parses the -format and -timezone options
+func clockOptions(args []string) (string, *time.Location, error) {
+	layout, loc := time.RFC3339, time.UTC
+	for j := 0; j < len(args); j += 2 {
+		if args[j] != "-format" && args[j] != "-timezone" {
+			return "", nil, fmt.Errorf("bad option \"%s\": must be -format or -timezone", args[j])
+		}
+		if j+1 == len(args) {
+			return "", nil, fmt.Errorf("missing value for %s", args[j])
+		}
+		value := args[j+1]
+		if args[j] == "-format" {
+			if named, ok := clockLayouts[value]; ok {
+				value = named
+			}
+			layout = value
+			continue
+		}
+		l, err := time.LoadLocation(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("unknown time zone \"%s\"", value)
+		}
+		loc = l
+	}
+	return layout, loc, nil
+}
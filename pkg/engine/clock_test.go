@@ -0,0 +1,85 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+)
+
+func TestClockCommand(t *testing.T) {
+	runEvalCases(t, []evalCase{
+		{
+			name:    "format defaults to RFC3339 in UTC",
+			content: `clock format 1700000000`,
+			want:    `2023-11-14T22:13:20Z`,
+		},
+		{
+			name:    "format with layout",
+			content: `clock format 1700000000 -format {2006-01-02 15:04}`,
+			want:    `2023-11-14 22:13`,
+		},
+		{
+			name:    "format with named layout and zone",
+			content: `clock format 1700000000 -format RFC1123 -timezone America/New_York`,
+			want:    `Tue, 14 Nov 2023 17:13:20 EST`,
+		},
+		{
+			name:    "scan RFC3339 with offset",
+			content: `clock scan 2023-11-14T17:13:20-05:00`,
+			want:    `1700000000`,
+		},
+		{
+			name:    "scan with layout in zone",
+			content: `clock scan {2023-11-14 17:13:20} -format DateTime -timezone America/New_York`,
+			want:    `1700000000`,
+		},
+		{
+			name:    "round trip",
+			content: `clock scan [clock format 1234567890 -format RFC822Z -timezone Asia/Tokyo] -format RFC822Z`,
+			want:    `1234567860`,
+		},
+		{
+			name:    "scan mismatch",
+			content: `clock scan yesterday`,
+			wantErr: `unable to scan "yesterday"`,
+		},
+		{
+			name:    "unknown zone",
+			content: `clock format 0 -timezone Mars/Olympus`,
+			wantErr: `unknown time zone "Mars/Olympus"`,
+		},
+		{
+			name:    "bad seconds",
+			content: `clock format soon`,
+			wantErr: `expected integer but got "soon"`,
+		},
+		{
+			name:    "bad option",
+			content: `clock format 0 -locale fr`,
+			wantErr: `bad option "-locale"`,
+		},
+	})
+}
+
+func TestClockNow(t *testing.T) {
+	eng := engine.New(nil, nil, nil)
+	before := time.Now()
+	result, err := eng.Run(context.Background(), appdb.Script{Name: "now", Content: `list [clock seconds] [clock milliseconds]`}, nil)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	var seconds, millis int64
+	if _, err := fmt.Sscan(result.Value, &seconds, &millis); err != nil {
+		t.Fatalf("value %q: %v", result.Value, err)
+	}
+	if seconds < before.Unix() || seconds > time.Now().Unix() {
+		t.Errorf("seconds = %d, want about %d", seconds, before.Unix())
+	}
+	if millis/1000 < before.Unix() || millis > time.Now().UnixMilli() {
+		t.Errorf("milliseconds = %d, want about %d", millis, before.UnixMilli())
+	}
+}
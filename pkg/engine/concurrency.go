@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+)
+
+// ErrBusy is returned when a script is already running as many times as
+// its concurrency limit allows and doesn't queue excess runs
+var ErrBusy = errors.New("script is running too many times at once")
+
+// concurrency returns the concurrency settings stored for a script. Unsaved
+// scripts have none.
+func (e *Engine) concurrency(ctx context.Context, script appdb.Script) (appdb.ScriptConcurrency, error) {
+	if script.ID == 0 || e.db == nil {
+		return appdb.ScriptConcurrency{}, nil
+	}
+	c, err := appdb.New(e.db).GetScriptConcurrency(ctx, script.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return appdb.ScriptConcurrency{}, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("failed to load script concurrency: %w", err)
+	}
+	return c, nil
+}
+
+// slots bounds the runs of each script that happen at once
+type slots struct {
+	mu   sync.Mutex
+	sems map[int64]chan struct{}
+}
+
+// acquire takes one of a script's slots, waiting for one to free up if the
+// script queues excess runs. The returned func gives the slot back.
+func (s *slots) acquire(ctx context.Context, c appdb.ScriptConcurrency) (func(), error) {
+	if c.MaxConcurrent <= 0 {
+		return func() {}, nil
+	}
+	s.mu.Lock()
+	sem, ok := s.sems[c.ScriptID]
+	// A changed limit starts a new semaphore; runs holding the old one
+	// release it as they finish
+	if !ok || cap(sem) != int(c.MaxConcurrent) {
+		if s.sems == nil {
+			s.sems = map[int64]chan struct{}{}
+		}
+		sem = make(chan struct{}, c.MaxConcurrent)
+		s.sems[c.ScriptID] = sem
+	}
+	s.mu.Unlock()
+
+	release := func() { <-sem }
+	select {
+	case sem <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if !c.Queue {
+		return nil, ErrBusy
+	}
+	select {
+	case sem <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a free slot: %w", context.Cause(ctx))
+	}
+}
+
+// flight is a run shared by concurrent callers
+type flight struct {
+	done    chan struct{}
+	result  *Result
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightGroup coalesces concurrent runs with the same key into one, in the
+// manner of singleflight. The shared run is cancelled only once every
+// caller has gone away.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (*Result, error)) (*Result, error) {
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if !ok {
+		if g.flights == nil {
+			g.flights = map[string]*flight{}
+		}
+		// The run outlives the caller that started it if others still wait
+		runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+		go g.run(runCtx, key, f, fn)
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		// Callers get their own copy of the result
+		result := *f.result
+		return &result, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+		}
+		g.mu.Unlock()
+		return &Result{ContentType: ContentTypeText}, fmt.Errorf("waiting for a shared run: %w", context.Cause(ctx))
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, f *flight, fn func(context.Context) (*Result, error)) {
+	defer func() {
+		// There is no HTTP server to recover a panic on this goroutine
+		if p := recover(); p != nil {
+			f.result, f.err = &Result{ContentType: ContentTypeText}, fmt.Errorf("script run panicked: %v", p)
+		}
+		f.cancel()
+		g.mu.Lock()
+		if g.flights[key] == f {
+			delete(g.flights, key)
+		}
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	f.result, f.err = fn(ctx)
+}
+
+// flightKey identifies runs that can share a result: the same version of
+// a script with the same variables, run by the same user from the same
+// source. Scripts can read who runs them, and each caller's run is recorded
+// as theirs.
+func flightKey(ctx context.Context, script appdb.Script, vars map[string]string) string {
+	// Maps are marshaled with sorted keys
+	encoded, _ := json.Marshal(vars)
+	user := "-"
+	if id := userID(ctx); id.Valid {
+		user = fmt.Sprint(id.Int64)
+	}
+	return fmt.Sprintf("%d:%d:%s:%s:%s", script.ID, script.Updated.UnixNano(), user, source(ctx), encoded)
+}
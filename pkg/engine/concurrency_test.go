@@ -0,0 +1,140 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+// gated waits until the test sets the kv key "open", so a test can hold a
+// run in progress
+const gated = `kv incr started; while {![kv get open 0]} {}; kv get started`
+
+func TestConcurrencyLimit(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+	eng := engine.New(testDB.DB, nil, nil)
+
+	newScript := func(name string, queue bool) appdb.Script {
+		t.Helper()
+		script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{Name: name, Content: gated, AccessLevel: "user"})
+		if err != nil {
+			t.Fatalf("Failed to create script: %v", err)
+		}
+		if err := queries.UpsertScriptConcurrency(ctx, appdb.UpsertScriptConcurrencyParams{
+			ScriptID:      script.ID,
+			MaxConcurrent: 1,
+			Queue:         queue,
+		}); err != nil {
+			t.Fatalf("Failed to set concurrency: %v", err)
+		}
+		return script
+	}
+	setKV := func(script appdb.Script, key string) {
+		t.Helper()
+		if err := queries.SetKV(ctx, appdb.SetKVParams{ScriptID: script.ID, Key: key, Value: "1", Updated: time.Now().UTC()}); err != nil {
+			t.Fatalf("Failed to set %s: %v", key, err)
+		}
+	}
+	// hold starts a run and returns once it is in progress
+	hold := func(script appdb.Script) <-chan error {
+		t.Helper()
+		done := make(chan error, 1)
+		go func() {
+			_, err := eng.Run(ctx, script, nil)
+			done <- err
+		}()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			_, err := queries.GetKV(ctx, appdb.GetKVParams{ScriptID: script.ID, Key: "started", Now: appdb.NullTime(time.Now().UTC())})
+			if err == nil {
+				return done
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("run didn't start")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		script := newScript("reject", false)
+		first := hold(script)
+		if _, err := eng.Run(ctx, script, nil); !errors.Is(err, engine.ErrBusy) {
+			t.Errorf("second run error = %v, want ErrBusy", err)
+		}
+		setKV(script, "open")
+		if err := <-first; err != nil {
+			t.Fatalf("first run error: %v", err)
+		}
+		// The slot is free again
+		if result, err := eng.Run(ctx, script, nil); err != nil || result.Value != "2" {
+			t.Errorf("third run = %v, %v, want value 2", result.Value, err)
+		}
+	})
+
+	t.Run("queue", func(t *testing.T) {
+		script := newScript("queue", true)
+		first := hold(script)
+
+		// A queued run gives up at its deadline
+		waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if _, err := eng.Run(waitCtx, script, nil); !engine.IsTimeout(err) {
+			t.Errorf("queued run error = %v, want a timeout", err)
+		}
+
+		second := make(chan *engine.Result, 1)
+		go func() {
+			result, err := eng.Run(ctx, script, nil)
+			if err != nil {
+				t.Errorf("queued run error: %v", err)
+			}
+			second <- result
+		}()
+		setKV(script, "open")
+		if err := <-first; err != nil {
+			t.Fatalf("first run error: %v", err)
+		}
+		if result := <-second; result != nil && result.Value != "2" {
+			t.Errorf("queued run value = %q, want it to run after the first", result.Value)
+		}
+	})
+}
+
+func TestCoalesce(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+	eng := engine.New(testDB.DB, nil, nil)
+
+	script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{Name: "coalesced", Content: `list $who [kv incr runs]`, AccessLevel: "user"})
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if err := queries.UpsertScriptConcurrency(ctx, appdb.UpsertScriptConcurrencyParams{ScriptID: script.ID, Coalesce: true}); err != nil {
+		t.Fatalf("Failed to set concurrency: %v", err)
+	}
+
+	// Runs that don't overlap aren't shared
+	for _, want := range []string{"ann 1", "ann 2", "bob 3"} {
+		who := want[:3]
+		result, err := eng.Run(ctx, script, map[string]string{"who": who})
+		if err != nil {
+			t.Fatalf("Run error: %v", err)
+		}
+		if result.Value != want {
+			t.Errorf("value = %q, want %q", result.Value, want)
+		}
+	}
+}
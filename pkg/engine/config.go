@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// registerConfig adds read-only access to admin managed configuration:
+//
+//	var get KEY ?default?
+//	var exists KEY
+//	secret get KEY
+//	secret exists KEY
+func (r *run) registerConfig(interp *tcl.Interp) {
+	interp.Register("var", r.cmdVar)
+	interp.Register("secret", r.cmdSecret)
+}
+
+func (r *run) cmdVar(i *tcl.Interp, args []string) (string, error) {
+	if len(args) < 3 {
+		return "", tcl.WrongArgs(args[0], "subcommand key ?arg ...?")
+	}
+	queries, err := r.queries()
+	if err != nil {
+		return "", err
+	}
+
+	switch args[1] {
+	case "get":
+		if len(args) > 4 {
+			return "", tcl.WrongArgs("var get", "key ?default?")
+		}
+		v, err := queries.GetVar(i.Context(), args[2])
+		if errors.Is(err, sql.ErrNoRows) {
+			if len(args) == 4 {
+				return args[3], nil
+			}
+			return "", fmt.Errorf("var \"%s\" not found", args[2])
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read var \"%s\": %w", args[2], err)
+		}
+		return v.Value, nil
+	case "exists":
+		if len(args) != 3 {
+			return "", tcl.WrongArgs("var exists", "key")
+		}
+		_, err := queries.GetVar(i.Context(), args[2])
+		if errors.Is(err, sql.ErrNoRows) {
+			return "0", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read var \"%s\": %w", args[2], err)
+		}
+		return "1", nil
+	}
+	return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be exists or get", args[1])
+}
+
+func (r *run) cmdSecret(i *tcl.Interp, args []string) (string, error) {
+	if len(args) != 3 {
+		return "", tcl.WrongArgs(args[0], "subcommand key")
+	}
+	key := args[2]
+
+	switch args[1] {
+	case "get", "exists":
+	default:
+		return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be exists or get", args[1])
+	}
+	if !r.allowed[key] {
+		return "", fmt.Errorf("script \"%s\" is not allowed to read secret \"%s\"", r.script.Name, key)
+	}
+	if r.engine.secrets == nil {
+		return "", errors.New("secrets are disabled: no passphrase configured")
+	}
+
+	value, err := r.engine.secrets.Get(i.Context(), key)
+	if errors.Is(err, sql.ErrNoRows) {
+		if args[1] == "exists" {
+			return "0", nil
+		}
+		return "", fmt.Errorf("secret \"%s\" not found", key)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret \"%s\": %w", key, err)
+	}
+	if args[1] == "exists" {
+		return "1", nil
+	}
+	r.redactor.add(value)
+	return value, nil
+}
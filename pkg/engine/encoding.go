@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// registerEncoding adds encoding and hashing commands:
+//
+//	base64 encode|decode ?-url? DATA
+//	hex encode|decode DATA
+//	sha256 DATA
+//	hmac ?-algorithm sha1|sha256|sha512? KEY DATA
+//	uuid
+//
+// -url uses the URL-safe alphabet without padding, as JWTs do. Digests are
+// returned as lowercase hex.
+func registerEncoding(interp *tcl.Interp) {
+	interp.Register("base64", cmdBase64)
+	interp.Register("hex", cmdHex)
+	interp.Register("sha256", cmdSHA256)
+	interp.Register("hmac", cmdHMAC)
+	interp.Register("uuid", cmdUUID)
+}
+
+func cmdBase64(i *tcl.Interp, args []string) (string, error) {
+	url := len(args) == 4 && args[2] == "-url"
+	if len(args) != 3 && !url {
+		return "", tcl.WrongArgs(args[0], "encode|decode ?-url? data")
+	}
+	data := args[len(args)-1]
+	enc := base64.StdEncoding
+	if url {
+		enc = base64.RawURLEncoding
+	}
+
+	switch args[1] {
+	case "encode":
+		return enc.EncodeToString([]byte(data)), nil
+	case "decode":
+		// Accept input with or without padding
+		decoded, err := enc.WithPadding(base64.NoPadding).DecodeString(strings.TrimRight(strings.TrimSpace(data), "="))
+		if err != nil {
+			return "", fmt.Errorf("invalid base64 data: %w", err)
+		}
+		return string(decoded), nil
+	}
+	return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be decode or encode", args[1])
+}
+
+func cmdHex(i *tcl.Interp, args []string) (string, error) {
+	if len(args) != 3 {
+		return "", tcl.WrongArgs(args[0], "encode|decode data")
+	}
+	switch args[1] {
+	case "encode":
+		return hex.EncodeToString([]byte(args[2])), nil
+	case "decode":
+		decoded, err := hex.DecodeString(strings.TrimSpace(args[2]))
+		if err != nil {
+			return "", fmt.Errorf("invalid hex data: %w", err)
+		}
+		return string(decoded), nil
+	}
+	return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be decode or encode", args[1])
+}
+
+func cmdSHA256(i *tcl.Interp, args []string) (string, error) {
+	if len(args) != 2 {
+		return "", tcl.WrongArgs(args[0], "data")
+	}
+	sum := sha256.Sum256([]byte(args[1]))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func cmdHMAC(i *tcl.Interp, args []string) (string, error) {
+	algorithm := "sha256"
+	rest := args[1:]
+	if len(rest) > 0 && rest[0] == "-algorithm" {
+		if len(rest) < 2 {
+			return "", fmt.Errorf("missing value for -algorithm")
+		}
+		algorithm, rest = rest[1], rest[2:]
+	}
+	if len(rest) != 2 {
+		return "", tcl.WrongArgs(args[0], "?-algorithm name? key data")
+	}
+
+	var h func() hash.Hash
+	switch algorithm {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha512":
+		h = sha512.New
+	default:
+		return "", fmt.Errorf("bad algorithm \"%s\": must be sha1, sha256 or sha512", algorithm)
+	}
+	mac := hmac.New(h, []byte(rest[0]))
+	mac.Write([]byte(rest[1]))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func cmdUUID(i *tcl.Interp, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", tcl.WrongArgs(args[0], "")
+	}
+	return uuid.NewString(), nil
+}
@@ -0,0 +1,79 @@
+package engine_test
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+)
+
+func TestEncodingCommands(t *testing.T) {
+	runEvalCases(t, []evalCase{
+		{
+			name:    "base64 encode",
+			content: `base64 encode {hello, world}`,
+			want:    `aGVsbG8sIHdvcmxk`,
+		},
+		{
+			name:    "base64 decode with and without padding",
+			content: `list [base64 decode aGk=] [base64 decode aGk]`,
+			want:    `hi hi`,
+		},
+		{
+			name:    "base64 url",
+			content: `list [base64 encode ??>] [base64 encode -url ??>] [base64 decode -url Pz8-]`,
+			want:    `Pz8+ Pz8- ??>`,
+		},
+		{
+			name:    "base64 invalid",
+			content: `base64 decode {not base64!}`,
+			wantErr: "invalid base64 data",
+		},
+		{
+			name:    "hex",
+			content: `list [hex encode toolmin] [hex decode 746f6f6c6d696e]`,
+			want:    `746f6f6c6d696e toolmin`,
+		},
+		{
+			name:    "hex invalid",
+			content: `hex decode xyz`,
+			wantErr: "invalid hex data",
+		},
+		{
+			name:    "sha256",
+			content: `sha256 abc`,
+			want:    `ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad`,
+		},
+		{
+			name:    "hmac sha256 by default",
+			content: `hmac key {The quick brown fox jumps over the lazy dog}`,
+			want:    `f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8`,
+		},
+		{
+			name:    "hmac sha1",
+			content: `hmac -algorithm sha1 key {The quick brown fox jumps over the lazy dog}`,
+			want:    `de7c9b85b8b78aa6bc8a7a36f70a90701c9db4d9`,
+		},
+		{
+			name:    "hmac bad algorithm",
+			content: `hmac -algorithm md5 key data`,
+			wantErr: `bad algorithm "md5"`,
+		},
+	})
+}
+
+func TestUUIDCommand(t *testing.T) {
+	eng := engine.New(nil, nil, nil)
+	result, err := eng.Run(context.Background(), appdb.Script{Name: "uuid", Content: `list [uuid] [uuid]`}, nil)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	ids := strings.Fields(result.Value)
+	v4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if len(ids) != 2 || !v4.MatchString(ids[0]) || !v4.MatchString(ids[1]) || ids[0] == ids[1] {
+		t.Errorf("uuids = %q, want two different version 4 UUIDs", result.Value)
+	}
+}
@@ -0,0 +1,272 @@
+// Package engine runs scripts stored in the scripts table with the
+// embedded Tcl interpreter.
+package engine
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"sync"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/secrets"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// Engine executes scripts. It is safe for concurrent use; every run gets
+// its own interpreter, taken from a pool and reset when the run ends.
+type Engine struct {
+	db      *sql.DB
+	secrets *secrets.Service
+	log     *slog.Logger
+	limits  Limits
+	// templates holds the built-in templates for the render command
+	templates fs.FS
+	// interps holds reset interpreters for reuse
+	interps sync.Pool
+	// parsed holds saved scripts and libraries, parsed
+	parsed parseCache
+	// slots and flights apply the scripts' concurrency settings
+	slots   slots
+	flights flightGroup
+}
+
+// Result is the outcome of a script run
+type Result struct {
+	// Stdout is everything the script wrote with puts
+	Stdout string `json:"stdout"`
+	// Stderr is everything the script wrote with puts stderr
+	Stderr string `json:"stderr"`
+	// Value is the result of the last command, or the value passed to return
+	Value string `json:"value"`
+	// ContentType is the response type chosen with the response command
+	ContentType string        `json:"content_type"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// New creates a new engine. db backs the var and secret commands and may be
+// nil if scripts don't need them; secretsService is nil when no passphrase
+// is configured.
+func New(db *sql.DB, secretsService *secrets.Service, log *slog.Logger) *Engine {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Engine{
+		db:      db,
+		secrets: secretsService,
+		log:     log,
+		limits:  DefaultLimits,
+		interps: sync.Pool{New: func() any { return tcl.New() }},
+	}
+}
+
+// SetLimits replaces the default limits of every run. Limits stored for a
+// script override them. It must be called before the engine is used.
+func (e *Engine) SetLimits(limits Limits) {
+	e.limits = limits
+}
+
+// run holds the state of a single script execution
+type run struct {
+	engine *Engine
+	script appdb.Script
+	result *Result
+	// allowed lists the secrets the script may read
+	allowed map[string]bool
+	// redactor hides secret values the script has read
+	redactor redactor
+	// parsed caches the templates the script has rendered
+	parsed map[string]*template.Template
+	// execs are the commands the script ran, for its history
+	execs []execRecord
+	// packages maps the libraries the script has loaded to their versions
+	packages map[string]string
+	// loading is the chain of libraries being loaded, to detect cycles
+	loading []string
+}
+
+// Run executes script with vars set as global variables. The returned
+// Result is never nil, so output produced before a failure is kept. Script
+// errors are returned as *tcl.Error and carry the failing line. Secret
+// values the script read are redacted from the result and the error.
+//
+// A run stops when ctx is done or the script exceeds its limits; the
+// error then wraps ErrTimeout, ErrCommandLimit, ErrOutputLimit or
+// ErrMemoryLimit.
+//
+// A saved script may limit how many of its runs happen at once. Runs over
+// the limit wait for a free slot if the script queues them, and otherwise
+// fail with ErrBusy. A script that coalesces runs shares one execution and
+// its result between concurrent calls with the same vars, user and source,
+// except for calls streaming output with WithLines.
+//
+// Runs of saved scripts are recorded in the script's history, attributed
+// to the user set with WithUserID and the source set with WithSource. A
+// shared run is recorded once.
+func (e *Engine) Run(ctx context.Context, script appdb.Script, vars map[string]string) (*Result, error) {
+	c, err := e.concurrency(ctx, script)
+	if err != nil {
+		return &Result{ContentType: ContentTypeText}, err
+	}
+	if c.Coalesce && ctx.Value(linesKey{}) == nil {
+		return e.flights.do(ctx, flightKey(ctx, script, vars), func(ctx context.Context) (*Result, error) {
+			return e.execute(ctx, script, vars, c)
+		})
+	}
+	return e.execute(ctx, script, vars, c)
+}
+
+// execute runs a script once it has a slot
+func (e *Engine) execute(ctx context.Context, script appdb.Script, vars map[string]string, c appdb.ScriptConcurrency) (*Result, error) {
+	r := &run{
+		engine:   e,
+		script:   script,
+		result:   &Result{ContentType: ContentTypeText},
+		packages: map[string]string{},
+	}
+	if err := r.loadAllowedSecrets(ctx); err != nil {
+		return r.result, err
+	}
+	limits, err := r.limits(ctx)
+	if err != nil {
+		return r.result, err
+	}
+
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, limits.Timeout, ErrTimeout)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// Time spent waiting for a slot counts against the timeout
+	release, err := e.slots.acquire(ctx, c)
+	if err != nil {
+		return r.result, err
+	}
+	defer release()
+	if start, ok := ctx.Value(startKey{}).(func()); ok {
+		start()
+	}
+
+	var stdout, stderr bytes.Buffer
+	outWriter, flushOut := r.streamLines(ctx, &stdout, false)
+	errWriter, flushErr := r.streamLines(ctx, &stderr, true)
+	interp := e.interps.Get().(*tcl.Interp)
+	defer func() {
+		interp.Reset()
+		e.interps.Put(interp)
+	}()
+	interp.Stdout = outWriter
+	interp.Stderr = errWriter
+	interp.MaxCommands = limits.MaxCommands
+	interp.MaxValue = limits.MaxValue
+	if limits.MaxOutput > 0 {
+		budget := &outputBudget{remaining: limits.MaxOutput, cancel: cancel}
+		interp.Stdout = budget.writer(outWriter)
+		interp.Stderr = budget.writer(errWriter)
+	}
+	registerResponse(interp, r.result)
+	registerJSON(interp)
+	registerRegexp(interp)
+	registerClock(interp)
+	registerEncoding(interp)
+	r.registerConfig(interp)
+	r.registerRender(interp)
+	r.registerHTTP(interp)
+	r.registerExec(interp)
+	r.registerKV(interp)
+	r.registerSQL(interp)
+	r.registerPackage(interp)
+
+	for name, value := range vars {
+		if err := interp.SetGlobal(name, value); err != nil {
+			return r.result, err
+		}
+	}
+
+	logger := e.log.With("script", script.Name)
+	logger.Debug("running script", "vars", len(vars))
+
+	start := time.Now()
+	value, err := r.eval(ctx, interp)
+	flushOut()
+	flushErr()
+	result := r.result
+	result.Duration = time.Since(start)
+	result.Stdout = r.redactor.redact(stdout.String())
+	result.Stderr = r.redactor.redact(stderr.String())
+	result.Value = r.redactor.redact(value)
+
+	if err != nil {
+		err = r.redactor.redactError(err)
+		logger.Debug("script failed", "error", err, "duration_ms", result.Duration.Milliseconds())
+	} else {
+		logger.Debug("script finished", "duration_ms", result.Duration.Milliseconds())
+	}
+	r.record(ctx, start, vars, err)
+	return result, err
+}
+
+// eval parses the script, from the engine's cache if it is saved, and
+// evaluates it
+func (r *run) eval(ctx context.Context, interp *tcl.Interp) (string, error) {
+	var s *tcl.Script
+	var err error
+	if r.script.ID == 0 {
+		s, err = tcl.Parse(r.script.Content)
+	} else {
+		s, err = r.engine.parsed.parse(cacheKey{"script", r.script.ID}, r.script.Updated, r.script.Content)
+	}
+	if err != nil {
+		return "", err
+	}
+	return interp.EvalScript(ctx, s)
+}
+
+// loadAllowedSecrets reads the script's secret allow-list. Unsaved scripts
+// can't read any secrets.
+func (r *run) loadAllowedSecrets(ctx context.Context) error {
+	r.allowed = map[string]bool{}
+	if r.script.ID == 0 || r.engine.db == nil {
+		return nil
+	}
+	keys, err := appdb.New(r.engine.db).ListScriptSecrets(ctx, r.script.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load script secrets: %w", err)
+	}
+	for _, k := range keys {
+		r.allowed[k] = true
+	}
+	return nil
+}
+
+// limits returns the engine's limits with any stored for the script applied
+func (r *run) limits(ctx context.Context) (Limits, error) {
+	if r.script.ID == 0 || r.engine.db == nil {
+		return r.engine.limits, nil
+	}
+	stored, err := appdb.New(r.engine.db).GetScriptLimits(ctx, r.script.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.engine.limits, nil
+	}
+	if err != nil {
+		return Limits{}, fmt.Errorf("failed to load script limits: %w", err)
+	}
+	return r.engine.limits.withOverrides(stored), nil
+}
+
+// queries returns the database queries, or an error if the engine has no database
+func (r *run) queries() (*appdb.Queries, error) {
+	if r.engine.db == nil {
+		return nil, errors.New("no database available")
+	}
+	return appdb.New(r.engine.db), nil
+}
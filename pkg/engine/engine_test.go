@@ -0,0 +1,417 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/secrets"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestRun(t *testing.T) {
+	eng := engine.New(nil, nil, nil)
+
+	script := appdb.Script{
+		Name:        "greet",
+		AccessLevel: "user",
+		Content: `
+puts "Hello, $name!"
+expr {$count * 2}
+`,
+	}
+
+	result, err := eng.Run(context.Background(), script, map[string]string{
+		"name":  "toolmin",
+		"count": "21",
+	})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if result.Stdout != "Hello, toolmin!\n" {
+		t.Errorf("stdout = %q", result.Stdout)
+	}
+	if result.Value != "42" {
+		t.Errorf("value = %q, want 42", result.Value)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	eng := engine.New(nil, nil, nil)
+
+	script := appdb.Script{
+		Name:    "broken",
+		Content: "puts before\nset x $missing\nputs after",
+	}
+
+	result, err := eng.Run(context.Background(), script, nil)
+	var tclErr *tcl.Error
+	if !errors.As(err, &tclErr) {
+		t.Fatalf("expected *tcl.Error, got %v", err)
+	}
+	if tclErr.Line != 2 {
+		t.Errorf("error line = %d, want 2", tclErr.Line)
+	}
+	if result.Stdout != "before\n" {
+		t.Errorf("stdout = %q, want output produced before the error", result.Stdout)
+	}
+}
+
+func TestRunsDontShareState(t *testing.T) {
+	eng := engine.New(nil, nil, nil)
+	ctx := context.Background()
+
+	// Interpreters are reused, so nothing a run defines may outlive it
+	dirty := appdb.Script{Name: "dirty", Content: `set leaked 1; proc leak {} {}; rename clock {}; response type html`}
+	if _, err := eng.Run(ctx, dirty, nil); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	check := appdb.Script{Name: "check", Content: `list [info exists leaked] [info commands leak] [llength [info commands clock]]`}
+	for range 3 {
+		result, err := eng.Run(ctx, check, nil)
+		if err != nil {
+			t.Fatalf("Run error: %v", err)
+		}
+		if result.Value != "0 {} 1" {
+			t.Errorf("value = %q, want a clean interpreter", result.Value)
+		}
+		if result.ContentType != engine.ContentTypeText {
+			t.Errorf("content type = %q, want the default", result.ContentType)
+		}
+	}
+}
+
+func TestParseCache(t *testing.T) {
+	eng := engine.New(nil, nil, nil)
+	ctx := context.Background()
+	updated := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		content string
+		updated time.Time
+		want    string
+	}{
+		{"first run", `set v one`, updated, "one"},
+		{"cached", `set v one`, updated, "one"},
+		{"edited", `set v two`, updated.Add(time.Second), "two"},
+		{"edited within the second", `set v three`, updated.Add(time.Second), "three"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := appdb.Script{ID: 1, Name: "cached", Content: tt.content, Updated: tt.updated}
+			result, err := eng.Run(ctx, script, nil)
+			if err != nil {
+				t.Fatalf("Run error: %v", err)
+			}
+			if result.Value != tt.want {
+				t.Errorf("value = %q, want %q", result.Value, tt.want)
+			}
+		})
+	}
+
+	eng.ForgetScript(1)
+	script := appdb.Script{ID: 1, Name: "cached", Content: `set v {`, Updated: updated}
+	if _, err := eng.Run(ctx, script, nil); err == nil {
+		t.Error("expected a parse error after ForgetScript")
+	}
+}
+
+func TestConfigCommands(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+
+	secretsService, err := secrets.New(ctx, testDB.DB, "test passphrase")
+	if err != nil {
+		t.Fatalf("Failed to create secrets service: %v", err)
+	}
+	if _, err := queries.CreateVar(ctx, appdb.CreateVarParams{Key: "region", Value: "us-east"}); err != nil {
+		t.Fatalf("Failed to create var: %v", err)
+	}
+	for key, value := range map[string]string{"api_token": "s3cr3t-value", "other": "hidden"} {
+		if _, err := secretsService.Create(ctx, key, value); err != nil {
+			t.Fatalf("Failed to create secret: %v", err)
+		}
+	}
+
+	eng := engine.New(testDB.DB, secretsService, nil)
+
+	newScript := func(name, content string, allowed ...string) appdb.Script {
+		t.Helper()
+		script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{Name: name, Content: content, AccessLevel: "user"})
+		if err != nil {
+			t.Fatalf("Failed to create script: %v", err)
+		}
+		for _, key := range allowed {
+			if err := queries.AddScriptSecret(ctx, appdb.AddScriptSecretParams{ScriptID: script.ID, SecretKey: key}); err != nil {
+				t.Fatalf("Failed to allow secret: %v", err)
+			}
+		}
+		return script
+	}
+
+	tests := []struct {
+		name       string
+		script     appdb.Script
+		wantStdout string
+		wantValue  string
+		wantErr    string
+	}{
+		{
+			name:       "var get",
+			script:     newScript("vars", `puts [var get region]; var get missing fallback`),
+			wantStdout: "us-east\n",
+			wantValue:  "fallback",
+		},
+		{
+			name:    "var missing",
+			script:  newScript("novar", `var get missing`),
+			wantErr: `var "missing" not found`,
+		},
+		{
+			name:       "secret redacted",
+			script:     newScript("token", `puts "token=[secret get api_token]"; return [secret get api_token]`, "api_token"),
+			wantStdout: "token=" + engine.Redacted + "\n",
+			wantValue:  engine.Redacted,
+		},
+		{
+			name:    "secret in error",
+			script:  newScript("leak", `error "bad [secret get api_token]"`, "api_token"),
+			wantErr: "bad " + engine.Redacted,
+		},
+		{
+			name:    "secret not allowed",
+			script:  newScript("sneaky", `secret get other`, "api_token"),
+			wantErr: `not allowed to read secret "other"`,
+		},
+		{
+			name:    "unsaved script",
+			script:  appdb.Script{Name: "(unsaved)", Content: `secret get api_token`},
+			wantErr: `not allowed to read secret "api_token"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := eng.Run(ctx, tt.script, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if strings.Contains(err.Error(), "s3cr3t-value") {
+					t.Errorf("error leaks secret: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run error: %v", err)
+			}
+			if result.Stdout != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", result.Stdout, tt.wantStdout)
+			}
+			if result.Value != tt.wantValue {
+				t.Errorf("value = %q, want %q", result.Value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	isCommandLimit := func(err error) bool { return errors.Is(err, engine.ErrCommandLimit) }
+	isOutputLimit := func(err error) bool { return errors.Is(err, engine.ErrOutputLimit) }
+	isMemoryLimit := func(err error) bool { return errors.Is(err, engine.ErrMemoryLimit) }
+
+	tests := []struct {
+		name     string
+		limits   engine.Limits
+		deadline time.Duration
+		content  string
+		check    func(error) bool
+	}{
+		{"wall time", engine.Limits{Timeout: 50 * time.Millisecond}, 0, "while 1 {}", engine.IsTimeout},
+		{"caller deadline", engine.Limits{}, 50 * time.Millisecond, "while 1 {}", engine.IsTimeout},
+		{"commands", engine.Limits{MaxCommands: 10_000}, 0, "while 1 { incr n }", isCommandLimit},
+		{"output", engine.Limits{MaxOutput: 64}, 0, "while 1 { catch { puts [string repeat x 10] } }", isOutputLimit},
+		{"memory", engine.Limits{MaxValue: 1 << 20}, 0, "set s a; while 1 { catch { append s $s } }", isMemoryLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := engine.New(nil, nil, nil)
+			eng.SetLimits(tt.limits)
+
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			result, err := eng.Run(ctx, appdb.Script{Name: tt.name, Content: tt.content}, nil)
+			if !tt.check(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.limits.MaxOutput > 0 && len(result.Stdout) != tt.limits.MaxOutput {
+				t.Errorf("stdout is %d bytes, want it truncated to %d", len(result.Stdout), tt.limits.MaxOutput)
+			}
+		})
+	}
+}
+
+func TestScriptLimits(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+
+	script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{Name: "chatty", Content: "puts [string repeat x 100]", AccessLevel: "user"})
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	eng := engine.New(testDB.DB, nil, nil)
+	if _, err := eng.Run(ctx, script, nil); err != nil {
+		t.Fatalf("Run with default limits: %v", err)
+	}
+
+	if err := queries.UpsertScriptLimits(ctx, appdb.UpsertScriptLimitsParams{ScriptID: script.ID, MaxOutputBytes: 10}); err != nil {
+		t.Fatalf("Failed to set limits: %v", err)
+	}
+	if _, err := eng.Run(ctx, script, nil); !errors.Is(err, engine.ErrOutputLimit) {
+		t.Errorf("Run with stored limits: got %v, want ErrOutputLimit", err)
+	}
+}
+
+func TestPruneRuns(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+
+	script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{Name: "tick", Content: "puts tick", AccessLevel: "user"})
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	old := time.Now().Add(-48 * time.Hour).UTC()
+	if _, err := queries.CreateScriptRun(ctx, appdb.CreateScriptRunParams{
+		ScriptID: script.ID, Started: old, Finished: old, Status: engine.StatusOK, Vars: "{}",
+	}); err != nil {
+		t.Fatalf("Failed to create old run: %v", err)
+	}
+
+	eng := engine.New(testDB.DB, nil, nil)
+	if _, err := eng.Run(ctx, script, nil); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	deleted, err := eng.PruneRuns(ctx, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("PruneRuns error: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted %d runs, want 1", deleted)
+	}
+	runs, err := queries.ListScriptRuns(ctx, appdb.ListScriptRunsParams{ScriptID: script.ID, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListScriptRuns error: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Output != "tick\n" {
+		t.Errorf("remaining runs = %+v, want the recent run", runs)
+	}
+}
+
+// evalCase is a script run without a database and its expected result
+type evalCase struct {
+	name    string
+	content string
+	want    string
+	wantErr string
+}
+
+func runEvalCases(t *testing.T, cases []evalCase) {
+	t.Helper()
+	eng := engine.New(nil, nil, nil)
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := eng.Run(context.Background(), appdb.Script{Name: "eval", Content: tt.content}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run error: %v", err)
+			}
+			if result.Value != tt.want {
+				t.Errorf("value = %q, want %q", result.Value, tt.want)
+			}
+		})
+	}
+}
+
+// dashboard holds the tools of a typical polled dashboard: a table of
+// service states built with helper procs, and a small status widget
+var dashboard = map[string]string{
+	"services": `
+proc badge {state} {
+    switch -- $state {
+        up {return {<span class="ok">up</span>}}
+        degraded {return {<span class="warn">degraded</span>}}
+        default {return {<span class="err">down</span>}}
+    }
+}
+proc row {name state latency} {
+    set class [expr {$latency > 200 ? "slow" : "fast"}]
+    set name [string map {< &lt; > &gt; & &amp;} $name]
+    return "<tr class=\"$class\"><td>$name</td><td>[badge $state]</td><td>[format %.1f $latency] ms</td></tr>"
+}
+response type html
+puts "<h2>[string totitle $env] services</h2><table>"
+set states {up up degraded up down}
+for {set i 0} {$i < 40} {incr i} {
+    set state [lindex $states [expr {$i % [llength $states]}]]
+    puts [row "service-$i" $state [expr {($i * 37) % 300 + 0.5}]]
+}
+puts "</table>"
+`,
+	"status": `
+response type json
+set load [expr {[string length $env] * 0.25}]
+json stringify [dict create env $env load $load ok [expr {$load < 4}]] {object {load number ok bool}}
+`,
+}
+
+// BenchmarkRunDashboard runs each saved dashboard tool from many
+// goroutines, as concurrent polling clients do
+func BenchmarkRunDashboard(b *testing.B) {
+	eng := engine.New(nil, nil, nil)
+	vars := map[string]string{"env": "production"}
+	ctx := context.Background()
+
+	for id, name := range []string{"services", "status"} {
+		script := appdb.Script{ID: int64(id + 1), Name: name, Content: dashboard[name], Updated: time.Now()}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := eng.Run(ctx, script, vars); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "req/s")
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+const (
+	// DefaultExecTimeout is how long a command may run unless the script
+	// passes -timeout
+	DefaultExecTimeout = 30 * time.Second
+	// MaxExecOutput is how much of a command's stdout and stderr is kept,
+	// each. The rest is discarded.
+	MaxExecOutput = 1 << 20
+)
+
+// execEnv is the whole environment of commands. They don't inherit the
+// server's, which may hold its secrets passphrase.
+var execEnv = []string{"PATH=/usr/local/bin:/usr/bin:/bin", "LANG=C.UTF-8"}
+
+// execRecord is a command run by exec, written to the run's history
+type execRecord struct {
+	path     string
+	args     []string
+	exitCode int
+	duration time.Duration
+	err      string
+}
+
+// CompileArgPattern compiles an allow-list argument pattern, anchored so
+// that it must match a whole argument
+func CompileArgPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
+
+// CheckExecPath reports whether path can be put on the exec allow-list:
+// it must be absolute and clean, so there's only one way to write it
+func CheckExecPath(path string) error {
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+		return fmt.Errorf("path \"%s\" must be absolute and clean", path)
+	}
+	return nil
+}
+
+// registerExec adds the exec command, which runs an allow-listed binary
+// directly, without a shell:
+//
+//	exec ?-timeout ms? ?-stdin data? ?--? PATH ?arg ...?
+//
+// It returns a dict with the command's exit code, stdout and stderr. A
+// non-zero exit is not an error.
+func (r *run) registerExec(interp *tcl.Interp) {
+	interp.Register("exec", r.cmdExec)
+}
+
+func (r *run) cmdExec(i *tcl.Interp, args []string) (string, error) {
+	timeout := DefaultExecTimeout
+	var stdin *string
+	j := 1
+	for j < len(args) && strings.HasPrefix(args[j], "-") {
+		if args[j] == "--" {
+			j++
+			break
+		}
+		if j+1 == len(args) {
+			return "", fmt.Errorf("missing value for %s", args[j])
+		}
+		switch args[j] {
+		case "-timeout":
+			ms, ok := tcl.ParseInt(args[j+1])
+			if !ok || ms <= 0 {
+				return "", fmt.Errorf("expected positive integer timeout but got \"%s\"", args[j+1])
+			}
+			timeout = time.Duration(ms) * time.Millisecond
+		case "-stdin":
+			stdin = &args[j+1]
+		default:
+			return "", fmt.Errorf("bad option \"%s\": must be -stdin or -timeout", args[j])
+		}
+		j += 2
+	}
+	if j == len(args) {
+		return "", tcl.WrongArgs(args[0], "?-timeout ms? ?-stdin data? ?--? path ?arg ...?")
+	}
+	path, cmdArgs := args[j], args[j+1:]
+
+	// Refused commands are recorded too, so the history shows attempts
+	record := execRecord{path: path, args: cmdArgs, exitCode: -1}
+	defer func() { r.execs = append(r.execs, record) }()
+	if err := r.checkExec(i.Context(), path, cmdArgs); err != nil {
+		record.err = err.Error()
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(i.Context(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, cmdArgs...)
+	cmd.Env = execEnv
+	cmd.Dir = "/"
+	// Don't wait forever for children that kept the output pipes open
+	cmd.WaitDelay = time.Second
+	if stdin != nil {
+		cmd.Stdin = strings.NewReader(*stdin)
+	}
+	stdout := &cappedBuffer{max: MaxExecOutput}
+	stderr := &cappedBuffer{max: MaxExecOutput}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	record.duration = time.Since(start)
+
+	var exitErr *exec.ExitError
+	switch {
+	case i.Context().Err() != nil:
+		record.err = context.Cause(i.Context()).Error()
+		return "", context.Cause(i.Context())
+	case ctx.Err() != nil:
+		err = fmt.Errorf("exec %s timed out after %s", path, timeout)
+		record.err = err.Error()
+		return "", err
+	case errors.As(err, &exitErr):
+		record.exitCode = exitErr.ExitCode()
+	case err != nil:
+		err = fmt.Errorf("exec %s failed: %w", path, err)
+		record.err = err.Error()
+		return "", err
+	default:
+		record.exitCode = 0
+	}
+
+	result := tcl.NewDict()
+	result.Set("exit", strconv.Itoa(record.exitCode))
+	result.Set("stdout", stdout.String())
+	result.Set("stderr", stderr.String())
+	return result.String(), nil
+}
+
+// checkExec checks path and args against the allow-list
+func (r *run) checkExec(ctx context.Context, path string, args []string) error {
+	queries, err := r.queries()
+	if err != nil {
+		return err
+	}
+	allowed, err := queries.GetExecCommandByPath(ctx, path)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("command \"%s\" is not allowed", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read exec allow-list: %w", err)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	if allowed.ArgPattern == "" {
+		return fmt.Errorf("command \"%s\" is not allowed arguments", path)
+	}
+	pattern, err := CompileArgPattern(allowed.ArgPattern)
+	if err != nil {
+		return fmt.Errorf("invalid argument pattern for \"%s\": %w", path, err)
+	}
+	for _, arg := range args {
+		if !pattern.MatchString(arg) {
+			return fmt.Errorf("argument \"%s\" is not allowed for command \"%s\"", arg, path)
+		}
+	}
+	return nil
+}
+
+// cappedBuffer keeps the first max bytes written to it and discards the
+// rest, so a chatty command can't exhaust memory
+type cappedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,174 @@
+package engine_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestExecCommand(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+	for _, c := range []appdb.CreateExecCommandParams{
+		{Path: "/bin/echo", ArgPattern: `[a-z ]+`},
+		{Path: "/bin/cat"},
+		{Path: "/bin/false"},
+		{Path: "/bin/sleep", ArgPattern: `[0-9]+`},
+		{Path: "/usr/bin/env"},
+	} {
+		if _, err := queries.CreateExecCommand(ctx, c); err != nil {
+			t.Fatalf("Failed to allow %s: %v", c.Path, err)
+		}
+	}
+
+	t.Setenv("TOOLMIN_EXEC_TEST", "leaked")
+	eng := engine.New(testDB.DB, nil, nil)
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "stdout and exit",
+			content: `set r [exec /bin/echo hello world]; list [dict get $r exit] [dict get $r stdout]`,
+			want:    "0 {hello world\n}",
+		},
+		{
+			name:    "argument with spaces is one argument",
+			content: `dict get [exec /bin/echo "a b"] stdout`,
+			want:    "a b\n",
+		},
+		{
+			name:    "stdin",
+			content: `dict get [exec -stdin "piped in" /bin/cat] stdout`,
+			want:    "piped in",
+		},
+		{
+			name:    "non-zero exit is not an error",
+			content: `dict get [exec /bin/false] exit`,
+			want:    "1",
+		},
+		{
+			name:    "environment is not inherited",
+			content: `string match *TOOLMIN_EXEC_TEST* [dict get [exec /usr/bin/env] stdout]`,
+			want:    "0",
+		},
+		{
+			name:    "not allowed",
+			content: `exec /bin/sh -c {echo hi}`,
+			wantErr: "command \"/bin/sh\" is not allowed",
+		},
+		{
+			name:    "path must match exactly",
+			content: `exec /bin/../bin/echo hi`,
+			wantErr: "is not allowed",
+		},
+		{
+			name:    "argument not allowed",
+			content: `exec /bin/echo {hi; rm -rf /}`,
+			wantErr: "argument \"hi; rm -rf /\" is not allowed for command \"/bin/echo\"",
+		},
+		{
+			name:    "no arguments allowed",
+			content: `exec /bin/cat /etc/passwd`,
+			wantErr: "command \"/bin/cat\" is not allowed arguments",
+		},
+		{
+			name:    "timeout",
+			content: `exec -timeout 50 /bin/sleep 5`,
+			wantErr: "exec /bin/sleep timed out after 50ms",
+		},
+		{
+			name:    "bad option",
+			content: `exec -shell /bin/echo hi`,
+			wantErr: "bad option \"-shell\"",
+		},
+		{
+			name:    "double dash ends options",
+			content: `exec -- -timeout`,
+			wantErr: "command \"-timeout\" is not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := eng.Run(ctx, appdb.Script{Name: "exec", Content: tt.content}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if engine.IsTimeout(err) {
+					t.Errorf("error %v counts as a run timeout", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run error: %v", err)
+			}
+			if result.Value != tt.want {
+				t.Errorf("value = %q, want %q", result.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecHistory(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+	if _, err := queries.CreateExecCommand(ctx, appdb.CreateExecCommandParams{Path: "/bin/echo", ArgPattern: `\S+`}); err != nil {
+		t.Fatalf("Failed to allow command: %v", err)
+	}
+	script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{
+		Name:        "execs",
+		Content:     `exec /bin/echo $token; exec /bin/cat`,
+		AccessLevel: "user",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	eng := engine.New(testDB.DB, nil, nil)
+	if _, err := eng.Run(ctx, script, map[string]string{"token": "abc"}); err == nil {
+		t.Fatal("expected the refused command to fail the run")
+	}
+
+	runs, err := queries.ListScriptRuns(ctx, appdb.ListScriptRunsParams{ScriptID: script.ID, Limit: 10})
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("runs = %v, %v; want one run", runs, err)
+	}
+	execs, err := queries.ListScriptRunExecs(ctx, runs[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to list execs: %v", err)
+	}
+	if len(execs) != 2 {
+		t.Fatalf("execs = %+v, want 2", execs)
+	}
+	if execs[0].Path != "/bin/echo" || execs[0].ExitCode != 0 || execs[0].Error != "" {
+		t.Errorf("first exec = %+v, want a successful echo", execs[0])
+	}
+	if execs[0].Args != `["abc"]` {
+		t.Errorf("args = %s, want [\"abc\"]", execs[0].Args)
+	}
+	if execs[1].Path != "/bin/cat" || execs[1].ExitCode != -1 || !strings.Contains(execs[1].Error, "not allowed") {
+		t.Errorf("second exec = %+v, want the refused cat", execs[1])
+	}
+
+	if err := queries.DeleteScriptRunExecs(ctx, script.ID); err != nil {
+		t.Fatalf("Failed to delete execs: %v", err)
+	}
+	if execs, _ := queries.ListScriptRunExecs(ctx, runs[0].ID); len(execs) != 0 {
+		t.Errorf("execs after delete = %+v, want none", execs)
+	}
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+)
+
+// waitForWaiters polls until n callers wait on the flight for key
+func waitForWaiters(t *testing.T, g *flightGroup, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		g.mu.Lock()
+		f := g.flights[key]
+		got := 0
+		if f != nil {
+			got = f.waiters
+		}
+		g.mu.Unlock()
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("waiters = %d, want %d", got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFlightGroupShares(t *testing.T) {
+	var g flightGroup
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func(context.Context) (*Result, error) {
+		calls.Add(1)
+		<-release
+		return &Result{Value: "shared", ContentType: ContentTypeText}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*Result, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := g.do(context.Background(), "k", fn)
+			if err != nil {
+				t.Errorf("do error: %v", err)
+			}
+			results[i] = result
+		}()
+	}
+	waitForWaiters(t, &g, "k", len(results))
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fn ran %d times, want 1", n)
+	}
+	for i, result := range results {
+		if result == nil || result.Value != "shared" {
+			t.Errorf("result %d = %v, want shared", i, result)
+		}
+	}
+	// Callers own their copy
+	if results[0] == results[1] {
+		t.Error("callers share one *Result")
+	}
+	if len(g.flights) != 0 {
+		t.Errorf("%d flights left after the run", len(g.flights))
+	}
+}
+
+func TestFlightGroupCancel(t *testing.T) {
+	var g flightGroup
+	cancelled := make(chan struct{})
+	fn := func(ctx context.Context) (*Result, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return &Result{ContentType: ContentTypeText}, ctx.Err()
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() { _, err := g.do(first, "k", fn); errs <- err }()
+	go func() { _, err := g.do(second, "k", fn); errs <- err }()
+	waitForWaiters(t, &g, "k", 2)
+
+	// The run goes on while anyone still waits for it
+	cancelFirst()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("first caller error = %v, want context.Canceled", err)
+	}
+	select {
+	case <-cancelled:
+		t.Fatal("run cancelled while a caller still waits")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancelSecond()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("second caller error = %v, want context.Canceled", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run not cancelled after every caller left")
+	}
+}
+
+func TestFlightKeyCaller(t *testing.T) {
+	script := appdb.Script{ID: 1}
+	vars := map[string]string{"a": "1"}
+	ctx := context.Background()
+	ann := WithUserID(ctx, 1)
+
+	if flightKey(ann, script, vars) != flightKey(WithUserID(ctx, 1), script, vars) {
+		t.Error("the same caller got different keys")
+	}
+	for name, other := range map[string]context.Context{
+		"anonymous":    ctx,
+		"another user": WithUserID(ctx, 2),
+		"webhook":      WithSource(ann, SourceWebhook),
+	} {
+		if flightKey(ann, script, vars) == flightKey(other, script, vars) {
+			t.Errorf("%s shares a key with the user", name)
+		}
+	}
+}
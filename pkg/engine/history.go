@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+)
+
+// Run statuses recorded in the script_runs table
+const (
+	StatusOK           = "ok"
+	StatusError        = "error"
+	StatusTimeout      = "timeout"
+	StatusCommandLimit = "command_limit"
+	StatusOutputLimit  = "output_limit"
+	StatusMemoryLimit  = "memory_limit"
+	StatusCancelled    = "cancelled"
+)
+
+// Run sources recorded in the script_runs table
+const (
+	SourceRequest  = "request"
+	SourceWebhook  = "webhook"
+	SourceSchedule = "schedule"
+)
+
+// MaxHistoryOutput is how much of a run's output is kept in its history
+const MaxHistoryOutput = 4096
+
+// sensitiveVar matches input variable names whose values are masked in
+// run history
+var sensitiveVar = regexp.MustCompile(`(?i)pass|secret|token|key|auth|credential`)
+
+type userIDKey struct{}
+
+type sourceKey struct{}
+
+// WithUserID returns a context that attributes runs to the given user
+func WithUserID(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// userID returns the user a run is attributed to, if any
+func userID(ctx context.Context) sql.NullInt64 {
+	id, ok := ctx.Value(userIDKey{}).(int64)
+	return sql.NullInt64{Int64: id, Valid: ok}
+}
+
+// WithSource returns a context that records runs as started by source,
+// one of the Source constants. Runs default to SourceRequest.
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// source returns what started a run
+func source(ctx context.Context) string {
+	if s, ok := ctx.Value(sourceKey{}).(string); ok {
+		return s
+	}
+	return SourceRequest
+}
+
+// Status returns the history status of a run that returned err
+func Status(err error) string {
+	switch {
+	case err == nil:
+		return StatusOK
+	case IsTimeout(err):
+		return StatusTimeout
+	case errors.Is(err, ErrCommandLimit):
+		return StatusCommandLimit
+	case errors.Is(err, ErrOutputLimit):
+		return StatusOutputLimit
+	case errors.Is(err, ErrMemoryLimit):
+		return StatusMemoryLimit
+	case errors.Is(err, context.Canceled):
+		return StatusCancelled
+	}
+	return StatusError
+}
+
+// record writes a finished run to the script's history. Unsaved scripts
+// have no history. Failures are logged rather than failing the run.
+func (r *run) record(ctx context.Context, started time.Time, vars map[string]string, runErr error) {
+	if r.script.ID == 0 || r.engine.db == nil {
+		return
+	}
+
+	masked := make(map[string]string, len(vars))
+	for name, value := range vars {
+		if sensitiveVar.MatchString(name) {
+			value = Redacted
+		}
+		masked[name] = r.redactor.redact(value)
+	}
+	varsJSON, err := json.Marshal(masked)
+	if err != nil {
+		r.engine.log.Warn("failed to record script run", "script", r.script.Name, "error", err)
+		return
+	}
+
+	var message string
+	if runErr != nil {
+		message = runErr.Error()
+	}
+
+	// The run's own context may already be done, but the record should
+	// still be written
+	ctx = context.WithoutCancel(ctx)
+	queries := appdb.New(r.engine.db)
+	saved, err := queries.CreateScriptRun(ctx, appdb.CreateScriptRunParams{
+		ScriptID: r.script.ID,
+		UserID:   userID(ctx),
+		Started:  started.UTC(),
+		Finished: started.Add(r.result.Duration).UTC(),
+		Status:   Status(runErr),
+		Output:   truncate(r.result.Stdout, MaxHistoryOutput),
+		Error:    message,
+		Vars:     string(varsJSON),
+		Source:   source(ctx),
+	})
+	if err != nil {
+		r.engine.log.Warn("failed to record script run", "script", r.script.Name, "error", err)
+		return
+	}
+
+	for n, e := range r.execs {
+		args := make([]string, len(e.args))
+		for j, arg := range e.args {
+			args[j] = r.redactor.redact(arg)
+		}
+		argsJSON, err := json.Marshal(args)
+		if err != nil {
+			r.engine.log.Warn("failed to record exec", "script", r.script.Name, "error", err)
+			continue
+		}
+		if err := queries.AddScriptRunExec(ctx, appdb.AddScriptRunExecParams{
+			RunID:      saved.ID,
+			Position:   int64(n),
+			Path:       e.path,
+			Args:       string(argsJSON),
+			ExitCode:   int64(e.exitCode),
+			DurationMs: e.duration.Milliseconds(),
+			Error:      r.redactor.redact(e.err),
+		}); err != nil {
+			r.engine.log.Warn("failed to record exec", "script", r.script.Name, "error", err)
+		}
+	}
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:n]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// PruneRuns deletes run history started before now minus retention
+func (e *Engine) PruneRuns(ctx context.Context, retention time.Duration) (int64, error) {
+	if e.db == nil {
+		return 0, nil
+	}
+	queries := appdb.New(e.db)
+	cutoff := time.Now().Add(-retention).UTC()
+	if err := queries.DeleteScriptRunExecsBefore(ctx, cutoff); err != nil {
+		return 0, fmt.Errorf("failed to prune script runs: %w", err)
+	}
+	deleted, err := queries.DeleteScriptRunsBefore(ctx, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune script runs: %w", err)
+	}
+	return deleted, nil
+}
+
+// PruneInBackground prunes expired kv keys and run history older than
+// retention now and then every hour, until the process exits. A zero
+// retention keeps history forever.
+func (e *Engine) PruneInBackground(retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	e.log.Debug("starting pruning", "retention", retention)
+	for {
+		if retention > 0 {
+			deleted, err := e.PruneRuns(context.Background(), retention)
+			if err != nil {
+				e.log.Error("failed to prune script runs", "error", err)
+			} else if deleted > 0 {
+				e.log.Info("pruned script runs", "deleted", deleted)
+			}
+		}
+		deleted, err := e.PruneKV(context.Background())
+		if err != nil {
+			e.log.Error("failed to prune kv", "error", err)
+		} else if deleted > 0 {
+			e.log.Info("pruned expired kv keys", "deleted", deleted)
+		}
+		<-ticker.C
+	}
+}
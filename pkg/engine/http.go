@@ -0,0 +1,325 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// Vars that configure the http command
+const (
+	// HTTPAllowVar lists the hosts scripts may call. Entries are host names,
+	// wildcards such as *.example.com, IP addresses or CIDR ranges. When it
+	// is empty every host not denied is allowed.
+	HTTPAllowVar = "http.allow"
+	// HTTPDenyVar lists hosts scripts may not call, in the same form
+	HTTPDenyVar = "http.deny"
+	// HTTPMaxResponseVar is the largest response body in bytes
+	HTTPMaxResponseVar = "http.max_response_bytes"
+)
+
+const (
+	// DefaultHTTPTimeout is how long a request may take unless the script
+	// passes -timeout
+	DefaultHTTPTimeout = 10 * time.Second
+	// DefaultHTTPMaxResponse is the largest response body unless
+	// HTTPMaxResponseVar says otherwise
+	DefaultHTTPMaxResponse = 1 << 20
+	// maxHTTPRedirects is how many redirects a request follows
+	maxHTTPRedirects = 5
+)
+
+// blockedPrefixes are refused unless HTTPAllowVar lists them by address or
+// range: link-local addresses, which include the cloud metadata services,
+// and metadata addresses outside the link-local ranges.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("fd00:ec2::254/128"),
+	netip.MustParsePrefix("100.100.100.200/32"),
+}
+
+// hostPolicy decides which hosts the http command may connect to
+type hostPolicy struct {
+	allowNames, denyNames       []string
+	allowPrefixes, denyPrefixes []netip.Prefix
+}
+
+func parseHostPolicy(allow, deny string) hostPolicy {
+	var p hostPolicy
+	p.allowNames, p.allowPrefixes = parseHosts(allow)
+	p.denyNames, p.denyPrefixes = parseHosts(deny)
+	return p
+}
+
+// parseHosts splits a comma or space separated host list into names and
+// address ranges. Single addresses become one-address ranges.
+func parseHosts(list string) ([]string, []netip.Prefix) {
+	var names []string
+	var prefixes []netip.Prefix
+	for _, entry := range strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	}) {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		} else {
+			names = append(names, strings.ToLower(strings.TrimSuffix(entry, ".")))
+		}
+	}
+	return names, prefixes
+}
+
+func matchName(patterns []string, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok && strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// check reports why connecting to addr, which host resolved to, isn't allowed
+func (p hostPolicy) check(host string, addr netip.Addr) error {
+	addr = addr.Unmap()
+	if matchName(p.denyNames, host) || matchAddr(p.denyPrefixes, addr) {
+		return fmt.Errorf("host \"%s\" is denied", host)
+	}
+	allowedAddr := matchAddr(p.allowPrefixes, addr)
+	if matchAddr(blockedPrefixes, addr) && !allowedAddr {
+		return fmt.Errorf("address %s of host \"%s\" is blocked", addr, host)
+	}
+	if len(p.allowNames)+len(p.allowPrefixes) > 0 && !allowedAddr && !matchName(p.allowNames, host) {
+		return fmt.Errorf("host \"%s\" is not in the allow list", host)
+	}
+	return nil
+}
+
+// dial resolves the host itself and checks every address before
+// connecting, so a name can't be pointed at a blocked address after it
+// was checked
+func (p hostPolicy) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if err := p.check(host, addr); err != nil {
+			return nil, err
+		}
+	}
+
+	var dialer net.Dialer
+	var conn net.Conn
+	for _, addr := range addrs {
+		conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// registerHTTP adds the http command:
+//
+//	http get|post|put|delete URL ?-headers dict? ?-body data? ?-timeout ms? ?-json?
+//
+// It returns a dict with the response's status, headers and body. -json
+// decodes the body into a Tcl value, with objects as dicts.
+func (r *run) registerHTTP(interp *tcl.Interp) {
+	interp.Register("http", r.cmdHTTP)
+}
+
+func (r *run) cmdHTTP(i *tcl.Interp, args []string) (string, error) {
+	if len(args) < 3 {
+		return "", tcl.WrongArgs(args[0], "method url ?-headers dict? ?-body data? ?-timeout ms? ?-json?")
+	}
+	var method string
+	switch args[1] {
+	case "get", "post", "put", "delete":
+		method = strings.ToUpper(args[1])
+	default:
+		return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be delete, get, post or put", args[1])
+	}
+	url := args[2]
+
+	var headers *tcl.Dict
+	var body io.Reader
+	timeout := DefaultHTTPTimeout
+	decode := false
+	for j := 3; j < len(args); j++ {
+		switch args[j] {
+		case "-json":
+			decode = true
+			continue
+		case "-headers", "-body", "-timeout":
+		default:
+			return "", fmt.Errorf("bad option \"%s\": must be -body, -headers, -json or -timeout", args[j])
+		}
+		if j+1 == len(args) {
+			return "", fmt.Errorf("missing value for %s", args[j])
+		}
+		value := args[j+1]
+		switch args[j] {
+		case "-headers":
+			d, err := tcl.ParseDict(value)
+			if err != nil {
+				return "", err
+			}
+			headers = d
+		case "-body":
+			body = strings.NewReader(value)
+		case "-timeout":
+			ms, ok := tcl.ParseInt(value)
+			if !ok || ms <= 0 {
+				return "", fmt.Errorf("expected positive integer timeout but got \"%s\"", value)
+			}
+			timeout = time.Duration(ms) * time.Millisecond
+		}
+		j++
+	}
+
+	policy, maxBytes, err := r.httpConfig(i.Context())
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(i.Context(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return "", fmt.Errorf("unsupported URL scheme \"%s\"", req.URL.Scheme)
+	}
+	if headers != nil {
+		for _, k := range headers.Keys() {
+			v, _ := headers.Get(k)
+			req.Header.Set(k, v)
+		}
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:             nil,
+			DialContext:       policy.dial,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxHTTPRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxHTTPRedirects)
+			}
+			return nil
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// Don't let the request's own timeout look like the run's
+		if i.Context().Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("http %s timed out after %s", args[1], timeout)
+		}
+		return "", fmt.Errorf("http %s failed: %w", args[1], err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %v", err)
+	}
+	if len(data) > maxBytes {
+		return "", fmt.Errorf("response is larger than %d bytes", maxBytes)
+	}
+
+	result := tcl.NewDict()
+	result.Set("status", strconv.Itoa(resp.StatusCode))
+	respHeaders := tcl.NewDict()
+	for _, k := range slices.Sorted(maps.Keys(resp.Header)) {
+		respHeaders.Set(strings.ToLower(k), strings.Join(resp.Header[k], ", "))
+	}
+	result.Set("headers", respHeaders.String())
+	if decode {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return "", fmt.Errorf("failed to decode JSON response: %w", err)
+		}
+		result.Set("body", TclValue(v))
+	} else {
+		result.Set("body", string(data))
+	}
+	return result.String(), nil
+}
+
+// httpConfig reads the http command's host policy and response size limit
+// from vars
+func (r *run) httpConfig(ctx context.Context) (hostPolicy, int, error) {
+	if r.engine.db == nil {
+		return hostPolicy{}, DefaultHTTPMaxResponse, nil
+	}
+	queries := appdb.New(r.engine.db)
+	get := func(key string) (string, error) {
+		v, err := queries.GetVar(ctx, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read var \"%s\": %w", key, err)
+		}
+		return v.Value, nil
+	}
+
+	allow, err := get(HTTPAllowVar)
+	if err != nil {
+		return hostPolicy{}, 0, err
+	}
+	deny, err := get(HTTPDenyVar)
+	if err != nil {
+		return hostPolicy{}, 0, err
+	}
+	maxBytes := DefaultHTTPMaxResponse
+	if v, err := get(HTTPMaxResponseVar); err != nil {
+		return hostPolicy{}, 0, err
+	} else if v != "" {
+		n, ok := tcl.ParseInt(v)
+		if !ok || n <= 0 {
+			return hostPolicy{}, 0, fmt.Errorf("var \"%s\" must be a positive integer", HTTPMaxResponseVar)
+		}
+		maxBytes = int(n)
+	}
+	return parseHostPolicy(allow, deny), maxBytes, nil
+}
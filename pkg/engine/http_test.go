@@ -0,0 +1,167 @@
+package engine_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestHTTPCommand(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"name": "toolmin", "tags": ["a", "b c"], "owner": {"id": 7}, "ok": true}`)
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		io.WriteString(w, r.Header.Get("X-Token")+" "+string(body))
+	})
+	mux.HandleFunc("GET /missing", http.NotFound)
+	mux.HandleFunc("GET /large", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("x", 100))
+	})
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("GET /metadata", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	eng := engine.New(testDB.DB, nil, nil)
+
+	tests := []struct {
+		name    string
+		vars    map[string]string
+		content string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "get json",
+			content: `set r [http get $url/json -json]; set b [dict get $r body]; list [dict get $r status] [dict get $b name] [lindex [dict get $b tags] 1] [dict get $b owner id] [dict get $b ok]`,
+			want:    `200 toolmin {b c} 7 true`,
+		},
+		{
+			name:    "post with headers and body",
+			content: `set r [http post $url/echo -headers {X-Token abc} -body payload]; list [dict get $r body] [dict get $r headers x-method]`,
+			want:    `{abc payload} POST`,
+		},
+		{
+			name:    "put and delete",
+			content: `list [dict get [http put $url/echo] headers x-method] [dict get [http delete $url/echo] headers x-method]`,
+			want:    `PUT DELETE`,
+		},
+		{
+			name:    "error statuses are returned",
+			content: `dict get [http get $url/missing] status`,
+			want:    `404`,
+		},
+		{
+			name:    "request timeout",
+			content: `http get $url/slow -timeout 50`,
+			wantErr: "timed out after 50ms",
+		},
+		{
+			name:    "metadata address blocked",
+			content: `http get http://169.254.169.254/latest/meta-data/`,
+			wantErr: "address 169.254.169.254 of host \"169.254.169.254\" is blocked",
+		},
+		{
+			name:    "redirect to metadata blocked",
+			content: `http get $url/metadata`,
+			wantErr: "is blocked",
+		},
+		{
+			name:    "not in allow list",
+			vars:    map[string]string{engine.HTTPAllowVar: "api.internal, *.example.com"},
+			content: `http get $url/json`,
+			wantErr: "host \"127.0.0.1\" is not in the allow list",
+		},
+		{
+			name:    "allowed by range",
+			vars:    map[string]string{engine.HTTPAllowVar: "api.internal 127.0.0.0/8"},
+			content: `dict get [http get $url/json] status`,
+			want:    `200`,
+		},
+		{
+			name:    "denied",
+			vars:    map[string]string{engine.HTTPAllowVar: "127.0.0.1", engine.HTTPDenyVar: "127.0.0.1"},
+			content: `http get $url/json`,
+			wantErr: "host \"127.0.0.1\" is denied",
+		},
+		{
+			name:    "response too large",
+			vars:    map[string]string{engine.HTTPMaxResponseVar: "99"},
+			content: `http get $url/large`,
+			wantErr: "response is larger than 99 bytes",
+		},
+		{
+			name:    "response at the limit",
+			vars:    map[string]string{engine.HTTPMaxResponseVar: "100"},
+			content: `string length [dict get [http get $url/large] body]`,
+			want:    `100`,
+		},
+		{
+			name:    "unsupported scheme",
+			content: `http get file:///etc/passwd`,
+			wantErr: "unsupported URL scheme \"file\"",
+		},
+		{
+			name:    "bad option",
+			content: `http get $url -follow 1`,
+			wantErr: "bad option \"-follow\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{engine.HTTPAllowVar, engine.HTTPDenyVar, engine.HTTPMaxResponseVar} {
+				if err := queries.DeleteVar(ctx, key); err != nil {
+					t.Fatalf("Failed to delete var: %v", err)
+				}
+			}
+			for key, value := range tt.vars {
+				if _, err := queries.CreateVar(ctx, appdb.CreateVarParams{Key: key, Value: value}); err != nil {
+					t.Fatalf("Failed to create var: %v", err)
+				}
+			}
+
+			result, err := eng.Run(ctx, appdb.Script{Name: "http", Content: tt.content}, map[string]string{"url": server.URL})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if engine.IsTimeout(err) {
+					t.Errorf("error %v counts as a run timeout", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run error: %v", err)
+			}
+			if result.Value != tt.want {
+				t.Errorf("value = %q, want %q", result.Value, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// jsonNumber matches numbers as JSON writes them
+var jsonNumber = regexp.MustCompile(`^-?(?:0|[1-9][0-9]*)(?:\.[0-9]+)?(?:[eE][+-]?[0-9]+)?$`)
+
+// TclValue formats a decoded JSON value as a Tcl value. Objects become
+// dicts with sorted keys and null becomes the empty string. Numbers are
+// kept as written if decoded with UseNumber.
+func TclValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return tcl.FormatFloat(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = TclValue(item)
+		}
+		return tcl.FormatList(items)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, 2*len(v))
+		for _, k := range keys {
+			items = append(items, k, TclValue(v[k]))
+		}
+		return tcl.FormatList(items)
+	}
+	return ""
+}
+
+// registerJSON adds the json command:
+//
+//	json parse TEXT
+//	json stringify VALUE ?TYPE?
+//
+// parse returns objects as dicts and arrays as lists, like TclValue.
+// Tcl values don't know their type, so stringify is told it:
+//
+//	string, number, bool or null
+//	auto        a number or bool if the value looks like one, else a string
+//	list ?TYPE? an array of TYPE, strings by default
+//	dict ?TYPE? an object with values of TYPE, strings by default
+//	object SPEC an object whose SPEC dict gives the TYPE of some keys; the
+//	            rest are strings
+//
+// TYPE defaults to string.
+func registerJSON(interp *tcl.Interp) {
+	interp.Register("json", cmdJSON)
+}
+
+func cmdJSON(i *tcl.Interp, args []string) (string, error) {
+	if len(args) < 3 {
+		return "", tcl.WrongArgs(args[0], "subcommand value ?arg ...?")
+	}
+	switch args[1] {
+	case "parse":
+		if len(args) != 3 {
+			return "", tcl.WrongArgs("json parse", "text")
+		}
+		dec := json.NewDecoder(strings.NewReader(args[2]))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return "", fmt.Errorf("invalid JSON: %w", err)
+		}
+		if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+			return "", errors.New("invalid JSON: unexpected data after value")
+		}
+		return TclValue(v), nil
+	case "stringify":
+		if len(args) > 4 {
+			return "", tcl.WrongArgs("json stringify", "value ?type?")
+		}
+		spec := "string"
+		if len(args) == 4 {
+			spec = args[3]
+		}
+		var buf bytes.Buffer
+		if err := writeJSON(&buf, args[2], spec); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be parse or stringify", args[1])
+}
+
+// writeJSON writes value to buf as the JSON type spec describes
+func writeJSON(buf *bytes.Buffer, value, spec string) error {
+	words, err := tcl.SplitList(spec)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		words = []string{"string"}
+	}
+	typ, rest := words[0], words[1:]
+	if len(rest) > 1 || (len(rest) == 1 && typ != "list" && typ != "dict" && typ != "object") {
+		return fmt.Errorf("bad type \"%s\"", spec)
+	}
+	elem := "string"
+	if typ == "object" {
+		elem = ""
+	}
+	if len(rest) == 1 {
+		elem = rest[0]
+	}
+
+	switch typ {
+	case "string":
+		writeJSONString(buf, value)
+	case "number":
+		n, err := jsonNumberValue(value)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(n)
+	case "bool":
+		b, ok := tcl.ParseBool(value)
+		if !ok {
+			return fmt.Errorf("expected boolean but got \"%s\"", value)
+		}
+		buf.WriteString(strconv.FormatBool(b))
+	case "null":
+		buf.WriteString("null")
+	case "auto":
+		if jsonNumber.MatchString(value) {
+			buf.WriteString(value)
+		} else if value == "true" || value == "false" {
+			buf.WriteString(value)
+		} else {
+			writeJSONString(buf, value)
+		}
+	case "list":
+		items, err := tcl.SplitList(value)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('[')
+		for j, item := range items {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, item, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case "dict", "object":
+		d, err := tcl.ParseDict(value)
+		if err != nil {
+			return err
+		}
+		var types *tcl.Dict
+		if typ == "object" {
+			if types, err = tcl.ParseDict(elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('{')
+		for j, k := range d.Keys() {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, k)
+			buf.WriteByte(':')
+			t := elem
+			if types != nil {
+				if t, _ = types.Get(k); t == "" {
+					t = "string"
+				}
+			}
+			v, _ := d.Get(k)
+			if err := writeJSON(buf, v, t); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("bad type \"%s\": must be auto, bool, dict, list, null, number, object or string", typ)
+	}
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	// Encode ends with a newline
+	buf.Truncate(buf.Len() - 1)
+}
+
+// jsonNumberValue returns value as a JSON number. Tcl forms such as hex
+// integers are converted.
+func jsonNumberValue(value string) (string, error) {
+	if jsonNumber.MatchString(value) {
+		return value, nil
+	}
+	if n, ok := tcl.ParseInt(value); ok {
+		return strconv.FormatInt(n, 10), nil
+	}
+	if f, ok := tcl.ParseFloat(value); ok && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("expected number but got \"%s\"", value)
+}
@@ -0,0 +1,83 @@
+package engine_test
+
+import "testing"
+
+func TestJSONCommand(t *testing.T) {
+	runEvalCases(t, []evalCase{
+		{
+			name:    "parse object",
+			content: `set d [json parse {{"name": "toolmin", "tags": ["a", "b c"], "owner": {"id": 7}, "ok": true, "none": null}}]; list [dict get $d name] [lindex [dict get $d tags] 1] [dict get $d owner id] [dict get $d ok] [dict get $d none]`,
+			want:    `toolmin {b c} 7 true {}`,
+		},
+		{
+			name:    "parse keeps numbers as written",
+			content: `json parse {[1.50, 12345678901234567890, -2e3]}`,
+			want:    `1.50 12345678901234567890 -2e3`,
+		},
+		{
+			name:    "parse scalar",
+			content: `json parse {"a \"quoted\" string"}`,
+			want:    `a "quoted" string`,
+		},
+		{
+			name:    "parse invalid",
+			content: `json parse {{"a": }}`,
+			wantErr: "invalid JSON",
+		},
+		{
+			name:    "parse trailing data",
+			content: `json parse {{} {}}`,
+			wantErr: "unexpected data after value",
+		},
+		{
+			name:    "stringify string by default",
+			content: `json stringify {say "hi" <b>}`,
+			want:    `"say \"hi\" <b>"`,
+		},
+		{
+			name:    "stringify number",
+			content: `list [json stringify 42 number] [json stringify 0x1F number] [json stringify 1.5 number]`,
+			want:    `42 31 1.5`,
+		},
+		{
+			name:    "stringify bad number",
+			content: `json stringify abc number`,
+			wantErr: `expected number but got "abc"`,
+		},
+		{
+			name:    "stringify bool and null",
+			content: `list [json stringify yes bool] [json stringify 0 bool] [json stringify anything null]`,
+			want:    `true false null`,
+		},
+		{
+			name:    "stringify auto",
+			content: `list [json stringify 12 auto] [json stringify true auto] [json stringify 007 auto] [json stringify x auto]`,
+			want:    `12 true {"007"} {"x"}`,
+		},
+		{
+			name:    "stringify list",
+			content: `list [json stringify {a {b c}} list] [json stringify {1 2 3} {list number}]`,
+			want:    `{["a","b c"]} {[1,2,3]}`,
+		},
+		{
+			name:    "stringify dict",
+			content: `json stringify [dict create a 1 b 2] {dict number}`,
+			want:    `{"a":1,"b":2}`,
+		},
+		{
+			name:    "stringify object",
+			content: `json stringify [dict create name x count 3 tags {p q} meta {k v}] {object {count number tags list meta {dict auto}}}`,
+			want:    `{"name":"x","count":3,"tags":["p","q"],"meta":{"k":"v"}}`,
+		},
+		{
+			name:    "round trip",
+			content: `json stringify [json parse {{"a":[1,2],"b":"c"}}] {object {a {list number}}}`,
+			want:    `{"a":[1,2],"b":"c"}`,
+		},
+		{
+			name:    "stringify bad type",
+			content: `json stringify x integer`,
+			wantErr: `bad type "integer"`,
+		},
+	})
+}
@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// kvMu serializes increments within the server, so concurrent runs wait
+// for each other rather than contending for SQLite's write lock
+var kvMu sync.Mutex
+
+// registerKV adds storage that lasts between runs, private to the script:
+//
+//	kv get KEY ?default?
+//	kv set KEY VALUE ?-ttl seconds?
+//	kv incr KEY ?amount? ?-ttl seconds?
+//	kv delete KEY
+//	kv keys ?pattern?
+//
+// Keys set with -ttl expire after that many seconds. set replaces a key's
+// expiry; incr only sets it when it creates the key, so a counter with a
+// TTL counts over a fixed window.
+func (r *run) registerKV(interp *tcl.Interp) {
+	interp.Register("kv", r.cmdKV)
+}
+
+func (r *run) cmdKV(i *tcl.Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", tcl.WrongArgs(args[0], "subcommand ?arg ...?")
+	}
+	queries, err := r.queries()
+	if err != nil {
+		return "", err
+	}
+	if r.script.ID == 0 {
+		return "", errors.New("kv is only available to saved scripts")
+	}
+	ctx := i.Context()
+
+	switch args[1] {
+	case "get":
+		if len(args) != 3 && len(args) != 4 {
+			return "", tcl.WrongArgs("kv get", "key ?default?")
+		}
+		v, err := queries.GetKV(ctx, appdb.GetKVParams{ScriptID: r.script.ID, Key: args[2], Now: kvNow()})
+		if errors.Is(err, sql.ErrNoRows) {
+			if len(args) == 4 {
+				return args[3], nil
+			}
+			return "", fmt.Errorf("key \"%s\" not found", args[2])
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read key \"%s\": %w", args[2], err)
+		}
+		return v.Value, nil
+	case "set":
+		rest, ttl, err := cutTTL(args[2:])
+		if err != nil {
+			return "", err
+		}
+		if len(rest) != 2 {
+			return "", tcl.WrongArgs("kv set", "key value ?-ttl seconds?")
+		}
+		if err := r.setKV(ctx, queries, rest[0], rest[1], ttl); err != nil {
+			return "", err
+		}
+		return rest[1], nil
+	case "incr":
+		rest, ttl, err := cutTTL(args[2:])
+		if err != nil {
+			return "", err
+		}
+		if len(rest) != 1 && len(rest) != 2 {
+			return "", tcl.WrongArgs("kv incr", "key ?amount? ?-ttl seconds?")
+		}
+		amount := int64(1)
+		if len(rest) == 2 {
+			n, ok := tcl.ParseInt(rest[1])
+			if !ok {
+				return "", fmt.Errorf("expected integer but got \"%s\"", rest[1])
+			}
+			amount = n
+		}
+		return r.incrKV(ctx, rest[0], amount, ttl)
+	case "delete":
+		if len(args) != 3 {
+			return "", tcl.WrongArgs("kv delete", "key")
+		}
+		if _, err := queries.DeleteKV(ctx, appdb.DeleteKVParams{ScriptID: r.script.ID, Key: args[2]}); err != nil {
+			return "", fmt.Errorf("failed to delete key \"%s\": %w", args[2], err)
+		}
+		return "", nil
+	case "keys":
+		if len(args) > 3 {
+			return "", tcl.WrongArgs("kv keys", "?pattern?")
+		}
+		entries, err := queries.ListKV(ctx, appdb.ListKVParams{ScriptID: r.script.ID, Now: kvNow()})
+		if err != nil {
+			return "", fmt.Errorf("failed to list keys: %w", err)
+		}
+		var keys []string
+		for _, e := range entries {
+			if len(args) == 2 || tcl.Match(args[2], e.Key) {
+				keys = append(keys, e.Key)
+			}
+		}
+		return tcl.FormatList(keys), nil
+	}
+	return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be delete, get, incr, keys or set", args[1])
+}
+
+// setKV stores a value. A zero ttl stores it without an expiry.
+func (r *run) setKV(ctx context.Context, queries *appdb.Queries, key, value string, ttl time.Duration) error {
+	now := kvNow()
+	var expires sql.NullTime
+	if ttl > 0 {
+		expires = appdb.NullTime(now.Time.Add(ttl))
+	}
+	if err := queries.SetKV(ctx, appdb.SetKVParams{
+		ScriptID: r.script.ID,
+		Key:      key,
+		Value:    value,
+		Expires:  expires,
+		Updated:  now.Time,
+	}); err != nil {
+		return fmt.Errorf("failed to set key \"%s\": %w", key, err)
+	}
+	return nil
+}
+
+// incrKV adds amount to an integer value in a transaction and returns the
+// result. Missing and expired keys count as 0 and get the ttl, if any.
+func (r *run) incrKV(ctx context.Context, key string, amount int64, ttl time.Duration) (string, error) {
+	kvMu.Lock()
+	defer kvMu.Unlock()
+
+	tx, err := r.engine.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := appdb.New(r.engine.db).WithTx(tx)
+
+	// Write first, so the transaction holds SQLite's write lock before it
+	// reads. Upgrading a read transaction fails if another writer is busy.
+	now := kvNow()
+	if err := queries.DeleteKVIfExpired(ctx, appdb.DeleteKVIfExpiredParams{ScriptID: r.script.ID, Key: key, Now: now}); err != nil {
+		return "", fmt.Errorf("failed to expire key \"%s\": %w", key, err)
+	}
+
+	var value int64
+	expires := sql.NullTime{}
+	current, err := queries.GetKV(ctx, appdb.GetKVParams{ScriptID: r.script.ID, Key: key, Now: now})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if ttl > 0 {
+			expires = appdb.NullTime(now.Time.Add(ttl))
+		}
+	case err != nil:
+		return "", fmt.Errorf("failed to read key \"%s\": %w", key, err)
+	default:
+		n, ok := tcl.ParseInt(current.Value)
+		if !ok {
+			return "", fmt.Errorf("expected integer but got \"%s\"", current.Value)
+		}
+		value, expires = n, current.Expires
+	}
+	value += amount
+
+	if err := queries.SetKV(ctx, appdb.SetKVParams{
+		ScriptID: r.script.ID,
+		Key:      key,
+		Value:    strconv.FormatInt(value, 10),
+		Expires:  expires,
+		Updated:  now.Time,
+	}); err != nil {
+		return "", fmt.Errorf("failed to set key \"%s\": %w", key, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return strconv.FormatInt(value, 10), nil
+}
+
+// cutTTL removes a trailing -ttl option from args. It comes last so that
+// it can't be confused with a negative amount.
+func cutTTL(args []string) ([]string, time.Duration, error) {
+	if len(args) < 2 || args[len(args)-2] != "-ttl" {
+		return args, 0, nil
+	}
+	seconds, ok := tcl.ParseInt(args[len(args)-1])
+	if !ok || seconds <= 0 {
+		return nil, 0, fmt.Errorf("expected positive integer ttl but got \"%s\"", args[len(args)-1])
+	}
+	return args[:len(args)-2], time.Duration(seconds) * time.Second, nil
+}
+
+// kvNow is the current time as kv stores it: in UTC and whole seconds, so
+// stored times compare correctly as text
+func kvNow() sql.NullTime {
+	return appdb.NullTime(time.Now().UTC().Truncate(time.Second))
+}
+
+// PruneKV deletes expired keys. They are already invisible to scripts.
+func (e *Engine) PruneKV(ctx context.Context) (int64, error) {
+	if e.db == nil {
+		return 0, nil
+	}
+	deleted, err := appdb.New(e.db).DeleteExpiredKV(ctx, kvNow())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune kv: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,206 @@
+package engine_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestKVCommand(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+	eng := engine.New(testDB.DB, nil, nil)
+
+	newScript := func(name, content string) appdb.Script {
+		t.Helper()
+		script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{Name: name, Content: content, AccessLevel: "user"})
+		if err != nil {
+			t.Fatalf("Failed to create script: %v", err)
+		}
+		return script
+	}
+	counter := newScript("counter", `kv incr hits`)
+	other := newScript("other", `kv get hits none`)
+
+	tests := []struct {
+		name    string
+		script  appdb.Script
+		content string
+		want    string
+		wantErr string
+	}{
+		{name: "set returns the value", script: counter, content: `kv set greeting hello`, want: "hello"},
+		{name: "get", script: counter, content: `kv get greeting`, want: "hello"},
+		{name: "get missing", script: counter, content: `kv get nosuch`, wantErr: "key \"nosuch\" not found"},
+		{name: "get default", script: counter, content: `kv get nosuch fallback`, want: "fallback"},
+		{name: "incr creates", script: counter, content: `kv incr hits`, want: "1"},
+		{name: "incr by amount", script: counter, content: `kv incr hits 10`, want: "11"},
+		{name: "incr by negative amount", script: counter, content: `kv incr hits -2`, want: "9"},
+		{name: "incr non-integer", script: counter, content: `kv incr greeting`, wantErr: "expected integer but got \"hello\""},
+		{name: "keys are scoped per script", script: other, content: `kv get hits none`, want: "none"},
+		{name: "keys", script: counter, content: `kv set user:1 a; kv set user:2 b; kv keys`, want: "greeting hits user:1 user:2"},
+		{name: "keys pattern", script: counter, content: `kv keys user:*`, want: "user:1 user:2"},
+		{name: "delete", script: counter, content: `kv delete user:1; kv delete user:1; kv keys user:*`, want: "user:2"},
+		{name: "bad ttl", script: counter, content: `kv set x y -ttl 0`, wantErr: "expected positive integer ttl"},
+		{name: "unknown subcommand", script: counter, content: `kv append x y`, wantErr: "unknown or ambiguous subcommand \"append\""},
+		{name: "unsaved script", script: appdb.Script{Name: "unsaved"}, content: `kv get x`, wantErr: "only available to saved scripts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := tt.script
+			script.Content = tt.content
+			result, err := eng.Run(ctx, script, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run error: %v", err)
+			}
+			if result.Value != tt.want {
+				t.Errorf("value = %q, want %q", result.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestKVExpiry(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+	eng := engine.New(testDB.DB, nil, nil)
+	script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{Name: "cache", Content: "", AccessLevel: "user"})
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	run := func(content string) string {
+		t.Helper()
+		script.Content = content
+		result, err := eng.Run(ctx, script, nil)
+		if err != nil {
+			t.Fatalf("Run %q: %v", content, err)
+		}
+		return result.Value
+	}
+
+	// A key that expired a minute ago
+	past := time.Now().UTC().Truncate(time.Second).Add(-time.Minute)
+	if err := queries.SetKV(ctx, appdb.SetKVParams{
+		ScriptID: script.ID,
+		Key:      "stale",
+		Value:    "41",
+		Expires:  appdb.NullTime(past),
+		Updated:  past,
+	}); err != nil {
+		t.Fatalf("Failed to set key: %v", err)
+	}
+
+	if got := run(`kv get stale gone`); got != "gone" {
+		t.Errorf("expired key = %q, want the default", got)
+	}
+	if got := run(`kv keys`); got != "" {
+		t.Errorf("keys = %q, want none", got)
+	}
+	if got := run(`kv incr stale`); got != "1" {
+		t.Errorf("incr of expired key = %q, want 1", got)
+	}
+
+	run(`kv set session abc -ttl 3600; kv incr window 1 -ttl 60; kv incr window`)
+	entries, err := queries.ListKV(ctx, appdb.ListKVParams{ScriptID: script.ID, Now: appdb.NullTime(time.Now().UTC())})
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	expiry := map[string]time.Duration{}
+	for _, e := range entries {
+		if e.Expires.Valid {
+			expiry[e.Key] = time.Until(e.Expires.Time)
+		}
+	}
+	if d := expiry["session"]; d < 59*time.Minute || d > time.Hour {
+		t.Errorf("session expires in %s, want about an hour", d)
+	}
+	if d := expiry["window"]; d < 58*time.Second || d > time.Minute {
+		t.Errorf("window expires in %s, want about a minute", d)
+	}
+	if _, ok := expiry["stale"]; ok {
+		t.Errorf("stale was recreated with an expiry")
+	}
+	if got := run(`kv get window`); got != "2" {
+		t.Errorf("window = %q, want 2", got)
+	}
+
+	// set replaces the expiry
+	run(`kv set session abc`)
+	if got := run(`kv get session`); got != "abc" {
+		t.Errorf("session = %q, want abc", got)
+	}
+
+	if err := queries.SetKV(ctx, appdb.SetKVParams{
+		ScriptID: script.ID,
+		Key:      "old",
+		Value:    "x",
+		Expires:  appdb.NullTime(past),
+		Updated:  past,
+	}); err != nil {
+		t.Fatalf("Failed to set key: %v", err)
+	}
+	deleted, err := eng.PruneKV(ctx)
+	if err != nil {
+		t.Fatalf("PruneKV: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("pruned %d keys, want 1", deleted)
+	}
+}
+
+func TestKVIncrConcurrent(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+	eng := engine.New(testDB.DB, nil, nil)
+	script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{
+		Name:        "counter",
+		Content:     `for {set i 0} {$i < 5} {incr i} { kv incr hits }`,
+		AccessLevel: "user",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := eng.Run(ctx, script, nil); err != nil {
+				t.Errorf("Run error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	script.Content = `kv get hits`
+	result, err := eng.Run(ctx, script, nil)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if result.Value != "50" {
+		t.Errorf("hits = %s, want 50", result.Value)
+	}
+}
@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// registerPackage adds the package command, which loads libraries from the
+// libraries table:
+//
+//	package require ?-exact? NAME ?VERSION?
+//	package present NAME
+//	package provide NAME ?VERSION?
+//
+// require loads the highest stored version with the same major version
+// that is at least VERSION, or exactly VERSION with -exact, and returns the
+// version loaded. A library is loaded once per run; it is evaluated at the
+// global level and may require other libraries.
+func (r *run) registerPackage(interp *tcl.Interp) {
+	interp.Register("package", r.cmdPackage)
+}
+
+func (r *run) cmdPackage(i *tcl.Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", tcl.WrongArgs(args[0], "subcommand ?arg ...?")
+	}
+	switch args[1] {
+	case "require":
+		rest := args[2:]
+		exact := len(rest) > 0 && rest[0] == "-exact"
+		if exact {
+			rest = rest[1:]
+		}
+		if len(rest) < 1 || len(rest) > 2 || (exact && len(rest) != 2) {
+			return "", tcl.WrongArgs("package require", "?-exact? package ?version?")
+		}
+		var want []int
+		if len(rest) == 2 {
+			v, err := parseVersion(rest[1])
+			if err != nil {
+				return "", err
+			}
+			want = v
+		}
+		return r.requirePackage(i, rest[0], want, exact)
+	case "present":
+		if len(args) != 3 {
+			return "", tcl.WrongArgs("package present", "package")
+		}
+		version, ok := r.packages[args[2]]
+		if !ok {
+			return "", fmt.Errorf("package %s is not present", args[2])
+		}
+		return version, nil
+	case "provide":
+		if len(args) != 3 && len(args) != 4 {
+			return "", tcl.WrongArgs("package provide", "package ?version?")
+		}
+		name := args[2]
+		if len(args) == 3 {
+			return r.packages[name], nil
+		}
+		if _, err := parseVersion(args[3]); err != nil {
+			return "", err
+		}
+		if have, ok := r.packages[name]; ok && have != args[3] {
+			return "", fmt.Errorf("conflicting versions provided for package \"%s\": %s, then %s", name, have, args[3])
+		}
+		r.packages[name] = args[3]
+		return "", nil
+	}
+	return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be present, provide or require", args[1])
+}
+
+// requirePackage loads the best stored version of name unless one is
+// already loaded
+func (r *run) requirePackage(i *tcl.Interp, name string, want []int, exact bool) (string, error) {
+	if have, ok := r.packages[name]; ok {
+		v, err := parseVersion(have)
+		if err != nil {
+			return "", err
+		}
+		if want != nil && !versionSatisfies(v, want, exact) {
+			return "", fmt.Errorf("version conflict for package \"%s\": have %s, need %s", name, have, formatVersion(want))
+		}
+		return have, nil
+	}
+	if slices.Contains(r.loading, name) {
+		return "", fmt.Errorf("package cycle: %s -> %s", strings.Join(r.loading, " -> "), name)
+	}
+
+	queries, err := r.queries()
+	if err != nil {
+		return "", err
+	}
+	ctx := i.Context()
+	versions, err := queries.ListLibraryVersions(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to load package \"%s\": %w", name, err)
+	}
+	var lib *appdb.Library
+	var best []int
+	for j := range versions {
+		v, err := parseVersion(versions[j].Version)
+		if err != nil {
+			continue
+		}
+		if want != nil && !versionSatisfies(v, want, exact) {
+			continue
+		}
+		if lib == nil || compareVersions(v, best) > 0 {
+			lib, best = &versions[j], v
+		}
+	}
+	if lib == nil {
+		if want != nil {
+			return "", fmt.Errorf("can't find package %s %s", name, formatVersion(want))
+		}
+		return "", fmt.Errorf("can't find package %s", name)
+	}
+
+	s, err := r.engine.parsed.parse(cacheKey{"library", lib.ID}, lib.Updated, lib.Content)
+	if err != nil {
+		return "", &packageError{lib: *lib, err: err}
+	}
+	r.loading = append(r.loading, name)
+	_, err = i.EvalScript(ctx, s)
+	r.loading = r.loading[:len(r.loading)-1]
+	if err != nil {
+		return "", &packageError{lib: *lib, err: err}
+	}
+	if have, ok := r.packages[name]; ok && have != lib.Version {
+		return "", fmt.Errorf("package \"%s\" %s provides version %s", name, lib.Version, have)
+	}
+	r.packages[name] = lib.Version
+	return lib.Version, nil
+}
+
+// packageError reports a failure inside a library. It keeps the library's
+// line in its message but doesn't unwrap to the library's *tcl.Error, so
+// the error is placed at the requiring command.
+type packageError struct {
+	lib appdb.Library
+	err error
+}
+
+func (e *packageError) Error() string {
+	return fmt.Sprintf("error loading package \"%s\" %s: %s", e.lib.Name, e.lib.Version, e.err)
+}
+
+func (e *packageError) Unwrap() error {
+	var tclErr *tcl.Error
+	if errors.As(e.err, &tclErr) {
+		return tclErr.Err
+	}
+	return e.err
+}
+
+// parseVersion splits a dotted version into its numbers
+func parseVersion(v string) ([]int, error) {
+	fields := strings.Split(v, ".")
+	version := make([]int, len(fields))
+	for j, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 || f == "" || f[0] == '+' {
+			return nil, fmt.Errorf("expected version number but got \"%s\"", v)
+		}
+		version[j] = n
+	}
+	return version, nil
+}
+
+func formatVersion(v []int) string {
+	fields := make([]string, len(v))
+	for j, n := range v {
+		fields[j] = strconv.Itoa(n)
+	}
+	return strings.Join(fields, ".")
+}
+
+// compareVersions orders versions, treating missing trailing numbers as 0
+func compareVersions(a, b []int) int {
+	for j := 0; j < max(len(a), len(b)); j++ {
+		var x, y int
+		if j < len(a) {
+			x = a[j]
+		}
+		if j < len(b) {
+			y = b[j]
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// versionSatisfies reports whether have meets a request for want: the same
+// major version and no older, or equal if exact
+func versionSatisfies(have, want []int, exact bool) bool {
+	if exact {
+		return compareVersions(have, want) == 0
+	}
+	return have[0] == want[0] && compareVersions(have, want) >= 0
+}
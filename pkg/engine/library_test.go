@@ -0,0 +1,164 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestPackageRequire(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+	for _, lib := range []appdb.CreateLibraryParams{
+		{Name: "greet", Version: "1.0", Content: `proc greet {name} { return "Hello, $name" }`},
+		{Name: "greet", Version: "1.2", Content: `proc greet {name} { return "Hi, $name" }`},
+		{Name: "greet", Version: "1.10", Content: `proc greet {name} { return "Hey, $name" }`},
+		{Name: "greet", Version: "2.0", Content: `proc greet {name} { return "Yo, $name" }`},
+		{Name: "widgets", Version: "1.0", Content: "package require greet 1\nproc banner {name} { return \"<h1>[greet $name]</h1>\" }"},
+		{Name: "counter", Version: "1.0", Content: `incr ::loads`},
+		{Name: "a", Version: "1.0", Content: `package require b`},
+		{Name: "b", Version: "1.0", Content: `package require c`},
+		{Name: "c", Version: "1.0", Content: `package require a`},
+		{Name: "broken", Version: "1.0", Content: "set x 1\nerror boom"},
+		{Name: "liar", Version: "1.0", Content: `package provide liar 2.0`},
+		{Name: "spin", Version: "1.0", Content: `while 1 {}`},
+	} {
+		if _, err := queries.CreateLibrary(ctx, lib); err != nil {
+			t.Fatalf("Failed to create library: %v", err)
+		}
+	}
+
+	eng := engine.New(testDB.DB, nil, nil)
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "highest version",
+			content: `list [package require greet] [greet Ann]`,
+			want:    `2.0 {Yo, Ann}`,
+		},
+		{
+			name:    "highest minor of the major",
+			content: `list [package require greet 1] [greet Ann]`,
+			want:    `1.10 {Hey, Ann}`,
+		},
+		{
+			name:    "at least the requested version",
+			content: `package require greet 1.3`,
+			want:    `1.10`,
+		},
+		{
+			name:    "exact",
+			content: `list [package require -exact greet 1.2] [greet Ann]`,
+			want:    `1.2 {Hi, Ann}`,
+		},
+		{
+			name:    "nested require",
+			content: `package require widgets; list [banner Ann] [package present greet]`,
+			want:    `{<h1>Hey, Ann</h1>} 1.10`,
+		},
+		{
+			name:    "loaded once",
+			content: `set loads 0; package require counter; package require counter 1.0; set loads`,
+			want:    `1`,
+		},
+		{
+			name:    "loaded version must satisfy later requires",
+			content: `package require greet 2; package require greet 1`,
+			wantErr: "version conflict for package \"greet\": have 2.0, need 1",
+		},
+		{
+			name:    "provide",
+			content: `package provide mine 1.0; list [package require mine] [package provide mine]`,
+			want:    `1.0 1.0`,
+		},
+		{
+			name:    "missing",
+			content: `package require nosuch`,
+			wantErr: "can't find package nosuch",
+		},
+		{
+			name:    "no matching version",
+			content: `package require greet 3`,
+			wantErr: "can't find package greet 3",
+		},
+		{
+			name:    "cycle",
+			content: `package require a`,
+			wantErr: "package cycle: a -> b -> c -> a",
+		},
+		{
+			name:    "library error",
+			content: `package require broken`,
+			wantErr: "error loading package \"broken\" 1.0: line 2: boom",
+		},
+		{
+			name:    "library provides another version",
+			content: `package require liar`,
+			wantErr: "package \"liar\" 1.0 provides version 2.0",
+		},
+		{
+			name:    "not present",
+			content: `package present greet`,
+			wantErr: "package greet is not present",
+		},
+		{
+			name:    "bad version",
+			content: `package require greet 1.x`,
+			wantErr: "expected version number but got \"1.x\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := eng.Run(ctx, appdb.Script{Name: "package", Content: tt.content}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run error: %v", err)
+			}
+			if result.Value != tt.want {
+				t.Errorf("value = %q, want %q", result.Value, tt.want)
+			}
+		})
+	}
+
+	// Edits take effect on the next run
+	if _, err := queries.UpdateLibrary(ctx, appdb.UpdateLibraryParams{
+		Name:    "greet",
+		Version: "2.0",
+		Content: `proc greet {name} { return "Howdy, $name" }`,
+	}); err != nil {
+		t.Fatalf("Failed to update library: %v", err)
+	}
+	result, err := eng.Run(ctx, appdb.Script{Name: "package", Content: `package require greet; greet Ann`}, nil)
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if result.Value != "Howdy, Ann" {
+		t.Errorf("value = %q, want %q", result.Value, "Howdy, Ann")
+	}
+
+	// Limits apply to libraries too
+	eng.SetLimits(engine.Limits{MaxCommands: 1000})
+	_, err = eng.Run(ctx, appdb.Script{Name: "package", Content: `package require spin`}, nil)
+	if !errors.Is(err, engine.ErrCommandLimit) {
+		t.Errorf("error = %v, want ErrCommandLimit", err)
+	}
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// Limits bounds the resources a single run may use. A zero field means
+// no limit.
+type Limits struct {
+	// Timeout is the maximum wall time of a run
+	Timeout time.Duration
+	// MaxCommands is the maximum number of Tcl commands a run may evaluate
+	MaxCommands int
+	// MaxOutput is the maximum number of bytes a run may write to stdout
+	// and stderr combined
+	MaxOutput int
+	// MaxValue is the maximum size in bytes of a single value a run may
+	// build, such as a string or list
+	MaxValue int
+}
+
+// DefaultLimits are the limits of a new engine
+var DefaultLimits = Limits{
+	Timeout:     30 * time.Second,
+	MaxCommands: 10_000_000,
+	MaxOutput:   1 << 20,
+	MaxValue:    16 << 20,
+}
+
+var (
+	// ErrTimeout is the cause of a run stopped for exceeding Limits.Timeout
+	ErrTimeout = errors.New("script exceeded its time limit")
+	// ErrOutputLimit is the cause of a run stopped for exceeding Limits.MaxOutput
+	ErrOutputLimit = errors.New("script exceeded its output limit")
+	// ErrCommandLimit is reported when a run exceeds Limits.MaxCommands
+	ErrCommandLimit = tcl.ErrCommandLimit
+	// ErrMemoryLimit is reported when a run builds a value larger than
+	// Limits.MaxValue
+	ErrMemoryLimit = tcl.ErrMemoryLimit
+)
+
+// IsTimeout reports whether err means a run ran out of time, either its
+// own Timeout or the deadline of the caller's context.
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrTimeout) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// withOverrides returns l with the non-zero limits stored for a script
+// applied on top
+func (l Limits) withOverrides(o appdb.ScriptLimit) Limits {
+	if o.TimeoutMs > 0 {
+		l.Timeout = time.Duration(o.TimeoutMs) * time.Millisecond
+	}
+	if o.MaxCommands > 0 {
+		l.MaxCommands = int(o.MaxCommands)
+	}
+	if o.MaxOutputBytes > 0 {
+		l.MaxOutput = int(o.MaxOutputBytes)
+	}
+	return l
+}
+
+// outputBudget is shared by a run's stdout and stderr. Going over it
+// cancels the run, so the script can't catch the error and carry on.
+type outputBudget struct {
+	remaining int
+	cancel    context.CancelCauseFunc
+}
+
+// writer wraps w so that writes count against the budget. Writes that
+// don't fit are truncated.
+func (b *outputBudget) writer(w io.Writer) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		if len(p) <= b.remaining {
+			b.remaining -= len(p)
+			return w.Write(p)
+		}
+		n, _ := w.Write(p[:b.remaining])
+		b.remaining = 0
+		b.cancel(ErrOutputLimit)
+		return n, ErrOutputLimit
+	})
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
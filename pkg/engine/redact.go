@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// Redacted replaces secret values in script output, results and errors
+const Redacted = "[REDACTED]"
+
+// redactor remembers secret values read during a run so they can be
+// scrubbed from anything leaving the engine
+type redactor struct {
+	values   []string
+	replacer *strings.Replacer
+}
+
+func (r *redactor) add(value string) {
+	if value == "" {
+		return
+	}
+	for _, v := range r.values {
+		if v == value {
+			return
+		}
+	}
+	r.values = append(r.values, value)
+	// Longest first, so a secret containing another is fully replaced
+	sort.Slice(r.values, func(a, b int) bool { return len(r.values[a]) > len(r.values[b]) })
+	pairs := make([]string, 0, len(r.values)*2)
+	for _, v := range r.values {
+		pairs = append(pairs, v, Redacted)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+func (r *redactor) redact(s string) string {
+	if r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// redactError returns err with secrets removed from its message. Interpreter
+// errors are copied so their positions are kept.
+func (r *redactor) redactError(err error) error {
+	if r.replacer == nil || err == nil {
+		return err
+	}
+	var tclErr *tcl.Error
+	if errors.As(err, &tclErr) {
+		redacted := *tclErr
+		redacted.Message = r.redact(tclErr.Message)
+		redacted.Command = r.redact(tclErr.Command)
+		redacted.Info = r.redact(tclErr.Info)
+		return &redacted
+	}
+	return errors.New(r.redact(err.Error()))
+}
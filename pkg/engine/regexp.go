@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// maxCachedRegexps bounds the compiled expressions a run keeps
+const maxCachedRegexps = 256
+
+// regexpCache compiles expressions once per run, since scripts often match
+// in a loop
+type regexpCache map[string]*regexp.Regexp
+
+func (c regexpCache) compile(exp string, nocase bool) (*regexp.Regexp, error) {
+	if nocase {
+		exp = "(?i)" + exp
+	}
+	if re, ok := c[exp]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(exp)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't compile regular expression pattern: %w", err)
+	}
+	if len(c) >= maxCachedRegexps {
+		clear(c)
+	}
+	c[exp] = re
+	return re, nil
+}
+
+// registerRegexp adds regexp and regsub, using Go's RE2 syntax:
+//
+//	regexp ?-nocase? ?-all? ?-inline? ?--? EXP STRING ?matchVar? ?subMatchVar ...?
+//	regsub ?-nocase? ?-all? ?--? EXP STRING SUBSPEC ?varName?
+//
+// They work like Tcl's. In SUBSPEC, & and \0 stand for the match and \1 to
+// \9 for submatches.
+func registerRegexp(interp *tcl.Interp) {
+	cache := regexpCache{}
+	interp.Register("regexp", cache.cmdRegexp)
+	interp.Register("regsub", cache.cmdRegsub)
+}
+
+// regexpSwitches parses leading switches, returning the rest of args
+func regexpSwitches(args []string, allowed ...string) (map[string]bool, []string, error) {
+	set := map[string]bool{}
+	j := 1
+	for ; j < len(args) && strings.HasPrefix(args[j], "-"); j++ {
+		if args[j] == "--" {
+			j++
+			break
+		}
+		known := false
+		for _, a := range allowed {
+			if args[j] == a {
+				known = true
+			}
+		}
+		if !known {
+			return nil, nil, fmt.Errorf("bad switch \"%s\": must be %s or --", args[j], strings.Join(allowed, ", "))
+		}
+		set[args[j]] = true
+	}
+	return set, args[j:], nil
+}
+
+func (c regexpCache) cmdRegexp(i *tcl.Interp, args []string) (string, error) {
+	switches, rest, err := regexpSwitches(args, "-all", "-inline", "-nocase")
+	if err != nil {
+		return "", err
+	}
+	if len(rest) < 2 {
+		return "", tcl.WrongArgs(args[0], "?-switch ...? exp string ?matchVar? ?subMatchVar ...?")
+	}
+	vars := rest[2:]
+	if switches["-inline"] && len(vars) > 0 {
+		return "", fmt.Errorf("regexp match variables not allowed when using -inline")
+	}
+	re, err := c.compile(rest[0], switches["-nocase"])
+	if err != nil {
+		return "", err
+	}
+
+	n := 1
+	if switches["-all"] {
+		n = -1
+	}
+	matches := re.FindAllStringSubmatchIndex(rest[1], n)
+	s := rest[1]
+
+	if switches["-inline"] {
+		var items []string
+		for _, m := range matches {
+			for g := 0; g < len(m); g += 2 {
+				items = append(items, submatch(s, m, g/2))
+			}
+		}
+		return tcl.FormatList(items), nil
+	}
+
+	// With -all the variables hold the last match
+	if len(vars) > 0 && len(matches) > 0 {
+		m := matches[len(matches)-1]
+		for g, name := range vars {
+			if err := i.SetVar(name, submatch(s, m, g)); err != nil {
+				return "", err
+			}
+		}
+	} else if len(vars) > 0 {
+		for _, name := range vars {
+			if err := i.SetVar(name, ""); err != nil {
+				return "", err
+			}
+		}
+	}
+	return strconv.Itoa(len(matches)), nil
+}
+
+func (c regexpCache) cmdRegsub(i *tcl.Interp, args []string) (string, error) {
+	switches, rest, err := regexpSwitches(args, "-all", "-nocase")
+	if err != nil {
+		return "", err
+	}
+	if len(rest) != 3 && len(rest) != 4 {
+		return "", tcl.WrongArgs(args[0], "?-switch ...? exp string subSpec ?varName?")
+	}
+	re, err := c.compile(rest[0], switches["-nocase"])
+	if err != nil {
+		return "", err
+	}
+
+	s, spec := rest[1], rest[2]
+	n := 1
+	if switches["-all"] {
+		n = -1
+	}
+	var out strings.Builder
+	last := 0
+	matches := re.FindAllStringSubmatchIndex(s, n)
+	for _, m := range matches {
+		out.WriteString(s[last:m[0]])
+		expandSubSpec(&out, spec, s, m)
+		last = m[1]
+	}
+	out.WriteString(s[last:])
+
+	if len(rest) == 4 {
+		if err := i.SetVar(rest[3], out.String()); err != nil {
+			return "", err
+		}
+		return strconv.Itoa(len(matches)), nil
+	}
+	return out.String(), nil
+}
+
+// submatch returns submatch g of match m in s, or "" if it didn't take part
+func submatch(s string, m []int, g int) string {
+	if 2*g+1 >= len(m) || m[2*g] < 0 {
+		return ""
+	}
+	return s[m[2*g]:m[2*g+1]]
+}
+
+// expandSubSpec writes a regsub substitution for match m
+func expandSubSpec(out *strings.Builder, spec, s string, m []int) {
+	for j := 0; j < len(spec); j++ {
+		switch {
+		case spec[j] == '&':
+			out.WriteString(submatch(s, m, 0))
+		case spec[j] == '\\' && j+1 < len(spec) && spec[j+1] >= '0' && spec[j+1] <= '9':
+			out.WriteString(submatch(s, m, int(spec[j+1]-'0')))
+			j++
+		case spec[j] == '\\' && j+1 < len(spec) && (spec[j+1] == '&' || spec[j+1] == '\\'):
+			out.WriteByte(spec[j+1])
+			j++
+		default:
+			out.WriteByte(spec[j])
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package engine_test
+
+import "testing"
+
+func TestRegexpCommands(t *testing.T) {
+	runEvalCases(t, []evalCase{
+		{
+			name:    "match",
+			content: `list [regexp {^\d+$} 123] [regexp {^\d+$} 12a]`,
+			want:    `1 0`,
+		},
+		{
+			name:    "match variables",
+			content: `regexp {(\w+)@(\w+)\.com} {mail bob@example.com now} all user domain; list $all $user $domain`,
+			want:    `bob@example.com bob example`,
+		},
+		{
+			name:    "unmatched submatch is empty",
+			content: `regexp {(a)|(b)} b all first second; list $all $first $second`,
+			want:    `b {} b`,
+		},
+		{
+			name:    "no match clears variables",
+			content: `set m old; list [regexp {x} abc m] $m`,
+			want:    `0 {}`,
+		},
+		{
+			name:    "nocase",
+			content: `regexp -nocase {^hello} HELLO`,
+			want:    `1`,
+		},
+		{
+			name:    "all counts matches",
+			content: `regexp -all {\d} a1b2c3`,
+			want:    `3`,
+		},
+		{
+			name:    "inline",
+			content: `regexp -inline {(\d+)-(\d+)} {range 10-20}`,
+			want:    `10-20 10 20`,
+		},
+		{
+			name:    "all inline",
+			content: `regexp -all -inline {\d+} {1 22 333}`,
+			want:    `1 22 333`,
+		},
+		{
+			name:    "double dash",
+			content: `regexp -- {-x} a-x`,
+			want:    `1`,
+		},
+		{
+			name:    "bad pattern",
+			content: `regexp {(} x`,
+			wantErr: "couldn't compile regular expression pattern",
+		},
+		{
+			name:    "bad switch",
+			content: `regexp -line x x`,
+			wantErr: `bad switch "-line"`,
+		},
+		{
+			name:    "inline with variables",
+			content: `regexp -inline x x m`,
+			wantErr: "not allowed when using -inline",
+		},
+		{
+			name:    "regsub first",
+			content: `regsub {o} foo 0`,
+			want:    `f0o`,
+		},
+		{
+			name:    "regsub all with submatches",
+			content: `regsub -all {(\w+)=(\w+)} {a=1 b=2} {\2:\1}`,
+			want:    `1:a 2:b`,
+		},
+		{
+			name:    "regsub ampersand",
+			content: `regsub -all {\d+} {a1 b22} {<&>}`,
+			want:    `a<1> b<22>`,
+		},
+		{
+			name:    "regsub escaped ampersand",
+			content: `regsub {x} x {\&\\}`,
+			want:    `&\`,
+		},
+		{
+			name:    "regsub into variable",
+			content: `list [regsub -all -nocase {A} aAb - out] $out`,
+			want:    `2 --b`,
+		},
+	})
+}
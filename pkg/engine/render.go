@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// TemplateFuncs are the functions available to render templates besides
+// the html/template builtins. Tcl values are strings, so templates use
+// list and dict to take nested lists and dicts apart.
+var TemplateFuncs = template.FuncMap{
+	"list": tcl.SplitList,
+	"dict": func(s string) (map[string]string, error) {
+		d, err := tcl.ParseDict(s)
+		if err != nil {
+			return nil, err
+		}
+		return dictMap(d), nil
+	},
+}
+
+// SetTemplates sets the built-in templates the render command falls back to,
+// read from NAME.html in fsys. It must be called before the engine is used.
+func (e *Engine) SetTemplates(fsys fs.FS) {
+	e.templates = fsys
+}
+
+// ParseTemplate parses an html/template snippet with TemplateFuncs. Keys
+// missing from the data render as empty strings.
+func ParseTemplate(name, content string) (*template.Template, error) {
+	return template.New(name).Funcs(TemplateFuncs).Option("missingkey=zero").Parse(content)
+}
+
+// registerRender adds the render command, which fills in an html/template
+// snippet with a dict and returns the HTML, escaped for its context:
+//
+//	render NAME ?dict?
+func (r *run) registerRender(interp *tcl.Interp) {
+	interp.Register("render", r.cmdRender)
+}
+
+func (r *run) cmdRender(i *tcl.Interp, args []string) (string, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return "", tcl.WrongArgs(args[0], "name ?dict?")
+	}
+	data := map[string]string{}
+	if len(args) == 3 {
+		d, err := tcl.ParseDict(args[2])
+		if err != nil {
+			return "", err
+		}
+		data = dictMap(d)
+	}
+
+	tmpl, err := r.template(i, args[1])
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template \"%s\": %w", args[1], err)
+	}
+	return buf.String(), nil
+}
+
+// template returns the named template, from the database if it is there
+// and otherwise from the built-in templates. Templates are parsed once per run.
+func (r *run) template(i *tcl.Interp, name string) (*template.Template, error) {
+	if tmpl, ok := r.parsed[name]; ok {
+		return tmpl, nil
+	}
+
+	content, err := r.loadTemplate(i, name)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := ParseTemplate(name, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template \"%s\": %w", name, err)
+	}
+	if r.parsed == nil {
+		r.parsed = map[string]*template.Template{}
+	}
+	r.parsed[name] = tmpl
+	return tmpl, nil
+}
+
+func (r *run) loadTemplate(i *tcl.Interp, name string) (string, error) {
+	if r.engine.db != nil {
+		t, err := appdb.New(r.engine.db).GetTemplate(i.Context(), name)
+		if err == nil {
+			return t.Content, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("failed to read template \"%s\": %w", name, err)
+		}
+	}
+	if r.engine.templates != nil {
+		content, err := fs.ReadFile(r.engine.templates, name+".html")
+		if err == nil {
+			return string(content), nil
+		}
+	}
+	return "", fmt.Errorf("template \"%s\" not found", name)
+}
+
+// dictMap converts a dict to a map for template data
+func dictMap(d *tcl.Dict) map[string]string {
+	m := make(map[string]string, d.Len())
+	for _, k := range d.Keys() {
+		m[k], _ = d.Get(k)
+	}
+	return m
+}
@@ -0,0 +1,95 @@
+package engine_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestRender(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	if _, err := appdb.New(testDB.DB).CreateTemplate(ctx, appdb.CreateTemplateParams{
+		Name:    "greeting",
+		Content: `<p title="{{.name}}">Hello, {{.name}}</p>`,
+	}); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	eng := engine.New(testDB.DB, nil, nil)
+	eng.SetTemplates(fstest.MapFS{
+		"greeting.html": {Data: []byte(`built-in {{.name}}`)},
+		"items.html":    {Data: []byte(`<ul>{{range list .items}}<li>{{with dict .}}{{.name}}={{.qty}}{{end}}</li>{{end}}</ul>`)},
+		"link.html":     {Data: []byte(`<a href="{{.url}}">link</a>`)},
+		"broken.html":   {Data: []byte(`{{.name`)},
+		"bad.html":      {Data: []byte(`{{range list .items}}{{end}}`)},
+	})
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{"database first", `render greeting {name world}`, `<p title="world">Hello, world</p>`, ""},
+		{"escapes text", `render greeting {name {<script>"x"</script>}}`,
+			`<p title="&lt;script&gt;&#34;x&#34;&lt;/script&gt;">Hello, &lt;script&gt;&#34;x&#34;&lt;/script&gt;</p>`, ""},
+		{"escapes urls", `render link {url javascript:alert(1)}`, `<a href="#ZgotmplZ">link</a>`, ""},
+		{"lists and dicts", `render items [dict create items [list {name apple qty 2} {name pear qty 1}]]`,
+			`<ul><li>apple=2</li><li>pear=1</li></ul>`, ""},
+		{"no data", `render items`, `<ul></ul>`, ""},
+		{"missing", `render nosuch {}`, "", `template "nosuch" not found`},
+		{"parse error", `render broken {}`, "", `failed to parse template "broken"`},
+		{"render error", `render bad [dict create items \{]`, "", `failed to render template "bad"`},
+		{"bad dict", `render greeting {a}`, "", "missing value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := eng.Run(ctx, appdb.Script{Name: "render", Content: tt.content}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run error: %v", err)
+			}
+			if result.Value != tt.want {
+				t.Errorf("value = %q, want %q", result.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	eng := engine.New(nil, nil, nil)
+	eng.SetTemplates(os.DirFS("../server/web/templates/tools"))
+
+	tests := map[string]string{
+		"card":  `render card {title Disk body {92% used} footer <b>}`,
+		"alert": `render alert {level warning message {Low disk}}`,
+		"stat":  `render stat {label Load value 0.42}`,
+		"table": `render table [dict create columns {host load} headers {Host Load} rows [list {host a load 1} {host b load 2}]]`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := eng.Run(context.Background(), appdb.Script{Name: name, Content: content}, nil)
+			if err != nil {
+				t.Fatalf("Run error: %v", err)
+			}
+			if !strings.HasPrefix(result.Value, "<div") || strings.Contains(result.Value, "<b>") {
+				t.Errorf("unexpected output:\n%s", result.Value)
+			}
+		})
+	}
+}
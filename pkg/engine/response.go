@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// Content types a script may choose for its output
+const (
+	ContentTypeText = "text/plain"
+	ContentTypeHTML = "text/html"
+	ContentTypeJSON = "application/json"
+)
+
+var contentTypes = map[string]string{
+	"text":          ContentTypeText,
+	"html":          ContentTypeHTML,
+	"json":          ContentTypeJSON,
+	ContentTypeText: ContentTypeText,
+	ContentTypeHTML: ContentTypeHTML,
+	ContentTypeJSON: ContentTypeJSON,
+}
+
+// registerResponse adds the response command, which lets a script describe
+// how its output should be served:
+//
+//	response type ?text|html|json|MIME?
+func registerResponse(interp *tcl.Interp, result *Result) {
+	interp.Register("response", func(i *tcl.Interp, args []string) (string, error) {
+		if len(args) < 2 {
+			return "", tcl.WrongArgs(args[0], "subcommand ?arg ...?")
+		}
+		switch args[1] {
+		case "type":
+			if len(args) == 2 {
+				return result.ContentType, nil
+			}
+			if len(args) != 3 {
+				return "", tcl.WrongArgs("response type", "?contentType?")
+			}
+			ct, ok := contentTypes[strings.ToLower(args[2])]
+			if !ok {
+				return "", fmt.Errorf("unsupported content type \"%s\": must be %s, %s, or %s", args[2], ContentTypeText, ContentTypeHTML, ContentTypeJSON)
+			}
+			result.ContentType = ct
+			return ct, nil
+		}
+		return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be type", args[1])
+	})
+}
@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ytjohn/toolmin/pkg/tcl"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLVarPrefix starts the vars that register databases for the sql
+// command. The var sql.db.inventory holds the path of the database scripts
+// query as "inventory".
+const SQLVarPrefix = "sql.db."
+
+// MaxSQLRows is the most rows a query may return
+const MaxSQLRows = 10000
+
+// registerSQL adds the sql command, which queries a registered SQLite
+// database read-only:
+//
+//	sql query ALIAS QUERY ?value ...?
+//
+// Values are bound to the query's ? parameters in order. It returns the
+// rows as a list of dicts from column name to value, with NULL as the empty
+// string.
+func (r *run) registerSQL(interp *tcl.Interp) {
+	interp.Register("sql", r.cmdSQL)
+}
+
+func (r *run) cmdSQL(i *tcl.Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", tcl.WrongArgs(args[0], "subcommand ?arg ...?")
+	}
+	if args[1] != "query" {
+		return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be query", args[1])
+	}
+	if len(args) < 4 {
+		return "", tcl.WrongArgs("sql query", "alias query ?value ...?")
+	}
+	alias, query := args[2], args[3]
+	params := make([]any, len(args)-4)
+	for j, v := range args[4:] {
+		params[j] = v
+	}
+
+	ctx := i.Context()
+	path, err := r.sqlPath(ctx, alias)
+	if err != nil {
+		return "", err
+	}
+	db, conn, err := openReadOnly(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open database \"%s\": %w", alias, err)
+	}
+	defer db.Close()
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, query, params...)
+	if err != nil {
+		return "", fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", fmt.Errorf("query failed: %w", err)
+	}
+
+	var result []string
+	values := make([]any, len(columns))
+	ptrs := make([]any, len(columns))
+	for j := range values {
+		ptrs[j] = &values[j]
+	}
+	for rows.Next() {
+		if len(result) == MaxSQLRows {
+			return "", fmt.Errorf("query returned more than %d rows", MaxSQLRows)
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return "", fmt.Errorf("query failed: %w", err)
+		}
+		row := tcl.NewDict()
+		for j, column := range columns {
+			row.Set(column, sqlValue(values[j]))
+		}
+		result = append(result, row.String())
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("query failed: %w", err)
+	}
+	return tcl.FormatList(result), nil
+}
+
+// sqlPath returns the path registered for alias, refusing the toolmin
+// database itself
+func (r *run) sqlPath(ctx context.Context, alias string) (string, error) {
+	queries, err := r.queries()
+	if err != nil {
+		return "", err
+	}
+	v, err := queries.GetVar(ctx, SQLVarPrefix+alias)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("database \"%s\" is not registered", alias)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read var \"%s\": %w", SQLVarPrefix+alias, err)
+	}
+	path := v.Value
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("path of database \"%s\" must be absolute", alias)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("database \"%s\" is not available: %w", alias, err)
+	}
+
+	var own string
+	if err := r.engine.db.QueryRowContext(ctx, "SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&own); err != nil {
+		return "", fmt.Errorf("failed to find the toolmin database: %w", err)
+	}
+	if ownInfo, err := os.Stat(own); err == nil && os.SameFile(info, ownInfo) {
+		return "", fmt.Errorf("database \"%s\" is the toolmin database", alias)
+	}
+	return path, nil
+}
+
+// openReadOnly opens a SQLite file so that it can't be written and no
+// other database can be attached to it. The returned connection is the
+// only one with those limits, so queries must use it.
+func openReadOnly(ctx context.Context, path string) (*sql.DB, *sql.Conn, error) {
+	dsn := (&url.URL{
+		Scheme:   "file",
+		Path:     path,
+		RawQuery: "mode=ro&_pragma=query_only(1)",
+	}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	if _, err := sqlite.Limit(conn, sqlite3.SQLITE_LIMIT_ATTACHED, 0); err != nil {
+		conn.Close()
+		db.Close()
+		return nil, nil, err
+	}
+	return db, conn, nil
+}
+
+// sqlValue formats a column value as a Tcl value
+func sqlValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return tcl.FormatFloat(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return strings.TrimSpace(fmt.Sprint(v))
+}
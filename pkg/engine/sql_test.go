@@ -0,0 +1,155 @@
+package engine_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestSQLCommand(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+
+	// A database another app owns
+	dir := t.TempDir()
+	inventory := filepath.Join(dir, "inventory.db")
+	db, err := sql.Open("sqlite", inventory)
+	if err != nil {
+		t.Fatalf("Failed to open inventory: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE hosts (name TEXT, cpus INTEGER, load REAL, notes TEXT)`,
+		`INSERT INTO hosts VALUES ('web1', 4, 0.5, 'front {end}'), ('db1', 16, 2.25, NULL), ('web2', 4, 0.75, '')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up inventory: %v", err)
+		}
+	}
+	db.Close()
+
+	var own string
+	if err := testDB.QueryRow("SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&own); err != nil {
+		t.Fatalf("Failed to find test database: %v", err)
+	}
+	for key, value := range map[string]string{
+		engine.SQLVarPrefix + "inventory": inventory,
+		engine.SQLVarPrefix + "toolmin":   own,
+		engine.SQLVarPrefix + "relative":  "inventory.db",
+		engine.SQLVarPrefix + "missing":   filepath.Join(dir, "missing.db"),
+	} {
+		if _, err := queries.CreateVar(ctx, appdb.CreateVarParams{Key: key, Value: value}); err != nil {
+			t.Fatalf("Failed to create var: %v", err)
+		}
+	}
+
+	eng := engine.New(testDB.DB, nil, nil)
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "rows as dicts",
+			content: `sql query inventory {SELECT name, cpus, load, notes FROM hosts ORDER BY name}`,
+			want:    `{name db1 cpus 16 load 2.25 notes {}} {name web1 cpus 4 load 0.5 notes {front {end}}} {name web2 cpus 4 load 0.75 notes {}}`,
+		},
+		{
+			name:    "parameters",
+			content: `set n 0; foreach row [sql query inventory {SELECT name FROM hosts WHERE cpus = ? AND name LIKE ?} 4 web%] { incr n }; set n`,
+			want:    `2`,
+		},
+		{
+			name:    "parameters are not SQL",
+			content: `llength [sql query inventory {SELECT name FROM hosts WHERE name = ?} {web1' OR '1'='1}]`,
+			want:    `0`,
+		},
+		{
+			name:    "no rows",
+			content: `sql query inventory {SELECT * FROM hosts WHERE 0}`,
+			want:    ``,
+		},
+		{
+			name:    "write refused",
+			content: `sql query inventory {DELETE FROM hosts}`,
+			wantErr: "readonly database",
+		},
+		{
+			name:    "pragma can't re-enable writes",
+			content: `sql query inventory {PRAGMA query_only = 0; INSERT INTO hosts VALUES ('x', 1, 1, '')}`,
+			wantErr: "readonly database",
+		},
+		{
+			name:    "attach refused",
+			content: `sql query inventory "ATTACH DATABASE '$own' AS t"`,
+			wantErr: "too many attached databases",
+		},
+		{
+			name:    "toolmin database refused",
+			content: `sql query toolmin {SELECT * FROM users}`,
+			wantErr: "database \"toolmin\" is the toolmin database",
+		},
+		{
+			name:    "unregistered",
+			content: `sql query nosuch {SELECT 1}`,
+			wantErr: "database \"nosuch\" is not registered",
+		},
+		{
+			name:    "relative path",
+			content: `sql query relative {SELECT 1}`,
+			wantErr: "must be absolute",
+		},
+		{
+			name:    "missing file",
+			content: `sql query missing {SELECT 1}`,
+			wantErr: "database \"missing\" is not available",
+		},
+		{
+			name:    "unknown subcommand",
+			content: `sql exec inventory {SELECT 1}`,
+			wantErr: "unknown or ambiguous subcommand \"exec\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := eng.Run(ctx, appdb.Script{Name: "sql", Content: tt.content}, map[string]string{"own": own})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run error: %v", err)
+			}
+			if result.Value != tt.want {
+				t.Errorf("value = %q, want %q", result.Value, tt.want)
+			}
+		})
+	}
+
+	// The refused statements changed nothing
+	db, err = sql.Open("sqlite", inventory)
+	if err != nil {
+		t.Fatalf("Failed to open inventory: %v", err)
+	}
+	defer db.Close()
+	var count int
+	if err := db.QueryRow(`SELECT count(*) FROM hosts`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count hosts: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("hosts = %d, want 3", count)
+	}
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// Line is a line of output streamed while a script runs
+type Line struct {
+	// Stderr is set for lines written with puts stderr
+	Stderr bool
+	// Text is the line without its newline, with secrets redacted
+	Text string
+	// ContentType is the response type the script had chosen when the
+	// line was written
+	ContentType string
+}
+
+type linesKey struct{}
+
+type startKey struct{}
+
+// WithStart returns a context that calls fn when a run has its slot and is
+// about to start the script. Runs that fail before then, such as with
+// ErrBusy or by timing out in the queue, never call it.
+func WithStart(ctx context.Context, fn func()) context.Context {
+	return context.WithValue(ctx, startKey{}, fn)
+}
+
+// WithLines returns a context that streams the output of runs to fn, a line
+// at a time, as the script writes it. fn is called on the goroutine running
+// the script. The result still holds all of the output.
+func WithLines(ctx context.Context, fn func(Line)) context.Context {
+	return context.WithValue(ctx, linesKey{}, fn)
+}
+
+// lineWriter passes writes through to w and hands each complete line to
+// the run's line function. A trailing partial line is sent by flush.
+type lineWriter struct {
+	w       io.Writer
+	r       *run
+	fn      func(Line)
+	stderr  bool
+	pending []byte
+}
+
+// streamLines wraps w to stream lines if ctx has a line function
+func (r *run) streamLines(ctx context.Context, w io.Writer, stderr bool) (io.Writer, func()) {
+	fn, _ := ctx.Value(linesKey{}).(func(Line))
+	if fn == nil {
+		return w, func() {}
+	}
+	lw := &lineWriter{w: w, r: r, fn: fn, stderr: stderr}
+	return lw, lw.flush
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	n, err := lw.w.Write(p)
+	lw.pending = append(lw.pending, p[:n]...)
+	for {
+		i := bytes.IndexByte(lw.pending, '\n')
+		if i < 0 {
+			break
+		}
+		lw.send(string(lw.pending[:i]))
+		lw.pending = lw.pending[i+1:]
+	}
+	return n, err
+}
+
+func (lw *lineWriter) flush() {
+	if len(lw.pending) > 0 {
+		lw.send(string(lw.pending))
+		lw.pending = nil
+	}
+}
+
+func (lw *lineWriter) send(text string) {
+	lw.fn(Line{
+		Stderr:      lw.stderr,
+		Text:        lw.r.redactor.redact(text),
+		ContentType: lw.r.result.ContentType,
+	})
+}
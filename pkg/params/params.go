@@ -0,0 +1,304 @@
+// Package params declares the typed inputs of scripts and checks the
+// variables a script is run with against them.
+package params
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+)
+
+// Parameter types
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeBool   = "bool"
+	TypeEnum   = "enum"
+	// TypeSecret takes the key of a secret the script may read, which the
+	// script can pass to "secret get"
+	TypeSecret = "secret"
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Param declares one input of a script
+type Param struct {
+	Name        string   `json:"name" required:"true" maxLength:"100" doc:"Variable name"`
+	Type        string   `json:"type,omitempty" enum:"string,int,bool,enum,secret" doc:"Value type, defaults to string"`
+	Required    bool     `json:"required,omitempty" doc:"Reject runs without a non-empty value"`
+	Default     *string  `json:"default,omitempty" doc:"Value used when none is given"`
+	Pattern     string   `json:"pattern,omitempty" doc:"Regular expression string and secret values must match in full"`
+	Enum        []string `json:"enum,omitempty" doc:"Allowed values of an enum parameter"`
+	Description string   `json:"description,omitempty" doc:"Help text for input forms"`
+
+	// pattern is Pattern compiled, set by Load
+	pattern *regexp.Regexp
+}
+
+// Violation is a problem with one field of a parameter declaration, or with
+// the value given for a parameter
+type Violation struct {
+	Field   string
+	Message string
+	Value   string
+}
+
+func (v Violation) Error() string {
+	return v.Field + ": " + v.Message
+}
+
+func (p Param) typ() string {
+	if p.Type == "" {
+		return TypeString
+	}
+	return p.Type
+}
+
+// Validate checks a parameter declaration. Violations name the field of the
+// declaration they are about.
+func (p Param) Validate() []Violation {
+	var problems []Violation
+	if !validName.MatchString(p.Name) {
+		problems = append(problems, Violation{"name", "must be letters, digits and underscores, not starting with a digit", p.Name})
+	}
+	switch p.typ() {
+	case TypeString, TypeInt, TypeBool, TypeEnum, TypeSecret:
+	default:
+		problems = append(problems, Violation{"type", "must be string, int, bool, enum or secret", p.Type})
+	}
+	if p.Pattern != "" {
+		if p.typ() != TypeString && p.typ() != TypeSecret {
+			problems = append(problems, Violation{"pattern", "only string and secret parameters have a pattern", p.Pattern})
+		} else if _, err := compile(p.Pattern); err != nil {
+			problems = append(problems, Violation{"pattern", err.Error(), p.Pattern})
+		}
+	}
+	if p.typ() == TypeEnum && len(p.Enum) == 0 {
+		problems = append(problems, Violation{"enum", "enum parameters need at least one value", ""})
+	}
+	if p.typ() != TypeEnum && len(p.Enum) > 0 {
+		problems = append(problems, Violation{"enum", "only enum parameters have values", ""})
+	}
+	if p.Default != nil && len(problems) == 0 {
+		// Secret defaults are checked against the script's list when it runs
+		if _, err := p.convert(*p.Default, []string{*p.Default}); err != nil {
+			problems = append(problems, Violation{"default", err.Error(), *p.Default})
+		}
+	}
+	return problems
+}
+
+// Apply checks vars against params and returns the variables to run the
+// script with: defaults are filled in and bools become 1 or 0. secrets are
+// the keys the script may read, which secret parameters must name. Variables
+// without a parameter are passed through unchecked.
+func Apply(params []Param, vars map[string]string, secrets []string) (map[string]string, []Violation) {
+	out := make(map[string]string, len(vars)+len(params))
+	for k, v := range vars {
+		out[k] = v
+	}
+
+	var problems []Violation
+	for _, p := range params {
+		// Empty form fields count as missing
+		value := vars[p.Name]
+		if value == "" {
+			if p.Default != nil {
+				value = *p.Default
+			} else if p.Required {
+				problems = append(problems, Violation{p.Name, "is required", ""})
+				continue
+			} else {
+				continue
+			}
+		}
+		converted, err := p.convert(value, secrets)
+		if err != nil {
+			problems = append(problems, Violation{p.Name, err.Error(), value})
+			continue
+		}
+		out[p.Name] = converted
+	}
+	return out, problems
+}
+
+// convert checks a value against the parameter's type and returns it in
+// the form scripts see
+func (p Param) convert(value string, secrets []string) (string, error) {
+	switch p.typ() {
+	case TypeInt:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("must be an integer")
+		}
+		return strconv.FormatInt(n, 10), nil
+	case TypeBool:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "1", "true", "yes", "on":
+			return "1", nil
+		case "0", "false", "no", "off":
+			return "0", nil
+		}
+		return "", fmt.Errorf("must be true or false")
+	case TypeEnum:
+		if !slices.Contains(p.Enum, value) {
+			return "", fmt.Errorf("must be one of %s", strings.Join(p.Enum, ", "))
+		}
+		return value, nil
+	}
+
+	if p.Pattern != "" {
+		re := p.pattern
+		if re == nil {
+			var err error
+			if re, err = compile(p.Pattern); err != nil {
+				return "", err
+			}
+		}
+		if !re.MatchString(value) {
+			return "", fmt.Errorf("must match %s", p.Pattern)
+		}
+	}
+	if p.typ() == TypeSecret && !slices.Contains(secrets, value) {
+		return "", fmt.Errorf("is not a secret this script may read")
+	}
+	return value, nil
+}
+
+// maxCachedPatterns bounds the compiled patterns kept across requests
+const maxCachedPatterns = 1024
+
+// patterns keeps compiled patterns, so the params loaded for each run don't
+// compile them again
+var patterns struct {
+	mu       sync.Mutex
+	compiled map[string]*regexp.Regexp
+}
+
+// compile compiles a pattern anchored to the whole value, from the cache if
+// it was compiled before
+func compile(pattern string) (*regexp.Regexp, error) {
+	patterns.mu.Lock()
+	re, ok := patterns.compiled[pattern]
+	patterns.mu.Unlock()
+	if ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	patterns.mu.Lock()
+	defer patterns.mu.Unlock()
+	if patterns.compiled == nil || len(patterns.compiled) >= maxCachedPatterns {
+		patterns.compiled = map[string]*regexp.Regexp{}
+	}
+	patterns.compiled[pattern] = re
+	return re, nil
+}
+
+// Load returns a script's parameters in order
+func Load(ctx context.Context, queries *appdb.Queries, scriptID int64) ([]Param, error) {
+	rows, err := queries.ListScriptParams(ctx, scriptID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list script params: %w", err)
+	}
+	params := make([]Param, 0, len(rows))
+	for _, row := range rows {
+		p := Param{
+			Name:        row.Name,
+			Type:        row.Type,
+			Required:    row.Required,
+			Pattern:     row.Pattern,
+			Description: row.Description,
+		}
+		if row.DefaultValue.Valid {
+			p.Default = &row.DefaultValue.String
+		}
+		if err := json.Unmarshal([]byte(row.EnumValues), &p.Enum); err != nil {
+			return nil, fmt.Errorf("invalid enum values for param %q: %w", row.Name, err)
+		}
+		if p.Pattern != "" {
+			if p.pattern, err = compile(p.Pattern); err != nil {
+				return nil, fmt.Errorf("param %q: %w", row.Name, err)
+			}
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// Save replaces a script's parameters. Parameters with an invalid pattern
+// are rejected before anything is changed.
+func Save(ctx context.Context, queries *appdb.Queries, scriptID int64, params []Param) error {
+	for _, p := range params {
+		if p.Pattern == "" {
+			continue
+		}
+		if _, err := compile(p.Pattern); err != nil {
+			return fmt.Errorf("param %q: %w", p.Name, err)
+		}
+	}
+	if err := queries.DeleteScriptParams(ctx, scriptID); err != nil {
+		return fmt.Errorf("failed to clear script params: %w", err)
+	}
+	for i, p := range params {
+		enum := p.Enum
+		if enum == nil {
+			enum = []string{}
+		}
+		enumValues, err := json.Marshal(enum)
+		if err != nil {
+			return fmt.Errorf("failed to encode enum values: %w", err)
+		}
+		var def sql.NullString
+		if p.Default != nil {
+			def = sql.NullString{String: *p.Default, Valid: true}
+		}
+		if err := queries.AddScriptParam(ctx, appdb.AddScriptParamParams{
+			ScriptID:     scriptID,
+			Position:     int64(i),
+			Name:         p.Name,
+			Type:         p.typ(),
+			Required:     p.Required,
+			DefaultValue: def,
+			Pattern:      p.Pattern,
+			EnumValues:   string(enumValues),
+			Description:  p.Description,
+		}); err != nil {
+			return fmt.Errorf("failed to add script param: %w", err)
+		}
+	}
+	return nil
+}
+
+// Check loads a script's parameters and applies them to vars. Scripts
+// without parameters get vars back unchanged.
+func Check(ctx context.Context, queries *appdb.Queries, scriptID int64, vars map[string]string) (map[string]string, []Violation, error) {
+	params, err := Load(ctx, queries, scriptID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(params) == 0 {
+		return vars, nil, nil
+	}
+
+	var secrets []string
+	if slices.ContainsFunc(params, func(p Param) bool { return p.typ() == TypeSecret }) {
+		secrets, err = queries.ListScriptSecrets(ctx, scriptID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list allowed secrets: %w", err)
+		}
+	}
+	vars, problems := Apply(params, vars, secrets)
+	return vars, problems, nil
+}
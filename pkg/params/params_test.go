@@ -0,0 +1,145 @@
+package params_test
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/params"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func ptr(s string) *string { return &s }
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		param params.Param
+		want  []string
+	}{
+		{"plain string", params.Param{Name: "host"}, nil},
+		{"full declaration", params.Param{Name: "env", Type: params.TypeEnum, Enum: []string{"dev", "prod"}, Default: ptr("dev")}, nil},
+		{"secret with pattern", params.Param{Name: "key", Type: params.TypeSecret, Pattern: `api\..*`, Default: ptr("api.github")}, nil},
+		{"bad name", params.Param{Name: "my-var"}, []string{"name"}},
+		{"bad type", params.Param{Name: "x", Type: "float"}, []string{"type"}},
+		{"bad pattern", params.Param{Name: "x", Pattern: "[a-"}, []string{"pattern"}},
+		{"pattern on int", params.Param{Name: "x", Type: params.TypeInt, Pattern: "1"}, []string{"pattern"}},
+		{"enum without values", params.Param{Name: "x", Type: params.TypeEnum}, []string{"enum"}},
+		{"values on string", params.Param{Name: "x", Enum: []string{"a"}}, []string{"enum"}},
+		{"bad int default", params.Param{Name: "x", Type: params.TypeInt, Default: ptr("ten")}, []string{"default"}},
+		{"default not matching", params.Param{Name: "x", Pattern: "[0-9]+", Default: ptr("12a")}, []string{"default"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			for _, v := range tt.param.Validate() {
+				fields = append(fields, v.Field)
+			}
+			if !slices.Equal(fields, tt.want) {
+				t.Errorf("violations on %v, want %v", fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	list := []params.Param{
+		{Name: "host", Required: true, Pattern: `[a-z0-9.-]+`},
+		{Name: "port", Type: params.TypeInt, Default: ptr("443")},
+		{Name: "tls", Type: params.TypeBool, Default: ptr("true")},
+		{Name: "mode", Type: params.TypeEnum, Enum: []string{"ping", "trace"}},
+		{Name: "token", Type: params.TypeSecret},
+	}
+	secrets := []string{"api.token"}
+
+	tests := []struct {
+		name string
+		vars map[string]string
+		want map[string]string
+		errs []string
+	}{
+		{
+			name: "defaults",
+			vars: map[string]string{"host": "example.com"},
+			want: map[string]string{"host": "example.com", "port": "443", "tls": "1"},
+		},
+		{
+			name: "conversions and extra vars",
+			vars: map[string]string{"host": "a.b", "port": " 08 ", "tls": "Off", "mode": "trace", "token": "api.token", "other": "kept"},
+			want: map[string]string{"host": "a.b", "port": "8", "tls": "0", "mode": "trace", "token": "api.token", "other": "kept"},
+		},
+		{
+			name: "empty counts as missing",
+			vars: map[string]string{"host": "", "port": ""},
+			errs: []string{"host: is required"},
+		},
+		{
+			name: "every violation",
+			vars: map[string]string{"host": "a b", "port": "1.5", "tls": "maybe", "mode": "scan", "token": "db.password"},
+			errs: []string{
+				"host: must match [a-z0-9.-]+",
+				"port: must be an integer",
+				"tls: must be true or false",
+				"mode: must be one of ping, trace",
+				"token: is not a secret this script may read",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, problems := params.Apply(list, tt.vars, secrets)
+			var errs []string
+			for _, v := range problems {
+				errs = append(errs, v.Error())
+			}
+			if !slices.Equal(errs, tt.errs) {
+				t.Errorf("violations = %q, want %q", errs, tt.errs)
+			}
+			if tt.errs == nil && !maps.Equal(got, tt.want) {
+				t.Errorf("vars = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+
+	script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{Name: "ping", AccessLevel: "public", Content: "puts $host"})
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	list := []params.Param{{Name: "host", Pattern: `[a-z.]+`}}
+	if err := params.Save(ctx, queries, script.ID, list); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// Bad patterns are rejected without replacing the saved params
+	if err := params.Save(ctx, queries, script.ID, []params.Param{{Name: "host", Pattern: "[a-"}}); err == nil {
+		t.Error("Save accepted an invalid pattern")
+	}
+	loaded, err := params.Load(ctx, queries, script.ID)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Pattern != `[a-z.]+` {
+		t.Fatalf("loaded = %+v", loaded)
+	}
+
+	for value, ok := range map[string]bool{"example.com": true, "a b": false} {
+		_, problems, err := params.Check(ctx, queries, script.ID, map[string]string{"host": value})
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		if (len(problems) == 0) != ok {
+			t.Errorf("%q: violations = %v", value, problems)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	invalid := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+		"@sometimes",
+	}
+	for _, expr := range invalid {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	// Wednesday
+	at := time.Date(2025, time.January, 15, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"* * * * *", true},
+		{"30 9 * * *", true},
+		{"31 9 * * *", false},
+		{"*/15 * * * *", true},
+		{"*/7 * * * *", false},
+		{"0-30/10 * * * *", true},
+		{"5/25 * * * *", true},
+		{"0,30 8-10 * * *", true},
+		{"30 9 15 jan *", true},
+		{"30 9 * FEB *", false},
+		{"30 9 * * wed", true},
+		{"30 9 * * mon-fri", true},
+		{"30 9 * * 0,6", false},
+		{"30 9 * * 7", false},
+		// Both day fields restricted: either may match
+		{"30 9 1 * wed", true},
+		{"30 9 15 * sun", true},
+		{"30 9 1 * sun", false},
+		// Only one restricted: it must match
+		{"30 9 1 * *", false},
+		{"30 9 * * sun", false},
+		{"@daily", false},
+		{"@hourly", false},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := c.Matches(at); got != tt.want {
+			t.Errorf("%q matches %v = %v, want %v", tt.expr, at, got, tt.want)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2025, time.January, 15, 9, 30, 20, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 1, 15, 9, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 1, 15, 9, 45, 0, 0, time.UTC)},
+		{"10 * * * *", time.Date(2025, 1, 15, 10, 10, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * sun", time.Date(2025, 1, 19, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 feb *", time.Time{}},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := c.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q next after %v = %v, want %v", tt.expr, from, got, tt.want)
+		}
+	}
+}
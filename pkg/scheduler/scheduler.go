@@ -0,0 +1,128 @@
+// Package scheduler runs scripts on the cron schedules stored in the
+// schedules table.
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+)
+
+// Scheduler starts scheduled scripts once a minute
+type Scheduler struct {
+	db     *sql.DB
+	engine *engine.Engine
+	log    *slog.Logger
+}
+
+// New creates a scheduler that runs scripts with eng
+func New(db *sql.DB, eng *engine.Engine, log *slog.Logger) *Scheduler {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Scheduler{db: db, engine: eng, log: log}
+}
+
+// Run ticks at the start of every minute until ctx is done. The current
+// minute is ticked straight away, so a tick missed while the process was
+// restarting still runs; ticks that already ran are not repeated.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.log.Debug("starting scheduler")
+	tick := time.Now().Truncate(time.Minute)
+	for {
+		go s.Tick(ctx, tick)
+
+		tick = tick.Add(time.Minute)
+		timer := time.NewTimer(time.Until(tick))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Tick runs every enabled schedule that fires at tick's minute and waits
+// for the runs to finish. Each schedule claims the tick in the database
+// before running, so a tick runs at most once however often it is ticked.
+func (s *Scheduler) Tick(ctx context.Context, tick time.Time) {
+	tick = tick.Truncate(time.Minute)
+	schedules, err := appdb.New(s.db).ListSchedules(ctx)
+	if err != nil {
+		s.log.Error("failed to list schedules", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, sched := range schedules {
+		if !sched.Enabled {
+			continue
+		}
+		cron, err := ParseCron(sched.Cron)
+		if err != nil {
+			s.log.Warn("skipping schedule with invalid cron expression", "schedule", sched.Name, "error", err)
+			continue
+		}
+		if !cron.Matches(tick) {
+			continue
+		}
+		claimed, err := s.claim(ctx, sched.ID, tick)
+		if err != nil {
+			s.log.Error("failed to claim schedule tick", "schedule", sched.Name, "error", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.run(ctx, sched); err != nil {
+				s.log.Warn("scheduled run failed", "schedule", sched.Name, "script", sched.ScriptName, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// claim marks tick as run for the schedule. It reports false if the tick,
+// or a later one, was already claimed.
+func (s *Scheduler) claim(ctx context.Context, id int64, tick time.Time) (bool, error) {
+	at := sql.NullTime{Time: tick.UTC(), Valid: true}
+	n, err := appdb.New(s.db).ClaimScheduleTick(ctx, appdb.ClaimScheduleTickParams{
+		LastTick:   at,
+		ID:         id,
+		LastTick_2: at,
+	})
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// run executes a schedule's script. The engine records the run in the
+// script's history with no user and the schedule as its source.
+func (s *Scheduler) run(ctx context.Context, sched appdb.ListSchedulesRow) error {
+	queries := appdb.New(s.db)
+	script, err := queries.GetScript(ctx, sched.ScriptName)
+	if err != nil {
+		return fmt.Errorf("failed to get script: %w", err)
+	}
+	vars := map[string]string{}
+	if err := json.Unmarshal([]byte(sched.Vars), &vars); err != nil {
+		return fmt.Errorf("invalid schedule vars: %w", err)
+	}
+
+	s.log.Debug("running scheduled script", "schedule", sched.Name, "script", script.Name)
+	_, err = s.engine.Run(engine.WithSource(ctx, engine.SourceSchedule), script, vars)
+	return err
+}
@@ -0,0 +1,96 @@
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/scheduler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestTick(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+
+	script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{Name: "report", Content: `puts "report for $team"`, AccessLevel: "admin"})
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	schedules := []appdb.CreateScheduleParams{
+		{Name: "hourly", ScriptID: script.ID, Cron: "0 * * * *", Vars: `{"team": "ops"}`, Enabled: true},
+		{Name: "never", ScriptID: script.ID, Cron: "30 * * * *", Vars: `{}`, Enabled: true},
+		{Name: "disabled", ScriptID: script.ID, Cron: "* * * * *", Vars: `{}`, Enabled: false},
+		{Name: "invalid", ScriptID: script.ID, Cron: "bogus", Vars: `{}`, Enabled: true},
+	}
+	for _, s := range schedules {
+		if _, err := queries.CreateSchedule(ctx, s); err != nil {
+			t.Fatalf("Failed to create schedule %s: %v", s.Name, err)
+		}
+	}
+
+	runs := func(t *testing.T) []appdb.ListScriptRunsRow {
+		t.Helper()
+		runs, err := queries.ListScriptRuns(ctx, appdb.ListScriptRunsParams{ScriptID: script.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("ListScriptRuns error: %v", err)
+		}
+		return runs
+	}
+
+	sched := scheduler.New(testDB.DB, engine.New(testDB.DB, nil, nil), nil)
+	tick := time.Date(2025, time.March, 1, 14, 0, 0, 0, time.Local)
+
+	t.Run("due schedule runs without a user", func(t *testing.T) {
+		sched.Tick(ctx, tick)
+		got := runs(t)
+		if len(got) != 1 {
+			t.Fatalf("got %d runs, want 1", len(got))
+		}
+		if got[0].Output != "report for ops\n" {
+			t.Errorf("output = %q", got[0].Output)
+		}
+		if got[0].UserID.Valid {
+			t.Errorf("run attributed to user %d, want none", got[0].UserID.Int64)
+		}
+		if got[0].Source != engine.SourceSchedule {
+			t.Errorf("source = %q, want %q", got[0].Source, engine.SourceSchedule)
+		}
+		users, err := queries.ListUsers(ctx)
+		if err != nil {
+			t.Fatalf("ListUsers error: %v", err)
+		}
+		if len(users) != 0 {
+			t.Errorf("scheduler created %d users, want none", len(users))
+		}
+	})
+
+	t.Run("tick runs once", func(t *testing.T) {
+		// A second scheduler, as after a restart within the same minute
+		again := scheduler.New(testDB.DB, engine.New(testDB.DB, nil, nil), nil)
+		again.Tick(ctx, tick.Add(20*time.Second))
+		sched.Tick(ctx, tick)
+		if got := runs(t); len(got) != 1 {
+			t.Errorf("got %d runs, want 1", len(got))
+		}
+	})
+
+	t.Run("earlier tick is not replayed", func(t *testing.T) {
+		sched.Tick(ctx, tick.Add(-time.Hour))
+		if got := runs(t); len(got) != 1 {
+			t.Errorf("got %d runs, want 1", len(got))
+		}
+	})
+
+	t.Run("next tick runs", func(t *testing.T) {
+		sched.Tick(ctx, tick.Add(time.Hour))
+		if got := runs(t); len(got) != 2 {
+			t.Errorf("got %d runs, want 2", len(got))
+		}
+	})
+}
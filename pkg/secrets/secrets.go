@@ -0,0 +1,177 @@
+// Package secrets encrypts values in the secrets table with AES-256-GCM
+// using a key derived from a master passphrase with Argon2id.
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id parameters for deriving the master key
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+	keyLen       = 32
+	saltLen      = 16
+)
+
+// verifierText is encrypted with the master key when it is first created.
+// Decrypting it on startup proves the passphrase is the same one.
+const verifierText = "toolmin secrets verifier"
+
+var (
+	// ErrWrongPassphrase is returned by New when the passphrase doesn't match the stored verifier
+	ErrWrongPassphrase = errors.New("secrets passphrase does not match the stored verifier")
+	// ErrNoPassphrase is returned by New when the passphrase is empty
+	ErrNoPassphrase = errors.New("no secrets passphrase configured")
+)
+
+// Service encrypts and decrypts secret values
+type Service struct {
+	db   *sql.DB
+	aead cipher.AEAD
+}
+
+// LoadPassphrase returns passphrase if set, otherwise the contents of keyFile
+// with surrounding whitespace removed. It returns "" if neither is set.
+func LoadPassphrase(passphrase, keyFile string) (string, error) {
+	if passphrase != "" || keyFile == "" {
+		return passphrase, nil
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secrets key file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// New derives the master key from passphrase. The first time it is called on
+// a database it generates a salt and stores it with a verifier; after that
+// it returns ErrWrongPassphrase unless the passphrase matches.
+func New(ctx context.Context, db *sql.DB, passphrase string) (*Service, error) {
+	if passphrase == "" {
+		return nil, ErrNoPassphrase
+	}
+
+	queries := appdb.New(db)
+	master, err := queries.GetMasterKey(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		master, err = createMasterKey(ctx, queries, passphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load master key: %w", err)
+	}
+
+	aead, err := newAEAD(passphrase, master.Salt)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{db: db, aead: aead}
+
+	plaintext, err := s.Decrypt("", master.Verifier)
+	if err != nil || string(plaintext) != verifierText {
+		return nil, ErrWrongPassphrase
+	}
+	return s, nil
+}
+
+// createMasterKey stores a new salt and verifier. If another process got
+// there first, its row is used instead.
+func createMasterKey(ctx context.Context, queries *appdb.Queries, passphrase string) (appdb.MasterKey, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return appdb.MasterKey{}, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return appdb.MasterKey{}, err
+	}
+	verifier, err := (&Service{aead: aead}).Encrypt("", []byte(verifierText))
+	if err != nil {
+		return appdb.MasterKey{}, err
+	}
+
+	master, err := queries.CreateMasterKey(ctx, appdb.CreateMasterKeyParams{
+		Salt:     salt,
+		Verifier: verifier,
+	})
+	if appdb.IsUniqueViolation(err) {
+		return queries.GetMasterKey(ctx)
+	}
+	return master, err
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, keyLen)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt seals plaintext with a fresh random nonce. The secret's key is
+// used as additional data, so a value can't be moved to another row.
+// The result is the nonce followed by the ciphertext.
+func (s *Service) Encrypt(key string, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(plaintext)+s.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, []byte(key)), nil
+}
+
+// Decrypt opens a value produced by Encrypt for the same key
+func (s *Service) Decrypt(key string, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < s.aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:s.aead.NonceSize()], ciphertext[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, sealed, []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret %q: %w", key, err)
+	}
+	return plaintext, nil
+}
+
+// Create encrypts value and stores it under key
+func (s *Service) Create(ctx context.Context, key, value string) (appdb.Secret, error) {
+	sealed, err := s.Encrypt(key, []byte(value))
+	if err != nil {
+		return appdb.Secret{}, err
+	}
+	return appdb.New(s.db).CreateSecret(ctx, appdb.CreateSecretParams{Key: key, Value: sealed})
+}
+
+// Update encrypts value and replaces the secret stored under key
+func (s *Service) Update(ctx context.Context, key, value string) (appdb.Secret, error) {
+	sealed, err := s.Encrypt(key, []byte(value))
+	if err != nil {
+		return appdb.Secret{}, err
+	}
+	return appdb.New(s.db).UpdateSecret(ctx, appdb.UpdateSecretParams{Key: key, Value: sealed})
+}
+
+// Get returns the decrypted value of the secret stored under key
+func (s *Service) Get(ctx context.Context, key string) (string, error) {
+	secret, err := appdb.New(s.db).GetSecret(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := s.Decrypt(key, secret.Value)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,103 @@
+package secrets_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/secrets"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestService(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+	ctx := context.Background()
+
+	svc, err := secrets.New(ctx, testDB.DB, "correct horse")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	if _, err := svc.Create(ctx, "api_token", "s3cret"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// The stored value must not contain the plaintext
+	row, err := appdb.New(testDB.DB).GetSecret(ctx, "api_token")
+	if err != nil {
+		t.Fatalf("GetSecret: %v", err)
+	}
+	if bytes.Contains(row.Value, []byte("s3cret")) {
+		t.Errorf("secret stored in plaintext")
+	}
+
+	if got, err := svc.Get(ctx, "api_token"); err != nil || got != "s3cret" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+
+	if _, err := svc.Update(ctx, "api_token", "rotated"); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, _ := svc.Get(ctx, "api_token"); got != "rotated" {
+		t.Errorf("Get after update = %q", got)
+	}
+
+	t.Run("same passphrase reopens", func(t *testing.T) {
+		again, err := secrets.New(ctx, testDB.DB, "correct horse")
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		if got, _ := again.Get(ctx, "api_token"); got != "rotated" {
+			t.Errorf("Get = %q", got)
+		}
+	})
+
+	t.Run("wrong passphrase refused", func(t *testing.T) {
+		if _, err := secrets.New(ctx, testDB.DB, "battery staple"); !errors.Is(err, secrets.ErrWrongPassphrase) {
+			t.Errorf("expected ErrWrongPassphrase, got %v", err)
+		}
+	})
+
+	t.Run("empty passphrase refused", func(t *testing.T) {
+		if _, err := secrets.New(ctx, testDB.DB, ""); !errors.Is(err, secrets.ErrNoPassphrase) {
+			t.Errorf("expected ErrNoPassphrase, got %v", err)
+		}
+	})
+
+	t.Run("nonces are unique", func(t *testing.T) {
+		a, _ := svc.Encrypt("k", []byte("same"))
+		b, _ := svc.Encrypt("k", []byte("same"))
+		if bytes.Equal(a, b) {
+			t.Errorf("encrypting twice gave identical ciphertext")
+		}
+	})
+
+	t.Run("value bound to key", func(t *testing.T) {
+		sealed, _ := svc.Encrypt("a", []byte("value"))
+		if _, err := svc.Decrypt("b", sealed); err == nil {
+			t.Errorf("decrypting under another key succeeded")
+		}
+	})
+}
+
+func TestLoadPassphrase(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte("from file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := secrets.LoadPassphrase("from env", keyFile); got != "from env" {
+		t.Errorf("passphrase should take precedence, got %q", got)
+	}
+	if got, _ := secrets.LoadPassphrase("", keyFile); got != "from file" {
+		t.Errorf("key file = %q", got)
+	}
+	if _, err := secrets.LoadPassphrase("", filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("expected error for missing key file")
+	}
+}
@@ -0,0 +1,251 @@
+// Package exechandler manages the allow-list of binaries scripts may run
+// with the exec command.
+package exechandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+)
+
+// Command is a binary on the exec allow-list
+type Command struct {
+	ID          int64     `json:"id"`
+	Path        string    `json:"path" doc:"Absolute path of the binary"`
+	ArgPattern  string    `json:"argPattern" doc:"Regular expression every argument must match in full. Empty allows no arguments."`
+	Description string    `json:"description,omitempty"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+// CommandBody is the editable part of a command
+type CommandBody struct {
+	Path        string `json:"path" required:"true" maxLength:"4096" doc:"Absolute path of the binary"`
+	ArgPattern  string `json:"argPattern,omitempty" doc:"Regular expression every argument must match in full. Empty allows no arguments."`
+	Description string `json:"description,omitempty" maxLength:"1000"`
+}
+
+type CommandPath struct {
+	ID int64 `path:"id" doc:"Command ID"`
+}
+
+type CommandResponse struct {
+	Body Command `json:"body"`
+}
+
+type ListCommandsResponse struct {
+	Body struct {
+		Commands []Command `json:"commands"`
+	} `json:"body"`
+}
+
+type CreateCommandRequest struct {
+	Body CommandBody `json:"body"`
+}
+
+type UpdateCommandRequest struct {
+	ID   int64       `path:"id" doc:"Command ID"`
+	Body CommandBody `json:"body"`
+}
+
+// RegisterExecHandlers registers the exec allow-list endpoints. They are
+// admin only.
+func RegisterExecHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "listExecCommands",
+		Method:      "GET",
+		Path:        "/api/v1/exec/commands",
+		Summary:     "List the commands scripts may run (admin only)",
+		Tags:        []string{"exec"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ListCommands)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "getExecCommand",
+		Method:      "GET",
+		Path:        "/api/v1/exec/commands/{id}",
+		Summary:     "Get an allowed command (admin only)",
+		Tags:        []string{"exec"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, GetCommand)
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "createExecCommand",
+		Method:        "POST",
+		Path:          "/api/v1/exec/commands",
+		Summary:       "Allow a command (admin only)",
+		Tags:          []string{"exec"},
+		DefaultStatus: 201,
+		Security:      []map[string][]string{{"bearerAuth": {}}},
+	}, CreateCommand)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "updateExecCommand",
+		Method:      "PUT",
+		Path:        "/api/v1/exec/commands/{id}",
+		Summary:     "Update an allowed command (admin only)",
+		Tags:        []string{"exec"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, UpdateCommand)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "deleteExecCommand",
+		Method:      "DELETE",
+		Path:        "/api/v1/exec/commands/{id}",
+		Summary:     "Remove a command from the allow-list (admin only)",
+		Tags:        []string{"exec"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, DeleteCommand)
+}
+
+func ListCommands(ctx context.Context, _ *struct{}) (*ListCommandsResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	commands, err := queries.ListExecCommands(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commands: %w", err)
+	}
+
+	response := &ListCommandsResponse{}
+	response.Body.Commands = make([]Command, 0, len(commands))
+	for _, c := range commands {
+		response.Body.Commands = append(response.Body.Commands, toCommand(c))
+	}
+	return response, nil
+}
+
+func GetCommand(ctx context.Context, input *CommandPath) (*CommandResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	c, err := queries.GetExecCommand(ctx, input.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("command not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get command: %w", err)
+	}
+	return &CommandResponse{Body: toCommand(c)}, nil
+}
+
+func CreateCommand(ctx context.Context, input *CreateCommandRequest) (*CommandResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := input.Body.validate(); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	c, err := queries.CreateExecCommand(ctx, appdb.CreateExecCommandParams{
+		Path:        input.Body.Path,
+		ArgPattern:  input.Body.ArgPattern,
+		Description: input.Body.Description,
+	})
+	if appdb.IsUniqueViolation(err) {
+		return nil, huma.Error409Conflict(fmt.Sprintf("command %q is already allowed", input.Body.Path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create command: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("exec command allowed", "path", c.Path, "arg_pattern", c.ArgPattern)
+	return &CommandResponse{Body: toCommand(c)}, nil
+}
+
+func UpdateCommand(ctx context.Context, input *UpdateCommandRequest) (*CommandResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := input.Body.validate(); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	c, err := queries.UpdateExecCommand(ctx, appdb.UpdateExecCommandParams{
+		Path:        input.Body.Path,
+		ArgPattern:  input.Body.ArgPattern,
+		Description: input.Body.Description,
+		ID:          input.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("command not found")
+	}
+	if appdb.IsUniqueViolation(err) {
+		return nil, huma.Error409Conflict(fmt.Sprintf("command %q is already allowed", input.Body.Path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update command: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("exec command updated", "path", c.Path, "arg_pattern", c.ArgPattern)
+	return &CommandResponse{Body: toCommand(c)}, nil
+}
+
+func DeleteCommand(ctx context.Context, input *CommandPath) (*struct{}, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	c, err := queries.GetExecCommand(ctx, input.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("command not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get command: %w", err)
+	}
+	if err := queries.DeleteExecCommand(ctx, input.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete command: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("exec command removed", "path", c.Path)
+	return &struct{}{}, nil
+}
+
+// validate checks the path and argument pattern, reporting both problems
+// at once
+func (b CommandBody) validate() error {
+	var problems []error
+	if err := engine.CheckExecPath(b.Path); err != nil {
+		problems = append(problems, &huma.ErrorDetail{
+			Message:  err.Error(),
+			Location: "body.path",
+			Value:    b.Path,
+		})
+	}
+	if _, err := engine.CompileArgPattern(b.ArgPattern); err != nil {
+		problems = append(problems, &huma.ErrorDetail{
+			Message:  fmt.Sprintf("invalid pattern: %v", err),
+			Location: "body.argPattern",
+			Value:    b.ArgPattern,
+		})
+	}
+	if len(problems) > 0 {
+		return huma.Error422UnprocessableEntity("invalid command", problems...)
+	}
+	return nil
+}
+
+func toCommand(c appdb.ExecCommand) Command {
+	return Command{
+		ID:          c.ID,
+		Path:        c.Path,
+		ArgPattern:  c.ArgPattern,
+		Description: c.Description,
+		Created:     c.Created,
+		Updated:     c.Updated,
+	}
+}
@@ -0,0 +1,92 @@
+package exechandler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ytjohn/toolmin/pkg/server/exechandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestExecHandlers(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	exechandler.RegisterExecHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	ping := map[string]any{"path": "/bin/ping", "argPattern": `-c|[0-9]+|[a-z0-9.-]+`}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		body   any
+		want   int
+	}{
+		{"create", "POST", "/api/v1/exec/commands", admin, ping, http.StatusCreated},
+		{"create as user", "POST", "/api/v1/exec/commands", user, map[string]any{"path": "/bin/ls"}, http.StatusForbidden},
+		{"create duplicate", "POST", "/api/v1/exec/commands", admin, ping, http.StatusConflict},
+		{"create relative path", "POST", "/api/v1/exec/commands", admin, map[string]any{"path": "ls"}, http.StatusUnprocessableEntity},
+		{"create unclean path", "POST", "/api/v1/exec/commands", admin, map[string]any{"path": "/bin/../bin/ls"}, http.StatusUnprocessableEntity},
+		{"create bad pattern", "POST", "/api/v1/exec/commands", admin, map[string]any{"path": "/bin/ls", "argPattern": "("}, http.StatusUnprocessableEntity},
+		{"get", "GET", "/api/v1/exec/commands/1", admin, nil, http.StatusOK},
+		{"get as user", "GET", "/api/v1/exec/commands/1", user, nil, http.StatusForbidden},
+		{"get missing", "GET", "/api/v1/exec/commands/99", admin, nil, http.StatusNotFound},
+		{"list as user", "GET", "/api/v1/exec/commands", user, nil, http.StatusForbidden},
+		{"list anonymous", "GET", "/api/v1/exec/commands", "", nil, http.StatusUnauthorized},
+		{"update", "PUT", "/api/v1/exec/commands/1", admin, map[string]any{"path": "/usr/bin/ping", "argPattern": "[a-z.]+"}, http.StatusOK},
+		{"update bad pattern", "PUT", "/api/v1/exec/commands/1", admin, map[string]any{"path": "/usr/bin/ping", "argPattern": "[a-"}, http.StatusUnprocessableEntity},
+		{"update missing", "PUT", "/api/v1/exec/commands/99", admin, map[string]any{"path": "/bin/ls"}, http.StatusNotFound},
+		{"delete as user", "DELETE", "/api/v1/exec/commands/1", user, nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []any
+			if tt.auth != "" {
+				args = append(args, tt.auth)
+			}
+			if tt.body != nil {
+				args = append(args, tt.body)
+			}
+			resp := api.Do(tt.method, tt.path, args...)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.want, resp.Body.String())
+			}
+		})
+	}
+
+	// Both problems are reported at once
+	resp := api.Post("/api/v1/exec/commands", admin, map[string]any{"path": "ls", "argPattern": "("})
+	if !strings.Contains(resp.Body.String(), "body.path") || !strings.Contains(resp.Body.String(), "body.argPattern") {
+		t.Errorf("errors = %s, want both the path and the pattern", resp.Body.String())
+	}
+
+	resp = api.Get("/api/v1/exec/commands", admin)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("list status = %d: %s", resp.Code, resp.Body.String())
+	}
+	var list struct {
+		Commands []exechandler.Command `json:"commands"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode commands: %v", err)
+	}
+	if len(list.Commands) != 1 || list.Commands[0].Path != "/usr/bin/ping" || list.Commands[0].ArgPattern != "[a-z.]+" {
+		t.Errorf("commands = %+v, want the updated ping", list.Commands)
+	}
+
+	if resp := api.Delete("/api/v1/exec/commands/1", admin); resp.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d: %s", resp.Code, resp.Body.String())
+	}
+	if resp := api.Delete("/api/v1/exec/commands/1", admin); resp.Code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want 404", resp.Code)
+	}
+}
@@ -0,0 +1,238 @@
+package libraryhandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// Library is a version of a Tcl library scripts load with package require
+type Library struct {
+	Name        string    `json:"name" doc:"Library name, as passed to package require"`
+	Version     string    `json:"version" doc:"Version, such as 1.2"`
+	Content     string    `json:"content" doc:"Tcl source"`
+	Description string    `json:"description"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+type LibraryPath struct {
+	Name    string `path:"name" doc:"Library name"`
+	Version string `path:"version" doc:"Library version"`
+}
+
+type LibraryResponse struct {
+	Body Library `json:"body"`
+}
+
+type ListLibrariesResponse struct {
+	Body struct {
+		Libraries []Library `json:"libraries"`
+	} `json:"body"`
+}
+
+type CreateLibraryRequest struct {
+	Body struct {
+		Name        string `json:"name" required:"true" pattern:"^[A-Za-z_][A-Za-z0-9_:.-]*$" maxLength:"100" doc:"Library name, as passed to package require"`
+		Version     string `json:"version" required:"true" pattern:"^[0-9]+(\\.[0-9]+)*$" maxLength:"20" doc:"Version, such as 1.2"`
+		Content     string `json:"content" required:"true" doc:"Tcl source"`
+		Description string `json:"description,omitempty"`
+	} `json:"body"`
+}
+
+type UpdateLibraryRequest struct {
+	Name    string `path:"name" doc:"Library name"`
+	Version string `path:"version" doc:"Library version"`
+	Body    struct {
+		Content     string `json:"content" required:"true" doc:"Tcl source"`
+		Description string `json:"description,omitempty"`
+	} `json:"body"`
+}
+
+// RegisterLibraryHandlers registers the library endpoints. Any
+// authenticated user can read libraries, only admins can change them.
+func RegisterLibraryHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "listLibraries",
+		Method:      "GET",
+		Path:        "/api/v1/libraries",
+		Summary:     "List libraries",
+		Tags:        []string{"libraries"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ListLibraries)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "getLibrary",
+		Method:      "GET",
+		Path:        "/api/v1/libraries/{name}/{version}",
+		Summary:     "Get a library version",
+		Tags:        []string{"libraries"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, GetLibrary)
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "createLibrary",
+		Method:        "POST",
+		Path:          "/api/v1/libraries",
+		Summary:       "Create a library version (admin only)",
+		Tags:          []string{"libraries"},
+		DefaultStatus: 201,
+		Security:      []map[string][]string{{"bearerAuth": {}}},
+	}, CreateLibrary)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "updateLibrary",
+		Method:      "PUT",
+		Path:        "/api/v1/libraries/{name}/{version}",
+		Summary:     "Update a library version (admin only)",
+		Tags:        []string{"libraries"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, UpdateLibrary)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "deleteLibrary",
+		Method:      "DELETE",
+		Path:        "/api/v1/libraries/{name}/{version}",
+		Summary:     "Delete a library version (admin only)",
+		Tags:        []string{"libraries"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, DeleteLibrary)
+}
+
+func ListLibraries(ctx context.Context, _ *struct{}) (*ListLibrariesResponse, error) {
+	if _, err := middleware.RequireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	libraries, err := queries.ListLibraries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list libraries: %w", err)
+	}
+
+	response := &ListLibrariesResponse{}
+	response.Body.Libraries = make([]Library, 0, len(libraries))
+	for _, l := range libraries {
+		response.Body.Libraries = append(response.Body.Libraries, toLibrary(l))
+	}
+	return response, nil
+}
+
+func GetLibrary(ctx context.Context, input *LibraryPath) (*LibraryResponse, error) {
+	if _, err := middleware.RequireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	l, err := queries.GetLibrary(ctx, appdb.GetLibraryParams{Name: input.Name, Version: input.Version})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("library not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library: %w", err)
+	}
+	return &LibraryResponse{Body: toLibrary(l)}, nil
+}
+
+func CreateLibrary(ctx context.Context, input *CreateLibraryRequest) (*LibraryResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	// A library that doesn't parse would break every script requiring it
+	if _, err := tcl.Parse(input.Body.Content); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	l, err := queries.CreateLibrary(ctx, appdb.CreateLibraryParams{
+		Name:        input.Body.Name,
+		Version:     input.Body.Version,
+		Content:     input.Body.Content,
+		Description: input.Body.Description,
+	})
+	if appdb.IsUniqueViolation(err) {
+		return nil, huma.Error409Conflict(fmt.Sprintf("library %q version %s already exists", input.Body.Name, input.Body.Version))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create library: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("library created", "name", l.Name, "version", l.Version)
+	return &LibraryResponse{Body: toLibrary(l)}, nil
+}
+
+func UpdateLibrary(ctx context.Context, input *UpdateLibraryRequest) (*LibraryResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := tcl.Parse(input.Body.Content); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	l, err := queries.UpdateLibrary(ctx, appdb.UpdateLibraryParams{
+		Content:     input.Body.Content,
+		Description: input.Body.Description,
+		Name:        input.Name,
+		Version:     input.Version,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("library not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update library: %w", err)
+	}
+	forgetParsed(ctx, l.ID)
+
+	middleware.GetLogger(ctx).Info("library updated", "name", l.Name, "version", l.Version)
+	return &LibraryResponse{Body: toLibrary(l)}, nil
+}
+
+func DeleteLibrary(ctx context.Context, input *LibraryPath) (*struct{}, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	params := appdb.GetLibraryParams{Name: input.Name, Version: input.Version}
+	l, err := queries.GetLibrary(ctx, params)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("library not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library: %w", err)
+	}
+	if err := queries.DeleteLibrary(ctx, appdb.DeleteLibraryParams(params)); err != nil {
+		return nil, fmt.Errorf("failed to delete library: %w", err)
+	}
+	forgetParsed(ctx, l.ID)
+
+	middleware.GetLogger(ctx).Info("library deleted", "name", input.Name, "version", input.Version)
+	return &struct{}{}, nil
+}
+
+// forgetParsed drops a changed or deleted library from the engine's parse cache
+func forgetParsed(ctx context.Context, id int64) {
+	if eng, ok := ctx.Value(middleware.EngineKey).(*engine.Engine); ok {
+		eng.ForgetLibrary(id)
+	}
+}
+
+func toLibrary(l appdb.Library) Library {
+	return Library{
+		Name:        l.Name,
+		Version:     l.Version,
+		Content:     l.Content,
+		Description: l.Description,
+		Created:     l.Created,
+		Updated:     l.Updated,
+	}
+}
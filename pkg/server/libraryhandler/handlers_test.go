@@ -0,0 +1,88 @@
+package libraryhandler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ytjohn/toolmin/pkg/server/libraryhandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestLibraryHandlers(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	libraryhandler.RegisterLibraryHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	greet := map[string]any{"name": "greet", "version": "1.0", "content": `proc greet {name} { return "Hello, $name" }`}
+	greet2 := map[string]any{"name": "greet", "version": "2.0", "content": `proc greet {name} { return "Hi, $name" }`, "description": "Shorter"}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		body   any
+		want   int
+	}{
+		{"create", "POST", "/api/v1/libraries", admin, greet, http.StatusCreated},
+		{"create another version", "POST", "/api/v1/libraries", admin, greet2, http.StatusCreated},
+		{"create as user", "POST", "/api/v1/libraries", user, map[string]any{"name": "x", "version": "1", "content": "x"}, http.StatusForbidden},
+		{"create duplicate", "POST", "/api/v1/libraries", admin, greet, http.StatusConflict},
+		{"create bad syntax", "POST", "/api/v1/libraries", admin, map[string]any{"name": "x", "version": "1", "content": "proc x {"}, http.StatusUnprocessableEntity},
+		{"create bad name", "POST", "/api/v1/libraries", admin, map[string]any{"name": "a b", "version": "1", "content": "x"}, http.StatusUnprocessableEntity},
+		{"create bad version", "POST", "/api/v1/libraries", admin, map[string]any{"name": "x", "version": "v1", "content": "x"}, http.StatusUnprocessableEntity},
+		{"get as user", "GET", "/api/v1/libraries/greet/1.0", user, nil, http.StatusOK},
+		{"get missing version", "GET", "/api/v1/libraries/greet/3.0", user, nil, http.StatusNotFound},
+		{"list anonymous", "GET", "/api/v1/libraries", "", nil, http.StatusUnauthorized},
+		{"update", "PUT", "/api/v1/libraries/greet/1.0", admin, map[string]any{"content": `proc greet {name} { return "Hey, $name" }`}, http.StatusOK},
+		{"update bad syntax", "PUT", "/api/v1/libraries/greet/1.0", admin, map[string]any{"content": `puts "x`}, http.StatusUnprocessableEntity},
+		{"update missing", "PUT", "/api/v1/libraries/nosuch/1.0", admin, map[string]any{"content": "x"}, http.StatusNotFound},
+		{"delete as user", "DELETE", "/api/v1/libraries/greet/1.0", user, nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []any
+			if tt.auth != "" {
+				args = append(args, tt.auth)
+			}
+			if tt.body != nil {
+				args = append(args, tt.body)
+			}
+			resp := api.Do(tt.method, tt.path, args...)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.want, resp.Body.String())
+			}
+		})
+	}
+
+	resp := api.Get("/api/v1/libraries", user)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("list status = %d: %s", resp.Code, resp.Body.String())
+	}
+	var list struct {
+		Libraries []libraryhandler.Library `json:"libraries"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode libraries: %v", err)
+	}
+	if len(list.Libraries) != 2 || list.Libraries[0].Content != `proc greet {name} { return "Hey, $name" }` || list.Libraries[1].Description != "Shorter" {
+		t.Errorf("libraries = %+v, want the updated 1.0 and 2.0", list.Libraries)
+	}
+
+	if resp := api.Delete("/api/v1/libraries/greet/1.0", admin); resp.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d: %s", resp.Code, resp.Body.String())
+	}
+	if resp := api.Delete("/api/v1/libraries/greet/1.0", admin); resp.Code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want 404", resp.Code)
+	}
+	if resp := api.Get("/api/v1/libraries/greet/2.0", user); resp.Code != http.StatusOK {
+		t.Errorf("other version status = %d, want 200", resp.Code)
+	}
+}
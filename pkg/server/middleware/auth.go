@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -21,6 +24,9 @@ type UserContext struct {
 	LastLogin time.Time
 }
 
+// ErrUnauthorized is returned by Authenticate when a request has no valid bearer token
+var ErrUnauthorized = errors.New("unauthorized")
+
 type AuthConfig struct {
 	TokenService *auth.TokenService
 	API          huma.API
@@ -36,45 +42,86 @@ func WithAuth(ctx huma.Context, next func(huma.Context)) {
 		return
 	}
 
-	// Get the token from the Authorization header
-	authHeader := ctx.Header("Authorization")
-	// slog.Debug("auth middleware", "header", authHeader)
+	// Get services from context
+	tokenService := ctx.Context().Value(TokenServiceKey).(*auth.TokenService)
+	db := ctx.Context().Value(appdb.DbContextKey).(*sql.DB)
 
-	if authHeader == "" {
+	user, token, err := Authenticate(ctx.Context(), tokenService, db, ctx.Header("Authorization"))
+	if errors.Is(err, ErrUnauthorized) {
 		ctx.SetStatus(http.StatusUnauthorized)
 		return
 	}
+	if err != nil {
+		slog.Debug("user lookup failed", "error", err)
+		next(ctx)
+		return
+	}
+
+	// Set user and token in context
+	ctx = huma.WithValue(ctx, UserContextKey, user)
+	ctx = huma.WithValue(ctx, TokenContextKey, token)
+	slog.Debug("auth successful", "user_id", user.ID)
+
+	next(ctx)
+}
+
+// Authenticate validates a "Bearer" Authorization header and loads the user
+// the token belongs to. It returns ErrUnauthorized when the header is missing,
+// malformed or carries an invalid token.
+func Authenticate(ctx context.Context, tokenService *auth.TokenService, db *sql.DB, authHeader string) (appdb.User, string, error) {
+	if authHeader == "" {
+		return appdb.User{}, "", ErrUnauthorized
+	}
 
 	// Check if it's a Bearer token
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		ctx.SetStatus(http.StatusUnauthorized)
-		return
+		return appdb.User{}, "", ErrUnauthorized
 	}
 
-	// Get services from context
-	tokenService := ctx.Context().Value(TokenServiceKey).(*auth.TokenService)
 	claims, err := tokenService.ValidateToken(parts[1], auth.AccessToken)
 	if err != nil {
-		ctx.SetStatus(http.StatusUnauthorized)
-		return
+		return appdb.User{}, "", ErrUnauthorized
 	}
 
 	// Get user from database
-	db := ctx.Context().Value(appdb.DbContextKey).(*sql.DB)
-	queries := appdb.New(db)
-
-	user, err := queries.GetUser(ctx.Context(), claims)
+	user, err := appdb.New(db).GetUser(ctx, claims)
 	if err != nil {
-		slog.Debug("user lookup failed", "error", err)
-		next(ctx)
-		return
+		return appdb.User{}, "", fmt.Errorf("user lookup failed: %w", err)
 	}
+	return user, parts[1], nil
+}
 
-	// Set user and token in context
-	ctx = huma.WithValue(ctx, UserContextKey, user)
-	ctx = huma.WithValue(ctx, TokenContextKey, parts[1])
-	slog.Debug("auth successful", "user_id", user.ID)
+// CurrentUser returns the user WithAuth stored in the context
+func CurrentUser(ctx context.Context) (appdb.User, bool) {
+	switch u := ctx.Value(UserContextKey).(type) {
+	case appdb.User:
+		return u, true
+	case *appdb.User:
+		if u != nil {
+			return *u, true
+		}
+	}
+	return appdb.User{}, false
+}
 
-	next(ctx)
+// RequireUser returns the current user, or a 401 error if there is none
+func RequireUser(ctx context.Context) (appdb.User, error) {
+	user, ok := CurrentUser(ctx)
+	if !ok {
+		return appdb.User{}, huma.Error401Unauthorized("not authenticated")
+	}
+	return user, nil
+}
+
+// RequireAdmin returns the current user, or an error if they aren't an admin
+func RequireAdmin(ctx context.Context) (appdb.User, error) {
+	user, err := RequireUser(ctx)
+	if err != nil {
+		return user, err
+	}
+	if user.Role != "admin" {
+		return user, huma.Error403Forbidden("admin access required")
+	}
+	return user, nil
 }
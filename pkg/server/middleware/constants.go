@@ -10,4 +10,6 @@ const (
 	ResponseWriterKey contextKey = "response_writer"
 	TokenServiceKey   contextKey = "tokenService"
 	TokenContextKey   contextKey = "token"
+	EngineKey         contextKey = "engine"
+	SecretsKey        contextKey = "secrets"
 )
@@ -0,0 +1,366 @@
+package pagehandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+)
+
+// validTrigger matches the htmx triggers a slot may use
+var validTrigger = regexp.MustCompile(`^(load|click|every [1-9][0-9]*s)$`)
+
+// Page is a control panel page made of a grid of tools
+type Page struct {
+	Slug    string    `json:"slug" doc:"The page is served at /{slug}"`
+	Title   string    `json:"title"`
+	Columns int64     `json:"columns" doc:"Number of grid columns on wide screens"`
+	Slots   []Slot    `json:"slots"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// Slot is a grid cell that loads a tool's output
+type Slot struct {
+	Script  string `json:"script" required:"true" doc:"Name of the script to load"`
+	Trigger string `json:"trigger,omitempty" default:"load" doc:"When to load the tool: load, click or every Ns, such as every 30s"`
+	Title   string `json:"title,omitempty" doc:"Heading shown above the tool"`
+}
+
+type PagePath struct {
+	Slug string `path:"slug" doc:"Page slug"`
+}
+
+type PageResponse struct {
+	Body Page `json:"body"`
+}
+
+type ListPagesResponse struct {
+	Body struct {
+		Pages []Page `json:"pages"`
+	} `json:"body"`
+}
+
+// PageBody holds the fields shared by create and update
+type PageBody struct {
+	Title   string `json:"title" required:"true" maxLength:"200"`
+	Columns int64  `json:"columns,omitempty" minimum:"1" maximum:"6" default:"2" doc:"Number of grid columns on wide screens"`
+	Slots   []Slot `json:"slots,omitempty" doc:"Tools on the page, in order. Replaces the current slots."`
+}
+
+type CreatePageRequest struct {
+	Body struct {
+		Slug string `json:"slug" required:"true" pattern:"^[A-Za-z0-9_-]+$" maxLength:"100" doc:"The page is served at /{slug}"`
+		PageBody
+	} `json:"body"`
+}
+
+type UpdatePageRequest struct {
+	Slug string   `path:"slug" doc:"Page slug"`
+	Body PageBody `json:"body"`
+}
+
+// RegisterPageHandlers registers the page endpoints. Any authenticated user
+// can read pages, only admins can change them.
+func RegisterPageHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "listPages",
+		Method:      "GET",
+		Path:        "/api/v1/pages",
+		Summary:     "List pages",
+		Tags:        []string{"pages"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ListPages)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "getPage",
+		Method:      "GET",
+		Path:        "/api/v1/pages/{slug}",
+		Summary:     "Get a page",
+		Tags:        []string{"pages"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, GetPage)
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "createPage",
+		Method:        "POST",
+		Path:          "/api/v1/pages",
+		Summary:       "Create a page (admin only)",
+		Tags:          []string{"pages"},
+		DefaultStatus: 201,
+		Security:      []map[string][]string{{"bearerAuth": {}}},
+	}, CreatePage)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "updatePage",
+		Method:      "PUT",
+		Path:        "/api/v1/pages/{slug}",
+		Summary:     "Update a page (admin only)",
+		Tags:        []string{"pages"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, UpdatePage)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "deletePage",
+		Method:      "DELETE",
+		Path:        "/api/v1/pages/{slug}",
+		Summary:     "Delete a page (admin only)",
+		Tags:        []string{"pages"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, DeletePage)
+}
+
+func ListPages(ctx context.Context, _ *struct{}) (*ListPagesResponse, error) {
+	if _, err := middleware.RequireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	pages, err := queries.ListPages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pages: %w", err)
+	}
+
+	response := &ListPagesResponse{}
+	response.Body.Pages = make([]Page, 0, len(pages))
+	for _, p := range pages {
+		page, err := toPage(ctx, queries, p)
+		if err != nil {
+			return nil, err
+		}
+		response.Body.Pages = append(response.Body.Pages, page)
+	}
+	return response, nil
+}
+
+func GetPage(ctx context.Context, input *PagePath) (*PageResponse, error) {
+	if _, err := middleware.RequireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	p, err := loadPage(ctx, queries, input.Slug)
+	if err != nil {
+		return nil, err
+	}
+	return pageResponse(ctx, queries, p)
+}
+
+func CreatePage(ctx context.Context, input *CreatePageRequest) (*PageResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	db := ctx.Value(appdb.DbContextKey).(*sql.DB)
+	if err := validateSlots(ctx, appdb.New(db), input.Body.Slots); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := appdb.New(db).WithTx(tx)
+
+	p, err := queries.CreatePage(ctx, appdb.CreatePageParams{
+		Slug:    input.Body.Slug,
+		Title:   input.Body.Title,
+		Columns: columns(input.Body.Columns),
+	})
+	if appdb.IsUniqueViolation(err) {
+		return nil, huma.Error409Conflict(fmt.Sprintf("page %q already exists", input.Body.Slug))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+	if err := setSlots(ctx, queries, p.ID, input.Body.Slots); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit page: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("page created", "slug", p.Slug, "slots", len(input.Body.Slots))
+	return pageResponse(ctx, appdb.New(db), p)
+}
+
+func UpdatePage(ctx context.Context, input *UpdatePageRequest) (*PageResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	db := ctx.Value(appdb.DbContextKey).(*sql.DB)
+	if err := validateSlots(ctx, appdb.New(db), input.Body.Slots); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := appdb.New(db).WithTx(tx)
+
+	p, err := queries.UpdatePage(ctx, appdb.UpdatePageParams{
+		Title:   input.Body.Title,
+		Columns: columns(input.Body.Columns),
+		Slug:    input.Slug,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("page not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update page: %w", err)
+	}
+	if err := setSlots(ctx, queries, p.ID, input.Body.Slots); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit page: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("page updated", "slug", p.Slug, "slots", len(input.Body.Slots))
+	return pageResponse(ctx, appdb.New(db), p)
+}
+
+func DeletePage(ctx context.Context, input *PagePath) (*struct{}, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	db := ctx.Value(appdb.DbContextKey).(*sql.DB)
+	p, err := loadPage(ctx, appdb.New(db), input.Slug)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := appdb.New(db).WithTx(tx)
+
+	// Foreign keys aren't enforced on every connection, so don't rely on cascades
+	if err := queries.DeletePageSlots(ctx, p.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete page: %w", err)
+	}
+	if err := queries.DeletePage(ctx, p.Slug); err != nil {
+		return nil, fmt.Errorf("failed to delete page: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to delete page: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("page deleted", "slug", p.Slug)
+	return &struct{}{}, nil
+}
+
+// loadPage fetches a page by slug, mapping a missing row to a 404
+func loadPage(ctx context.Context, queries *appdb.Queries, slug string) (appdb.Page, error) {
+	p, err := queries.GetPage(ctx, slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, huma.Error404NotFound("page not found")
+	}
+	if err != nil {
+		return p, fmt.Errorf("failed to get page: %w", err)
+	}
+	return p, nil
+}
+
+// validateSlots checks every slot's trigger and that its script exists,
+// reporting all the problems at once
+func validateSlots(ctx context.Context, queries *appdb.Queries, slots []Slot) error {
+	var problems []error
+	for i, slot := range slots {
+		if !validTrigger.MatchString(trigger(slot)) {
+			problems = append(problems, &huma.ErrorDetail{
+				Message:  fmt.Sprintf("invalid trigger %q, must be load, click or every Ns", slot.Trigger),
+				Location: fmt.Sprintf("body.slots[%d].trigger", i),
+				Value:    slot.Trigger,
+			})
+		}
+		_, err := queries.GetScript(ctx, slot.Script)
+		if errors.Is(err, sql.ErrNoRows) {
+			problems = append(problems, &huma.ErrorDetail{
+				Message:  fmt.Sprintf("script %q not found", slot.Script),
+				Location: fmt.Sprintf("body.slots[%d].script", i),
+				Value:    slot.Script,
+			})
+		} else if err != nil {
+			return fmt.Errorf("failed to get script: %w", err)
+		}
+	}
+	if len(problems) > 0 {
+		return huma.Error422UnprocessableEntity("invalid slots", problems...)
+	}
+	return nil
+}
+
+// setSlots replaces a page's slots
+func setSlots(ctx context.Context, queries *appdb.Queries, pageID int64, slots []Slot) error {
+	if err := queries.DeletePageSlots(ctx, pageID); err != nil {
+		return fmt.Errorf("failed to clear page slots: %w", err)
+	}
+	for i, slot := range slots {
+		if err := queries.AddPageSlot(ctx, appdb.AddPageSlotParams{
+			PageID:    pageID,
+			Position:  int64(i),
+			Script:    slot.Script,
+			HxTrigger: trigger(slot),
+			Title:     slot.Title,
+		}); err != nil {
+			return fmt.Errorf("failed to add page slot: %w", err)
+		}
+	}
+	return nil
+}
+
+func trigger(slot Slot) string {
+	if slot.Trigger == "" {
+		return "load"
+	}
+	return strings.TrimSpace(slot.Trigger)
+}
+
+func columns(n int64) int64 {
+	if n == 0 {
+		return 2
+	}
+	return n
+}
+
+func pageResponse(ctx context.Context, queries *appdb.Queries, p appdb.Page) (*PageResponse, error) {
+	page, err := toPage(ctx, queries, p)
+	if err != nil {
+		return nil, err
+	}
+	return &PageResponse{Body: page}, nil
+}
+
+func toPage(ctx context.Context, queries *appdb.Queries, p appdb.Page) (Page, error) {
+	slots, err := queries.ListPageSlots(ctx, p.ID)
+	if err != nil {
+		return Page{}, fmt.Errorf("failed to list page slots: %w", err)
+	}
+	page := Page{
+		Slug:    p.Slug,
+		Title:   p.Title,
+		Columns: p.Columns,
+		Slots:   make([]Slot, 0, len(slots)),
+		Created: p.Created,
+		Updated: p.Updated,
+	}
+	for _, s := range slots {
+		page.Slots = append(page.Slots, Slot{Script: s.Script, Trigger: s.HxTrigger, Title: s.Title})
+	}
+	return page, nil
+}
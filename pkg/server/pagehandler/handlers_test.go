@@ -0,0 +1,211 @@
+package pagehandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/server/pagehandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestPageHandlers(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	pagehandler.RegisterPageHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	for _, name := range []string{"uptime", "disk"} {
+		if _, err := appdb.New(testDB.DB).CreateScript(context.Background(), appdb.CreateScriptParams{
+			Name: name, Content: "puts ok", AccessLevel: "user",
+		}); err != nil {
+			t.Fatalf("Failed to create script: %v", err)
+		}
+	}
+
+	ops := map[string]any{
+		"slug":    "ops",
+		"title":   "Operations",
+		"columns": 3,
+		"slots": []map[string]any{
+			{"script": "uptime", "title": "Uptime"},
+			{"script": "disk", "trigger": "every 30s"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		body   any
+		want   int
+	}{
+		{"create", "POST", "/api/v1/pages", admin, ops, http.StatusCreated},
+		{"create as user", "POST", "/api/v1/pages", user, map[string]any{"slug": "x", "title": "X"}, http.StatusForbidden},
+		{"create duplicate", "POST", "/api/v1/pages", admin, map[string]any{"slug": "ops", "title": "X"}, http.StatusConflict},
+		{"create bad slug", "POST", "/api/v1/pages", admin, map[string]any{"slug": "a/b", "title": "X"}, http.StatusUnprocessableEntity},
+		{"get as user", "GET", "/api/v1/pages/ops", user, nil, http.StatusOK},
+		{"get missing", "GET", "/api/v1/pages/nosuch", user, nil, http.StatusNotFound},
+		{"list anonymous", "GET", "/api/v1/pages", "", nil, http.StatusUnauthorized},
+		{"update missing", "PUT", "/api/v1/pages/nosuch", admin, map[string]any{"title": "X"}, http.StatusNotFound},
+		{"delete as user", "DELETE", "/api/v1/pages/ops", user, nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []any
+			if tt.auth != "" {
+				args = append(args, tt.auth)
+			}
+			if tt.body != nil {
+				args = append(args, tt.body)
+			}
+			resp := api.Do(tt.method, tt.path, args...)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.want, resp.Body.String())
+			}
+		})
+	}
+
+	getPage := func(t *testing.T, slug string) pagehandler.Page {
+		t.Helper()
+		resp := api.Get("/api/v1/pages/"+slug, user)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", resp.Code, resp.Body.String())
+		}
+		var p pagehandler.Page
+		if err := json.Unmarshal(resp.Body.Bytes(), &p); err != nil {
+			t.Fatalf("decode page: %v", err)
+		}
+		return p
+	}
+
+	t.Run("slots are kept in order", func(t *testing.T) {
+		p := getPage(t, "ops")
+		want := []pagehandler.Slot{
+			{Script: "uptime", Trigger: "load", Title: "Uptime"},
+			{Script: "disk", Trigger: "every 30s"},
+		}
+		if p.Title != "Operations" || p.Columns != 3 || len(p.Slots) != len(want) {
+			t.Fatalf("page = %+v", p)
+		}
+		for i := range want {
+			if p.Slots[i] != want[i] {
+				t.Errorf("slot %d = %+v, want %+v", i, p.Slots[i], want[i])
+			}
+		}
+	})
+
+	t.Run("invalid slots are all reported", func(t *testing.T) {
+		resp := api.Put("/api/v1/pages/ops", admin, map[string]any{
+			"title": "Operations",
+			"slots": []map[string]any{
+				{"script": "uptime", "trigger": "every 0s"},
+				{"script": "nosuch"},
+				{"script": "disk", "trigger": "mouseover"},
+			},
+		})
+		if resp.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("status = %d, want 422: %s", resp.Code, resp.Body.String())
+		}
+		for _, want := range []string{"slots[0].trigger", "slots[1].script", "slots[2].trigger"} {
+			if !strings.Contains(resp.Body.String(), want) {
+				t.Errorf("response doesn't mention %s: %s", want, resp.Body.String())
+			}
+		}
+		if p := getPage(t, "ops"); len(p.Slots) != 2 {
+			t.Errorf("rejected update changed the slots: %+v", p.Slots)
+		}
+	})
+
+	t.Run("update replaces slots", func(t *testing.T) {
+		resp := api.Put("/api/v1/pages/ops", admin, map[string]any{
+			"title": "Ops",
+			"slots": []map[string]any{{"script": "disk", "trigger": "click"}},
+		})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", resp.Code, resp.Body.String())
+		}
+		p := getPage(t, "ops")
+		if p.Title != "Ops" || p.Columns != 2 || len(p.Slots) != 1 || p.Slots[0].Trigger != "click" {
+			t.Errorf("page = %+v", p)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if resp := api.Delete("/api/v1/pages/ops", admin); resp.Code != http.StatusNoContent {
+			t.Fatalf("status = %d: %s", resp.Code, resp.Body.String())
+		}
+		if resp := api.Get("/api/v1/pages/ops", user); resp.Code != http.StatusNotFound {
+			t.Errorf("status after delete = %d, want 404", resp.Code)
+		}
+	})
+}
+
+func TestServePage(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+	p, err := queries.CreatePage(ctx, appdb.CreatePageParams{Slug: "ops", Title: "Ops <b>", Columns: 2})
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	slots := []appdb.AddPageSlotParams{
+		{PageID: p.ID, Position: 0, Script: "uptime", HxTrigger: "every 10s", Title: "Uptime"},
+		{PageID: p.ID, Position: 1, Script: "restart", HxTrigger: "click"},
+	}
+	for _, s := range slots {
+		if err := queries.AddPageSlot(ctx, s); err != nil {
+			t.Fatalf("Failed to add slot: %v", err)
+		}
+	}
+
+	h := pagehandler.New(testDB.DB, slog.Default())
+
+	rr := httptest.NewRecorder()
+	if !h.ServePage(rr, httptest.NewRequest("GET", "/templates/pages/ops.html", nil), "ops") {
+		t.Fatal("ServePage didn't find the page")
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"Ops &lt;b&gt;",
+		`md:grid-cols-2`,
+		`hx-get="/tools/uptime" hx-trigger="load, every 10s"`,
+		`<h2 class="text-lg font-semibold mb-2">Uptime</h2>`,
+		`hx-get="/tools/restart" hx-target="next .tool-output"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page doesn't contain %q:\n%s", want, body)
+		}
+	}
+
+	if h.ServePage(httptest.NewRecorder(), httptest.NewRequest("GET", "/templates/pages/nosuch.html", nil), "nosuch") {
+		t.Error("ServePage found a missing page")
+	}
+
+	slugs := map[string]string{
+		"/templates/pages/ops.html":  "ops",
+		"/templates/pages/a/b.html":  "",
+		"/templates/pages/.html":     "",
+		"/templates/partials/x.html": "",
+		"/templates/pages/ops.htmlx": "",
+	}
+	for path, want := range slugs {
+		got, ok := pagehandler.TemplateSlug(path)
+		if got != want || ok != (want != "") {
+			t.Errorf("TemplateSlug(%q) = %q, %v; want %q", path, got, ok, want)
+		}
+	}
+}
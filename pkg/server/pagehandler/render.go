@@ -0,0 +1,122 @@
+// Package pagehandler manages control panel pages stored in the database
+// and renders them for the SPA.
+package pagehandler
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+)
+
+// pageTemplate renders a page into the SPA's content area. Each slot loads
+// its tool with htmx; click slots wait for their button.
+var pageTemplate = template.Must(template.New("page").Parse(`<div class="p-6">
+    <h1 class="text-2xl font-bold mb-4">{{.Title}}</h1>
+    <div class="grid gap-4 grid-cols-1 md:grid-cols-{{.Columns}}">
+        {{- range .Slots}}
+        <div class="p-4 bg-white rounded-lg shadow-md">
+            {{- if .Title}}
+            <h2 class="text-lg font-semibold mb-2">{{.Title}}</h2>
+            {{- end}}
+            {{- if eq .Trigger "click"}}
+            <button class="mb-2 rounded-md bg-primary px-3 py-1 text-sm text-primary-foreground"
+                    hx-get="{{.URL}}" hx-target="next .tool-output" hx-swap="innerHTML">Run</button>
+            <div class="tool-output"></div>
+            {{- else}}
+            <div class="tool-output" hx-get="{{.URL}}" hx-trigger="{{.Trigger}}" hx-swap="innerHTML"></div>
+            {{- end}}
+        </div>
+        {{- end}}
+    </div>
+</div>
+`))
+
+type slotView struct {
+	Title   string
+	URL     string
+	Trigger string
+}
+
+// Handler renders pages from the database
+type Handler struct {
+	db  *sql.DB
+	log *slog.Logger
+}
+
+// New creates a new page handler
+func New(db *sql.DB, log *slog.Logger) *Handler {
+	return &Handler{db: db, log: log}
+}
+
+// ServePage writes the page with the given slug as an HTML fragment. It
+// reports false, writing nothing, if there is no such page.
+func (h *Handler) ServePage(w http.ResponseWriter, r *http.Request, slug string) bool {
+	queries := appdb.New(h.db)
+	p, err := queries.GetPage(r.Context(), slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		h.log.Error("failed to load page", "slug", slug, "error", err)
+		http.Error(w, "failed to load page", http.StatusInternalServerError)
+		return true
+	}
+	slots, err := queries.ListPageSlots(r.Context(), p.ID)
+	if err != nil {
+		h.log.Error("failed to load page slots", "slug", slug, "error", err)
+		http.Error(w, "failed to load page", http.StatusInternalServerError)
+		return true
+	}
+
+	view := struct {
+		Title   string
+		Columns int64
+		Slots   []slotView
+	}{Title: p.Title, Columns: p.Columns}
+	for _, s := range slots {
+		trigger := s.HxTrigger
+		if strings.HasPrefix(trigger, "every ") {
+			// Show the tool straight away rather than after the first interval
+			trigger = "load, " + trigger
+		}
+		view.Slots = append(view.Slots, slotView{
+			Title:   s.Title,
+			URL:     "/tools/" + url.PathEscape(s.Script),
+			Trigger: trigger,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := pageTemplate.Execute(&buf, view); err != nil {
+		h.log.Error("failed to render page", "slug", slug, "error", err)
+		http.Error(w, "failed to render page", http.StatusInternalServerError)
+		return true
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := buf.WriteTo(w); err != nil {
+		h.log.Debug("failed to write page", "slug", slug, "error", err)
+	}
+	return true
+}
+
+// TemplateSlug returns the page slug of a page template path such as
+// /templates/pages/ops.html, as requested by the SPA router
+func TemplateSlug(path string) (string, bool) {
+	slug, ok := strings.CutPrefix(path, "/templates/pages/")
+	if !ok {
+		return "", false
+	}
+	slug, ok = strings.CutSuffix(slug, ".html")
+	if !ok || slug == "" || strings.Contains(slug, "/") {
+		return "", false
+	}
+	return slug, true
+}
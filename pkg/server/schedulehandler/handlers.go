@@ -0,0 +1,286 @@
+package schedulehandler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/scheduler"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+)
+
+// Schedule runs a script on a cron schedule
+type Schedule struct {
+	Name    string            `json:"name"`
+	Script  string            `json:"script" doc:"Name of the script to run"`
+	Cron    string            `json:"cron" doc:"Five field cron expression, in the server's time zone"`
+	Vars    map[string]string `json:"vars" doc:"Input variables passed to the script"`
+	Enabled bool              `json:"enabled"`
+	LastRun *time.Time        `json:"lastRun,omitempty" doc:"The most recent scheduled time the script ran"`
+	NextRun *time.Time        `json:"nextRun,omitempty" doc:"When the script will next run, if enabled"`
+	Created time.Time         `json:"created"`
+	Updated time.Time         `json:"updated"`
+}
+
+type SchedulePath struct {
+	Name string `path:"name" doc:"Schedule name"`
+}
+
+type ScheduleResponse struct {
+	Body Schedule `json:"body"`
+}
+
+type ListSchedulesResponse struct {
+	Body struct {
+		Schedules []Schedule `json:"schedules"`
+	} `json:"body"`
+}
+
+// ScheduleBody holds the fields shared by create and update
+type ScheduleBody struct {
+	Script  string            `json:"script" required:"true" doc:"Name of the script to run"`
+	Cron    string            `json:"cron" required:"true" doc:"Five field cron expression such as \"*/5 * * * *\" or \"@daily\""`
+	Vars    map[string]string `json:"vars,omitempty" doc:"Input variables passed to the script"`
+	Enabled *bool             `json:"enabled,omitempty" doc:"Whether the schedule runs, defaults to true"`
+}
+
+type CreateScheduleRequest struct {
+	Body struct {
+		Name string `json:"name" required:"true" pattern:"^[A-Za-z0-9_.-]+$" maxLength:"100" doc:"Schedule name"`
+		ScheduleBody
+	} `json:"body"`
+}
+
+type UpdateScheduleRequest struct {
+	Name string       `path:"name" doc:"Schedule name"`
+	Body ScheduleBody `json:"body"`
+}
+
+// RegisterScheduleHandlers registers the schedule endpoints. All of them
+// are admin only.
+func RegisterScheduleHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "listSchedules",
+		Method:      "GET",
+		Path:        "/api/v1/schedules",
+		Summary:     "List schedules (admin only)",
+		Tags:        []string{"schedules"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ListSchedules)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "getSchedule",
+		Method:      "GET",
+		Path:        "/api/v1/schedules/{name}",
+		Summary:     "Get a schedule (admin only)",
+		Tags:        []string{"schedules"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, GetSchedule)
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "createSchedule",
+		Method:        "POST",
+		Path:          "/api/v1/schedules",
+		Summary:       "Create a schedule (admin only)",
+		Tags:          []string{"schedules"},
+		DefaultStatus: 201,
+		Security:      []map[string][]string{{"bearerAuth": {}}},
+	}, CreateSchedule)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "updateSchedule",
+		Method:      "PUT",
+		Path:        "/api/v1/schedules/{name}",
+		Summary:     "Update a schedule (admin only)",
+		Tags:        []string{"schedules"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, UpdateSchedule)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "deleteSchedule",
+		Method:      "DELETE",
+		Path:        "/api/v1/schedules/{name}",
+		Summary:     "Delete a schedule (admin only)",
+		Tags:        []string{"schedules"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, DeleteSchedule)
+}
+
+func ListSchedules(ctx context.Context, _ *struct{}) (*ListSchedulesResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	schedules, err := queries.ListSchedules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+
+	response := &ListSchedulesResponse{}
+	response.Body.Schedules = make([]Schedule, 0, len(schedules))
+	for _, s := range schedules {
+		response.Body.Schedules = append(response.Body.Schedules, toSchedule(appdb.GetScheduleRow(s)))
+	}
+	return response, nil
+}
+
+func GetSchedule(ctx context.Context, input *SchedulePath) (*ScheduleResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	s, err := queries.GetSchedule(ctx, input.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("schedule not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	return &ScheduleResponse{Body: toSchedule(s)}, nil
+}
+
+func CreateSchedule(ctx context.Context, input *CreateScheduleRequest) (*ScheduleResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	scriptID, vars, err := validate(ctx, queries, input.Body.ScheduleBody)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := queries.CreateSchedule(ctx, appdb.CreateScheduleParams{
+		Name:     input.Body.Name,
+		ScriptID: scriptID,
+		Cron:     input.Body.Cron,
+		Vars:     vars,
+		Enabled:  enabled(input.Body.ScheduleBody),
+	})
+	if appdb.IsUniqueViolation(err) {
+		return nil, huma.Error409Conflict(fmt.Sprintf("schedule %q already exists", input.Body.Name))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schedule: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("schedule created", "name", created.Name, "script", input.Body.Script, "cron", created.Cron)
+	return getSchedule(ctx, queries, created.Name)
+}
+
+func UpdateSchedule(ctx context.Context, input *UpdateScheduleRequest) (*ScheduleResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	if _, err := queries.GetSchedule(ctx, input.Name); errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("schedule not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	scriptID, vars, err := validate(ctx, queries, input.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := queries.UpdateSchedule(ctx, appdb.UpdateScheduleParams{
+		ScriptID: scriptID,
+		Cron:     input.Body.Cron,
+		Vars:     vars,
+		Enabled:  enabled(input.Body),
+		Name:     input.Name,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to update schedule: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("schedule updated", "name", input.Name, "script", input.Body.Script, "cron", input.Body.Cron)
+	return getSchedule(ctx, queries, input.Name)
+}
+
+func DeleteSchedule(ctx context.Context, input *SchedulePath) (*struct{}, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	if _, err := queries.GetSchedule(ctx, input.Name); errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("schedule not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	if err := queries.DeleteSchedule(ctx, input.Name); err != nil {
+		return nil, fmt.Errorf("failed to delete schedule: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("schedule deleted", "name", input.Name)
+	return &struct{}{}, nil
+}
+
+// validate checks the cron expression and script of a schedule, returning
+// the script's ID and the vars encoded for storage
+func validate(ctx context.Context, queries *appdb.Queries, body ScheduleBody) (int64, string, error) {
+	if _, err := scheduler.ParseCron(body.Cron); err != nil {
+		return 0, "", huma.Error422UnprocessableEntity(err.Error())
+	}
+	script, err := queries.GetScript(ctx, body.Script)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", huma.Error422UnprocessableEntity(fmt.Sprintf("script %q not found", body.Script))
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get script: %w", err)
+	}
+
+	vars := body.Vars
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	encoded, err := json.Marshal(vars)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to encode vars: %w", err)
+	}
+	return script.ID, string(encoded), nil
+}
+
+func enabled(body ScheduleBody) bool {
+	return body.Enabled == nil || *body.Enabled
+}
+
+func getSchedule(ctx context.Context, queries *appdb.Queries, name string) (*ScheduleResponse, error) {
+	s, err := queries.GetSchedule(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	return &ScheduleResponse{Body: toSchedule(s)}, nil
+}
+
+func toSchedule(s appdb.GetScheduleRow) Schedule {
+	schedule := Schedule{
+		Name:    s.Name,
+		Script:  s.ScriptName,
+		Cron:    s.Cron,
+		Vars:    map[string]string{},
+		Enabled: s.Enabled,
+		Created: s.Created,
+		Updated: s.Updated,
+	}
+	// Vars are only written by this package, so they always decode
+	_ = json.Unmarshal([]byte(s.Vars), &schedule.Vars)
+	if s.LastTick.Valid {
+		last := s.LastTick.Time
+		schedule.LastRun = &last
+	}
+	if cron, err := scheduler.ParseCron(s.Cron); err == nil && s.Enabled {
+		if next := cron.Next(time.Now()); !next.IsZero() {
+			schedule.NextRun = &next
+		}
+	}
+	return schedule
+}
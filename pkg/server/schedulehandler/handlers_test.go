@@ -0,0 +1,118 @@
+package schedulehandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/server/schedulehandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestScheduleHandlers(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	schedulehandler.RegisterScheduleHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	for _, name := range []string{"backup", "report"} {
+		if _, err := appdb.New(testDB.DB).CreateScript(context.Background(), appdb.CreateScriptParams{
+			Name: name, Content: "puts ok", AccessLevel: "admin",
+		}); err != nil {
+			t.Fatalf("Failed to create script: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		body   any
+		want   int
+	}{
+		{"create", "POST", "/api/v1/schedules", admin, map[string]any{"name": "nightly", "script": "backup", "cron": "0 2 * * *", "vars": map[string]string{"target": "s3"}}, http.StatusCreated},
+		{"create as user", "POST", "/api/v1/schedules", user, map[string]any{"name": "x", "script": "backup", "cron": "@daily"}, http.StatusForbidden},
+		{"create duplicate", "POST", "/api/v1/schedules", admin, map[string]any{"name": "nightly", "script": "backup", "cron": "@daily"}, http.StatusConflict},
+		{"create invalid cron", "POST", "/api/v1/schedules", admin, map[string]any{"name": "bad", "script": "backup", "cron": "0 25 * * *"}, http.StatusUnprocessableEntity},
+		{"create unknown script", "POST", "/api/v1/schedules", admin, map[string]any{"name": "bad", "script": "nosuch", "cron": "@daily"}, http.StatusUnprocessableEntity},
+		{"get as user", "GET", "/api/v1/schedules/nightly", user, nil, http.StatusForbidden},
+		{"get missing", "GET", "/api/v1/schedules/nosuch", admin, nil, http.StatusNotFound},
+		{"list anonymous", "GET", "/api/v1/schedules", "", nil, http.StatusUnauthorized},
+		{"update", "PUT", "/api/v1/schedules/nightly", admin, map[string]any{"script": "report", "cron": "@hourly", "enabled": false}, http.StatusOK},
+		{"update missing", "PUT", "/api/v1/schedules/nosuch", admin, map[string]any{"script": "report", "cron": "@hourly"}, http.StatusNotFound},
+		{"delete as user", "DELETE", "/api/v1/schedules/nightly", user, nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []any
+			if tt.auth != "" {
+				args = append(args, tt.auth)
+			}
+			if tt.body != nil {
+				args = append(args, tt.body)
+			}
+			resp := api.Do(tt.method, tt.path, args...)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.want, resp.Body.String())
+			}
+		})
+	}
+
+	getSchedule := func(t *testing.T, name string) schedulehandler.Schedule {
+		t.Helper()
+		resp := api.Get("/api/v1/schedules/"+name, admin)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", resp.Code, resp.Body.String())
+		}
+		var s schedulehandler.Schedule
+		if err := json.Unmarshal(resp.Body.Bytes(), &s); err != nil {
+			t.Fatalf("decode schedule: %v", err)
+		}
+		return s
+	}
+
+	t.Run("update replaces fields", func(t *testing.T) {
+		s := getSchedule(t, "nightly")
+		if s.Script != "report" || s.Cron != "@hourly" || s.Enabled || len(s.Vars) != 0 {
+			t.Errorf("schedule = %+v", s)
+		}
+		if s.NextRun != nil {
+			t.Errorf("disabled schedule has next run %v", s.NextRun)
+		}
+	})
+
+	t.Run("enabled by default", func(t *testing.T) {
+		resp := api.Post("/api/v1/schedules", admin, map[string]any{"name": "often", "script": "backup", "cron": "*/5 * * * *"})
+		if resp.Code != http.StatusCreated {
+			t.Fatalf("status = %d: %s", resp.Code, resp.Body.String())
+		}
+		s := getSchedule(t, "often")
+		if !s.Enabled || s.NextRun == nil || s.NextRun.Minute()%5 != 0 {
+			t.Errorf("schedule = %+v", s)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if resp := api.Delete("/api/v1/schedules/nightly", admin); resp.Code != http.StatusNoContent {
+			t.Fatalf("status = %d: %s", resp.Code, resp.Body.String())
+		}
+		resp := api.Get("/api/v1/schedules", admin)
+		var body struct {
+			Schedules []schedulehandler.Schedule `json:"schedules"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode list: %v", err)
+		}
+		if len(body.Schedules) != 1 || body.Schedules[0].Name != "often" {
+			t.Errorf("schedules = %+v", body.Schedules)
+		}
+	})
+}
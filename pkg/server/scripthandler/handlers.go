@@ -0,0 +1,583 @@
+package scripthandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/params"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+)
+
+// Script is the API representation of a stored script
+type Script struct {
+	ID          int64          `json:"id" doc:"Script ID"`
+	Name        string         `json:"name" doc:"Unique script name, used in /tools/{name}"`
+	Content     string         `json:"content" doc:"Tcl source"`
+	AccessLevel string         `json:"accessLevel" enum:"public,user,admin" doc:"Who may run the script"`
+	Params      []params.Param `json:"params,omitempty" doc:"Inputs the script takes, checked before it runs"`
+	// AllowedSecrets is only shown to admins
+	AllowedSecrets []string `json:"allowedSecrets,omitempty" doc:"Secrets the script may read with \"secret get\""`
+	// Limits and Concurrency are only shown to admins
+	Limits      *ScriptLimits      `json:"limits,omitempty" doc:"Execution limits overriding the server defaults"`
+	Concurrency *ScriptConcurrency `json:"concurrency,omitempty" doc:"How many runs may happen at once"`
+	Created     time.Time          `json:"created"`
+	Updated     time.Time          `json:"updated"`
+}
+
+// ScriptLimits overrides the server's execution limits for one script.
+// Zero keeps the server default.
+type ScriptLimits struct {
+	TimeoutMs      int64 `json:"timeoutMs,omitempty" minimum:"0" doc:"Maximum wall time in milliseconds"`
+	MaxCommands    int64 `json:"maxCommands,omitempty" minimum:"0" doc:"Maximum number of Tcl commands"`
+	MaxOutputBytes int64 `json:"maxOutputBytes,omitempty" minimum:"0" doc:"Maximum bytes of output"`
+}
+
+// ScriptConcurrency bounds how many runs of one script happen at once.
+// Coalesced runs share a slot.
+type ScriptConcurrency struct {
+	MaxConcurrent int64 `json:"maxConcurrent,omitempty" minimum:"0" doc:"Maximum runs at once. Zero is unlimited."`
+	Queue         bool  `json:"queue,omitempty" doc:"Wait for a free slot instead of responding 429"`
+	Coalesce      bool  `json:"coalesce,omitempty" doc:"Share one run's result between concurrent runs by the same user with the same inputs"`
+}
+
+type ScriptPath struct {
+	Name string `path:"name" doc:"Script name"`
+}
+
+type ScriptResponse struct {
+	Body Script `json:"body"`
+}
+
+type ListScriptsResponse struct {
+	Body struct {
+		Scripts []Script `json:"scripts"`
+	} `json:"body"`
+}
+
+type CreateScriptRequest struct {
+	Body struct {
+		Name           string             `json:"name" required:"true" pattern:"^[A-Za-z0-9_.-]+$" maxLength:"100" doc:"Unique script name"`
+		Content        string             `json:"content" required:"true" doc:"Tcl source"`
+		AccessLevel    string             `json:"accessLevel,omitempty" enum:"public,user,admin" default:"user" doc:"Who may run the script"`
+		Params         []params.Param     `json:"params,omitempty" doc:"Inputs the script takes, checked before it runs"`
+		AllowedSecrets []string           `json:"allowedSecrets,omitempty" doc:"Secrets the script may read with \"secret get\""`
+		Limits         *ScriptLimits      `json:"limits,omitempty" doc:"Execution limits overriding the server defaults"`
+		Concurrency    *ScriptConcurrency `json:"concurrency,omitempty" doc:"How many runs may happen at once"`
+	} `json:"body"`
+}
+
+type UpdateScriptRequest struct {
+	Name string `path:"name" doc:"Script name"`
+	Body struct {
+		Content        string             `json:"content" required:"true" doc:"Tcl source"`
+		AccessLevel    string             `json:"accessLevel,omitempty" enum:"public,user,admin" default:"user" doc:"Who may run the script"`
+		Params         []params.Param     `json:"params,omitempty" doc:"Inputs the script takes, checked before it runs. Replaces the current list."`
+		AllowedSecrets []string           `json:"allowedSecrets,omitempty" doc:"Secrets the script may read with \"secret get\". Replaces the current list."`
+		Limits         *ScriptLimits      `json:"limits,omitempty" doc:"Execution limits overriding the server defaults. Omit to use the defaults."`
+		Concurrency    *ScriptConcurrency `json:"concurrency,omitempty" doc:"How many runs may happen at once. Omit for no limit."`
+	} `json:"body"`
+}
+
+// RegisterScriptHandlers registers the script management endpoints
+func RegisterScriptHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "listScripts",
+		Method:      "GET",
+		Path:        "/api/v1/scripts",
+		Summary:     "List scripts visible to the current user",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ListScripts)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "getScript",
+		Method:      "GET",
+		Path:        "/api/v1/scripts/{name}",
+		Summary:     "Get a script",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, GetScript)
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "createScript",
+		Method:        "POST",
+		Path:          "/api/v1/scripts",
+		Summary:       "Create a script (admin only)",
+		Tags:          []string{"scripts"},
+		DefaultStatus: 201,
+		Security:      []map[string][]string{{"bearerAuth": {}}},
+	}, CreateScript)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "updateScript",
+		Method:      "PUT",
+		Path:        "/api/v1/scripts/{name}",
+		Summary:     "Update a script (admin only)",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, UpdateScript)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "deleteScript",
+		Method:      "DELETE",
+		Path:        "/api/v1/scripts/{name}",
+		Summary:     "Delete a script (admin only)",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, DeleteScript)
+
+	registerRunHandlers(api)
+	registerHistoryHandlers(api)
+	registerKVHandlers(api)
+	registerVersionHandlers(api)
+	registerWebhookHandlers(api)
+}
+
+func ListScripts(ctx context.Context, _ *struct{}) (*ListScriptsResponse, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+
+	var scripts []appdb.Script
+	if user.Role == "admin" {
+		scripts, err = queries.ListScripts(ctx)
+	} else {
+		scripts, err = queries.ListScriptsExcludingAccess(ctx, "admin")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scripts: %w", err)
+	}
+
+	response := &ListScriptsResponse{}
+	response.Body.Scripts = make([]Script, 0, len(scripts))
+	for _, s := range scripts {
+		script, err := toScript(ctx, user, s)
+		if err != nil {
+			return nil, err
+		}
+		response.Body.Scripts = append(response.Body.Scripts, script)
+	}
+	return response, nil
+}
+
+func GetScript(ctx context.Context, input *ScriptPath) (*ScriptResponse, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+	// Admin scripts are hidden from other users rather than forbidden
+	if !CanRun(user, script.AccessLevel) {
+		return nil, huma.Error404NotFound("script not found")
+	}
+
+	return scriptResponse(ctx, user, script)
+}
+
+func CreateScript(ctx context.Context, input *CreateScriptRequest) (*ScriptResponse, error) {
+	user, err := middleware.RequireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logger := middleware.GetLogger(ctx)
+	if err := validateParams(input.Body.Params); err != nil {
+		return nil, err
+	}
+
+	db := ctx.Value(appdb.DbContextKey).(*sql.DB)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := appdb.New(db).WithTx(tx)
+
+	script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{
+		Name:        input.Body.Name,
+		Content:     input.Body.Content,
+		AccessLevel: accessLevel(input.Body.AccessLevel),
+	})
+	if appdb.IsUniqueViolation(err) {
+		return nil, huma.Error409Conflict(fmt.Sprintf("script %q already exists", input.Body.Name))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create script: %w", err)
+	}
+	if _, err := queries.SaveScriptVersion(ctx, script, userID(user)); err != nil {
+		return nil, fmt.Errorf("failed to save script version: %w", err)
+	}
+	if err := params.Save(ctx, queries, script.ID, input.Body.Params); err != nil {
+		return nil, err
+	}
+	if err := setAllowedSecrets(ctx, queries, script.ID, input.Body.AllowedSecrets); err != nil {
+		return nil, err
+	}
+	if err := setLimits(ctx, queries, script.ID, input.Body.Limits); err != nil {
+		return nil, err
+	}
+	if err := setConcurrency(ctx, queries, script.ID, input.Body.Concurrency); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to create script: %w", err)
+	}
+
+	logger.Info("script created", "script", script.Name)
+	return scriptResponse(ctx, user, script)
+}
+
+func UpdateScript(ctx context.Context, input *UpdateScriptRequest) (*ScriptResponse, error) {
+	user, err := middleware.RequireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logger := middleware.GetLogger(ctx)
+	if err := validateParams(input.Body.Params); err != nil {
+		return nil, err
+	}
+
+	db := ctx.Value(appdb.DbContextKey).(*sql.DB)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := appdb.New(db).WithTx(tx)
+
+	script, err := updateScript(ctx, queries, user, appdb.UpdateScriptParams{
+		Name:        input.Name,
+		Content:     input.Body.Content,
+		AccessLevel: accessLevel(input.Body.AccessLevel),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := params.Save(ctx, queries, script.ID, input.Body.Params); err != nil {
+		return nil, err
+	}
+	if err := setAllowedSecrets(ctx, queries, script.ID, input.Body.AllowedSecrets); err != nil {
+		return nil, err
+	}
+	if err := setLimits(ctx, queries, script.ID, input.Body.Limits); err != nil {
+		return nil, err
+	}
+	if err := setConcurrency(ctx, queries, script.ID, input.Body.Concurrency); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to update script: %w", err)
+	}
+	forgetParsed(ctx, script.ID)
+
+	logger.Info("script updated", "script", script.Name)
+	return scriptResponse(ctx, user, script)
+}
+
+func DeleteScript(ctx context.Context, input *ScriptPath) (*struct{}, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	logger := middleware.GetLogger(ctx)
+
+	// DeleteScript doesn't report missing rows, so look the script up first
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	db := ctx.Value(appdb.DbContextKey).(*sql.DB)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := appdb.New(db).WithTx(tx)
+
+	// Foreign keys aren't enforced on every connection, so don't rely on cascades
+	if err := queries.DeleteScriptSecrets(ctx, script.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete script: %w", err)
+	}
+	if err := queries.DeleteScriptLimits(ctx, script.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete script: %w", err)
+	}
+	if err := queries.DeleteScriptConcurrency(ctx, script.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete script: %w", err)
+	}
+	if err := queries.DeleteScriptParams(ctx, script.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete script: %w", err)
+	}
+	if err := queries.DeleteScriptKV(ctx, script.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete script: %w", err)
+	}
+	if err := queries.DeleteScriptRunExecs(ctx, script.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete script: %w", err)
+	}
+	if err := queries.DeleteScriptRuns(ctx, script.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete script: %w", err)
+	}
+	if err := queries.DeleteScriptVersions(ctx, script.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete script: %w", err)
+	}
+	if err := queries.DeleteSchedulesForScript(ctx, script.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete script: %w", err)
+	}
+	if hook, err := queries.GetScriptWebhook(ctx, script.ID); err == nil {
+		if err := deleteWebhook(ctx, queries, hook); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to delete script: %w", err)
+	}
+	if err := queries.DeleteScript(ctx, input.Name); err != nil {
+		return nil, fmt.Errorf("failed to delete script: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to delete script: %w", err)
+	}
+	forgetParsed(ctx, script.ID)
+
+	logger.Info("script deleted", "script", input.Name)
+	return &struct{}{}, nil
+}
+
+// LoadScript fetches a script by name, mapping a missing row to a 404
+func LoadScript(ctx context.Context, name string) (appdb.Script, error) {
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	script, err := queries.GetScript(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return script, huma.Error404NotFound("script not found")
+	}
+	if err != nil {
+		return script, fmt.Errorf("failed to get script: %w", err)
+	}
+	return script, nil
+}
+
+// CanRun reports whether user may see and run a script with the given access level
+func CanRun(user appdb.User, accessLevel string) bool {
+	return accessLevel != "admin" || user.Role == "admin"
+}
+
+func accessLevel(level string) string {
+	if level == "" {
+		return "user"
+	}
+	return level
+}
+
+// updateScript replaces a script's content and access level and records
+// the result as a new version
+func updateScript(ctx context.Context, queries *appdb.Queries, user appdb.User, arg appdb.UpdateScriptParams) (appdb.Script, error) {
+	previous, err := queries.GetScript(ctx, arg.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return appdb.Script{}, huma.Error404NotFound("script not found")
+	}
+	if err != nil {
+		return appdb.Script{}, fmt.Errorf("failed to get script: %w", err)
+	}
+	// Keep the content being replaced if it was never versioned, such as
+	// scripts saved before versions were recorded
+	if _, err := queries.SaveScriptVersion(ctx, previous, sql.NullInt64{}); err != nil {
+		return appdb.Script{}, fmt.Errorf("failed to save script version: %w", err)
+	}
+
+	script, err := queries.UpdateScript(ctx, arg)
+	if err != nil {
+		return appdb.Script{}, fmt.Errorf("failed to update script: %w", err)
+	}
+	if _, err := queries.SaveScriptVersion(ctx, script, userID(user)); err != nil {
+		return appdb.Script{}, fmt.Errorf("failed to save script version: %w", err)
+	}
+	return script, nil
+}
+
+// forgetParsed drops a changed or deleted script from the engine's parse cache
+func forgetParsed(ctx context.Context, scriptID int64) {
+	if eng, ok := ctx.Value(middleware.EngineKey).(*engine.Engine); ok {
+		eng.ForgetScript(scriptID)
+	}
+}
+
+func userID(user appdb.User) sql.NullInt64 {
+	return sql.NullInt64{Int64: user.ID, Valid: true}
+}
+
+// validateParams checks each parameter declaration and that names are
+// unique, reporting all the problems at once
+func validateParams(list []params.Param) error {
+	var problems []error
+	seen := map[string]bool{}
+	for i, p := range list {
+		for _, v := range p.Validate() {
+			problems = append(problems, &huma.ErrorDetail{
+				Message:  v.Message,
+				Location: fmt.Sprintf("body.params[%d].%s", i, v.Field),
+				Value:    v.Value,
+			})
+		}
+		if seen[p.Name] {
+			problems = append(problems, &huma.ErrorDetail{
+				Message:  fmt.Sprintf("duplicate param %q", p.Name),
+				Location: fmt.Sprintf("body.params[%d].name", i),
+				Value:    p.Name,
+			})
+		}
+		seen[p.Name] = true
+	}
+	if len(problems) > 0 {
+		return huma.Error422UnprocessableEntity("invalid params", problems...)
+	}
+	return nil
+}
+
+// CheckVars checks vars against a script's parameters and returns the
+// variables to run it with. Invalid vars return a 422 listing each problem.
+func CheckVars(ctx context.Context, script appdb.Script, vars map[string]string) (map[string]string, error) {
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	vars, problems, err := params.Check(ctx, queries, script.ID, vars)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		details := make([]error, 0, len(problems))
+		for _, v := range problems {
+			details = append(details, &huma.ErrorDetail{
+				Message:  v.Message,
+				Location: "body.vars." + v.Field,
+				Value:    v.Value,
+			})
+		}
+		return nil, huma.Error422UnprocessableEntity("invalid vars", details...)
+	}
+	return vars, nil
+}
+
+// setAllowedSecrets replaces the list of secrets a script may read
+func setAllowedSecrets(ctx context.Context, queries *appdb.Queries, scriptID int64, keys []string) error {
+	if err := queries.DeleteScriptSecrets(ctx, scriptID); err != nil {
+		return fmt.Errorf("failed to update allowed secrets: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err := queries.AddScriptSecret(ctx, appdb.AddScriptSecretParams{
+			ScriptID:  scriptID,
+			SecretKey: key,
+		}); err != nil {
+			return fmt.Errorf("failed to update allowed secrets: %w", err)
+		}
+	}
+	return nil
+}
+
+// setLimits stores a script's limits, or removes them if none are set
+func setLimits(ctx context.Context, queries *appdb.Queries, scriptID int64, limits *ScriptLimits) error {
+	if limits == nil || *limits == (ScriptLimits{}) {
+		if err := queries.DeleteScriptLimits(ctx, scriptID); err != nil {
+			return fmt.Errorf("failed to update limits: %w", err)
+		}
+		return nil
+	}
+	if err := queries.UpsertScriptLimits(ctx, appdb.UpsertScriptLimitsParams{
+		ScriptID:       scriptID,
+		TimeoutMs:      limits.TimeoutMs,
+		MaxCommands:    limits.MaxCommands,
+		MaxOutputBytes: limits.MaxOutputBytes,
+	}); err != nil {
+		return fmt.Errorf("failed to update limits: %w", err)
+	}
+	return nil
+}
+
+// setConcurrency stores a script's concurrency settings, or removes them if
+// none are set
+func setConcurrency(ctx context.Context, queries *appdb.Queries, scriptID int64, c *ScriptConcurrency) error {
+	if c == nil || *c == (ScriptConcurrency{}) {
+		if err := queries.DeleteScriptConcurrency(ctx, scriptID); err != nil {
+			return fmt.Errorf("failed to update concurrency: %w", err)
+		}
+		return nil
+	}
+	if err := queries.UpsertScriptConcurrency(ctx, appdb.UpsertScriptConcurrencyParams{
+		ScriptID:      scriptID,
+		MaxConcurrent: c.MaxConcurrent,
+		Queue:         c.Queue,
+		Coalesce:      c.Coalesce,
+	}); err != nil {
+		return fmt.Errorf("failed to update concurrency: %w", err)
+	}
+	return nil
+}
+
+func scriptResponse(ctx context.Context, user appdb.User, s appdb.Script) (*ScriptResponse, error) {
+	script, err := toScript(ctx, user, s)
+	if err != nil {
+		return nil, err
+	}
+	return &ScriptResponse{Body: script}, nil
+}
+
+func toScript(ctx context.Context, user appdb.User, s appdb.Script) (Script, error) {
+	script := Script{
+		ID:          s.ID,
+		Name:        s.Name,
+		Content:     s.Content,
+		AccessLevel: s.AccessLevel,
+		Created:     s.Created,
+		Updated:     s.Updated,
+	}
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	list, err := params.Load(ctx, queries, s.ID)
+	if err != nil {
+		return script, err
+	}
+	script.Params = list
+	if user.Role == "admin" {
+		keys, err := queries.ListScriptSecrets(ctx, s.ID)
+		if err != nil {
+			return script, fmt.Errorf("failed to list allowed secrets: %w", err)
+		}
+		script.AllowedSecrets = keys
+
+		limits, err := queries.GetScriptLimits(ctx, s.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return script, fmt.Errorf("failed to load limits: %w", err)
+		}
+		if err == nil {
+			script.Limits = &ScriptLimits{
+				TimeoutMs:      limits.TimeoutMs,
+				MaxCommands:    limits.MaxCommands,
+				MaxOutputBytes: limits.MaxOutputBytes,
+			}
+		}
+
+		c, err := queries.GetScriptConcurrency(ctx, s.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return script, fmt.Errorf("failed to load concurrency: %w", err)
+		}
+		if err == nil {
+			script.Concurrency = &ScriptConcurrency{
+				MaxConcurrent: c.MaxConcurrent,
+				Queue:         c.Queue,
+				Coalesce:      c.Coalesce,
+			}
+		}
+	}
+	return script, nil
+}
@@ -0,0 +1,242 @@
+package scripthandler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/ytjohn/toolmin/pkg/server/scripthandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestScriptHandlers(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	scripthandler.RegisterScriptHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	for _, s := range []map[string]any{
+		{"name": "hello", "content": "puts hi", "accessLevel": "public"},
+		{"name": "report", "content": "puts report"},
+		{"name": "reboot", "content": "puts rebooting", "accessLevel": "admin"},
+	} {
+		if resp := api.Post("/api/v1/scripts", admin, s); resp.Code != http.StatusCreated {
+			t.Fatalf("create %v: status %d: %s", s["name"], resp.Code, resp.Body.String())
+		}
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		body   any
+		want   int
+	}{
+		{"create as user", "POST", "/api/v1/scripts", user, map[string]any{"name": "x", "content": "puts x"}, http.StatusForbidden},
+		{"create duplicate", "POST", "/api/v1/scripts", admin, map[string]any{"name": "hello", "content": "puts x"}, http.StatusConflict},
+		{"create invalid name", "POST", "/api/v1/scripts", admin, map[string]any{"name": "a b", "content": "puts x"}, http.StatusUnprocessableEntity},
+		{"get as user", "GET", "/api/v1/scripts/report", user, nil, http.StatusOK},
+		{"get admin script as user", "GET", "/api/v1/scripts/reboot", user, nil, http.StatusNotFound},
+		{"get missing", "GET", "/api/v1/scripts/nosuch", admin, nil, http.StatusNotFound},
+		{"update as admin", "PUT", "/api/v1/scripts/report", admin, map[string]any{"content": "puts new"}, http.StatusOK},
+		{"update as user", "PUT", "/api/v1/scripts/report", user, map[string]any{"content": "puts new"}, http.StatusForbidden},
+		{"update missing", "PUT", "/api/v1/scripts/nosuch", admin, map[string]any{"content": "puts new"}, http.StatusNotFound},
+		{"delete as user", "DELETE", "/api/v1/scripts/hello", user, nil, http.StatusForbidden},
+		{"delete missing", "DELETE", "/api/v1/scripts/nosuch", admin, nil, http.StatusNotFound},
+		{"delete as admin", "DELETE", "/api/v1/scripts/hello", admin, nil, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []any{tt.auth}
+			if tt.body != nil {
+				args = append(args, tt.body)
+			}
+			resp := api.Do(tt.method, tt.path, args...)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.want, resp.Body.String())
+			}
+		})
+	}
+
+	listNames := func(auth string) []string {
+		resp := api.Get("/api/v1/scripts", auth)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("list: status %d", resp.Code)
+		}
+		var body struct {
+			Scripts []scripthandler.Script `json:"scripts"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode list: %v", err)
+		}
+		var names []string
+		for _, s := range body.Scripts {
+			names = append(names, s.Name)
+		}
+		return names
+	}
+
+	if got := listNames(user); len(got) != 1 || got[0] != "report" {
+		t.Errorf("user listing = %v, want [report]", got)
+	}
+	if got := listNames(admin); len(got) != 2 {
+		t.Errorf("admin listing = %v, want 2 scripts", got)
+	}
+	if resp := api.Get("/api/v1/scripts"); resp.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous listing status = %d, want 401", resp.Code)
+	}
+
+	t.Run("allowed secrets", func(t *testing.T) {
+		getAllowed := func(auth string) []string {
+			resp := api.Get("/api/v1/scripts/report", auth)
+			var script scripthandler.Script
+			if err := json.Unmarshal(resp.Body.Bytes(), &script); err != nil {
+				t.Fatalf("decode script: %v", err)
+			}
+			return script.AllowedSecrets
+		}
+
+		resp := api.Put("/api/v1/scripts/report", admin, map[string]any{
+			"content":        "puts [secret get api_token]",
+			"allowedSecrets": []string{"api_token", "db_password", "api_token"},
+		})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("update: status %d: %s", resp.Code, resp.Body.String())
+		}
+		if got := getAllowed(admin); len(got) != 2 || got[0] != "api_token" || got[1] != "db_password" {
+			t.Errorf("allowed secrets = %v, want [api_token db_password]", got)
+		}
+		if got := getAllowed(user); got != nil {
+			t.Errorf("user sees allowed secrets %v", got)
+		}
+
+		resp = api.Put("/api/v1/scripts/report", admin, map[string]any{"content": "puts hi"})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("update: status %d: %s", resp.Code, resp.Body.String())
+		}
+		if got := getAllowed(admin); len(got) != 0 {
+			t.Errorf("allowed secrets = %v after clearing, want none", got)
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		resp := api.Put("/api/v1/scripts/report", admin, map[string]any{
+			"content": "puts hi",
+			"limits":  map[string]any{"timeoutMs": 500, "maxOutputBytes": 1024},
+		})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("update: status %d: %s", resp.Code, resp.Body.String())
+		}
+		var script scripthandler.Script
+		if err := json.Unmarshal(resp.Body.Bytes(), &script); err != nil {
+			t.Fatalf("decode script: %v", err)
+		}
+		want := scripthandler.ScriptLimits{TimeoutMs: 500, MaxOutputBytes: 1024}
+		if script.Limits == nil || *script.Limits != want {
+			t.Errorf("limits = %+v, want %+v", script.Limits, want)
+		}
+
+		resp = api.Put("/api/v1/scripts/report", admin, map[string]any{
+			"content": "puts hi",
+			"limits":  map[string]any{"timeoutMs": -1},
+		})
+		if resp.Code != http.StatusUnprocessableEntity {
+			t.Errorf("negative limit: status %d, want 422", resp.Code)
+		}
+	})
+	t.Run("concurrency", func(t *testing.T) {
+		resp := api.Put("/api/v1/scripts/report", admin, map[string]any{
+			"content":     "puts hi",
+			"concurrency": map[string]any{"maxConcurrent": 2, "coalesce": true},
+		})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("update: status %d: %s", resp.Code, resp.Body.String())
+		}
+		var script scripthandler.Script
+		if err := json.Unmarshal(resp.Body.Bytes(), &script); err != nil {
+			t.Fatalf("decode script: %v", err)
+		}
+		want := scripthandler.ScriptConcurrency{MaxConcurrent: 2, Coalesce: true}
+		if script.Concurrency == nil || *script.Concurrency != want {
+			t.Errorf("concurrency = %+v, want %+v", script.Concurrency, want)
+		}
+
+		// Omitting it removes the limit
+		resp = api.Put("/api/v1/scripts/report", admin, map[string]any{"content": "puts hi"})
+		script = scripthandler.Script{}
+		if err := json.Unmarshal(resp.Body.Bytes(), &script); err != nil {
+			t.Fatalf("decode script: %v", err)
+		}
+		if script.Concurrency != nil {
+			t.Errorf("concurrency = %+v after clearing, want none", script.Concurrency)
+		}
+
+		resp = api.Put("/api/v1/scripts/report", admin, map[string]any{
+			"content":     "puts hi",
+			"concurrency": map[string]any{"maxConcurrent": -1},
+		})
+		if resp.Code != http.StatusUnprocessableEntity {
+			t.Errorf("negative limit: status %d, want 422", resp.Code)
+		}
+	})
+	t.Run("params", func(t *testing.T) {
+		resp := api.Put("/api/v1/scripts/report", admin, map[string]any{
+			"content": "puts $host",
+			"params": []map[string]any{
+				{"name": "host", "required": true, "pattern": "[a-z.]+", "description": "Host to check"},
+				{"name": "port", "type": "int", "default": "443"},
+				{"name": "proto", "type": "enum", "enum": []string{"tcp", "udp"}},
+			},
+		})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("update: status %d: %s", resp.Code, resp.Body.String())
+		}
+
+		// Users need the params to build a form
+		resp = api.Get("/api/v1/scripts/report", user)
+		var script scripthandler.Script
+		if err := json.Unmarshal(resp.Body.Bytes(), &script); err != nil {
+			t.Fatalf("decode script: %v", err)
+		}
+		if len(script.Params) != 3 || script.Params[0].Name != "host" || !script.Params[0].Required ||
+			script.Params[1].Default == nil || *script.Params[1].Default != "443" || len(script.Params[2].Enum) != 2 {
+			t.Errorf("params = %+v", script.Params)
+		}
+
+		resp = api.Put("/api/v1/scripts/report", admin, map[string]any{
+			"content": "puts hi",
+			"params": []map[string]any{
+				{"name": "1st"},
+				{"name": "port", "type": "int", "default": "http"},
+				{"name": "port", "pattern": "("},
+				{"name": "mode", "type": "enum"},
+			},
+		})
+		if resp.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("invalid params: status %d, want 422", resp.Code)
+		}
+		var problem struct {
+			Errors []struct {
+				Location string `json:"location"`
+			} `json:"errors"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("decode problem: %v", err)
+		}
+		var locations []string
+		for _, e := range problem.Errors {
+			locations = append(locations, e.Location)
+		}
+		want := []string{"body.params[0].name", "body.params[1].default", "body.params[2].pattern", "body.params[2].name", "body.params[3].enum"}
+		if !slices.Equal(locations, want) {
+			t.Errorf("error locations = %v, want %v", locations, want)
+		}
+	})
+}
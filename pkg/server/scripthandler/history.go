@@ -0,0 +1,117 @@
+package scripthandler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+)
+
+// ScriptRun is a past run of a script
+type ScriptRun struct {
+	ID       int64             `json:"id"`
+	UserID   int64             `json:"userId,omitempty" doc:"User who ran the script, unset for anonymous and scheduled runs"`
+	Username string            `json:"username,omitempty"`
+	Source   string            `json:"source" enum:"request,webhook,schedule" doc:"What started the run"`
+	Started  time.Time         `json:"started"`
+	Finished time.Time         `json:"finished"`
+	Status   string            `json:"status" enum:"ok,error,timeout,command_limit,output_limit,memory_limit,cancelled"`
+	Output   string            `json:"output" doc:"Start of the script's output"`
+	Error    string            `json:"error,omitempty"`
+	Vars     map[string]string `json:"vars" doc:"Input variables, with secrets masked"`
+	Execs    []RunExec         `json:"execs,omitempty" doc:"Commands the run executed with exec, in order"`
+}
+
+// RunExec is a command a run executed, or tried to
+type RunExec struct {
+	Path       string   `json:"path"`
+	Args       []string `json:"args" doc:"Arguments, with secrets redacted"`
+	ExitCode   int64    `json:"exitCode" doc:"Exit code, -1 if the command was refused or didn't finish"`
+	DurationMs int64    `json:"durationMs"`
+	Error      string   `json:"error,omitempty"`
+}
+
+type ListRunsRequest struct {
+	Name  string `path:"name" doc:"Script name"`
+	Limit int64  `query:"limit" minimum:"1" maximum:"1000" default:"50" doc:"Maximum number of runs to return, newest first"`
+}
+
+type ListRunsResponse struct {
+	Body struct {
+		Runs []ScriptRun `json:"runs"`
+	} `json:"body"`
+}
+
+// registerHistoryHandlers registers the run history endpoints
+func registerHistoryHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "listScriptRuns",
+		Method:      "GET",
+		Path:        "/api/v1/scripts/{name}/runs",
+		Summary:     "List recent runs of a script (admin only)",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ListRuns)
+}
+
+func ListRuns(ctx context.Context, input *ListRunsRequest) (*ListRunsResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	runs, err := queries.ListScriptRuns(ctx, appdb.ListScriptRunsParams{
+		ScriptID: script.ID,
+		Limit:    input.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+
+	response := &ListRunsResponse{}
+	response.Body.Runs = make([]ScriptRun, 0, len(runs))
+	for _, r := range runs {
+		run := ScriptRun{
+			ID:       r.ID,
+			UserID:   r.UserID.Int64,
+			Username: r.Username.String,
+			Source:   r.Source,
+			Started:  r.Started,
+			Finished: r.Finished,
+			Status:   r.Status,
+			Output:   r.Output,
+			Error:    r.Error,
+		}
+		if err := json.Unmarshal([]byte(r.Vars), &run.Vars); err != nil {
+			return nil, fmt.Errorf("failed to decode run vars: %w", err)
+		}
+		execs, err := queries.ListScriptRunExecs(ctx, r.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list run execs: %w", err)
+		}
+		for _, e := range execs {
+			exec := RunExec{
+				Path:       e.Path,
+				ExitCode:   e.ExitCode,
+				DurationMs: e.DurationMs,
+				Error:      e.Error,
+			}
+			if err := json.Unmarshal([]byte(e.Args), &exec.Args); err != nil {
+				return nil, fmt.Errorf("failed to decode exec args: %w", err)
+			}
+			run.Execs = append(run.Execs, exec)
+		}
+		response.Body.Runs = append(response.Body.Runs, run)
+	}
+	return response, nil
+}
@@ -0,0 +1,78 @@
+package scripthandler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/server/scripthandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestListRuns(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	scripthandler.RegisterScriptHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	resp := api.Post("/api/v1/scripts", admin, map[string]any{
+		"name":    "login",
+		"content": "puts \"hello $name\"\nif {$fail} { error boom }",
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", resp.Code, resp.Body.String())
+	}
+
+	for _, fail := range []string{"0", "1"} {
+		resp := api.Post("/api/v1/scripts/login/run", user, map[string]any{
+			"vars": map[string]string{"name": "world", "password": "hunter2", "fail": fail},
+		})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("run: status %d: %s", resp.Code, resp.Body.String())
+		}
+	}
+
+	if resp := api.Get("/api/v1/scripts/login/runs", user); resp.Code != http.StatusForbidden {
+		t.Errorf("list as user: status %d, want 403", resp.Code)
+	}
+	if resp := api.Get("/api/v1/scripts/nosuch/runs", admin); resp.Code != http.StatusNotFound {
+		t.Errorf("list missing script: status %d, want 404", resp.Code)
+	}
+
+	resp = api.Get("/api/v1/scripts/login/runs", admin)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("list: status %d: %s", resp.Code, resp.Body.String())
+	}
+	var body struct {
+		Runs []scripthandler.ScriptRun `json:"runs"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Runs) != 2 {
+		t.Fatalf("got %d runs, want 2", len(body.Runs))
+	}
+
+	// Newest first
+	failed, ok := body.Runs[0], body.Runs[1]
+	if failed.Status != engine.StatusError || failed.Error == "" {
+		t.Errorf("failed run = %+v", failed)
+	}
+	if ok.Status != engine.StatusOK || ok.Output != "hello world\n" || ok.Username == "" {
+		t.Errorf("successful run = %+v", ok)
+	}
+	if ok.Vars["password"] != engine.Redacted || ok.Vars["name"] != "world" {
+		t.Errorf("vars = %v, want password masked", ok.Vars)
+	}
+
+	if resp := api.Get("/api/v1/scripts/login/runs?limit=1", admin); resp.Code != http.StatusOK {
+		t.Errorf("list with limit: status %d", resp.Code)
+	} else if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil || len(body.Runs) != 1 {
+		t.Errorf("list with limit: got %d runs, want 1", len(body.Runs))
+	}
+}
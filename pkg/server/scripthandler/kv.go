@@ -0,0 +1,147 @@
+package scripthandler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// KVEntry is a key a script stored with the kv command
+type KVEntry struct {
+	Key     string     `json:"key"`
+	Value   string     `json:"value"`
+	Expires *time.Time `json:"expires,omitempty" doc:"When the key expires, unset for keys that don't"`
+	Updated time.Time  `json:"updated"`
+}
+
+type KVRequest struct {
+	Name    string `path:"name" doc:"Script name"`
+	Pattern string `query:"pattern" doc:"Only keys matching this glob pattern, as for kv keys"`
+}
+
+type ListKVResponse struct {
+	Body struct {
+		Entries []KVEntry `json:"entries"`
+	} `json:"body"`
+}
+
+// registerKVHandlers registers the endpoints for a script's kv store
+func registerKVHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "listScriptKV",
+		Method:      "GET",
+		Path:        "/api/v1/scripts/{name}/kv",
+		Summary:     "List the keys a script has stored (admin only)",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ListKV)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "clearScriptKV",
+		Method:      "DELETE",
+		Path:        "/api/v1/scripts/{name}/kv",
+		Summary:     "Delete the keys a script has stored (admin only)",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ClearKV)
+}
+
+func ListKV(ctx context.Context, input *KVRequest) (*ListKVResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	entries, err := listKV(ctx, queries, script.ID, input.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ListKVResponse{}
+	response.Body.Entries = make([]KVEntry, 0, len(entries))
+	for _, e := range entries {
+		entry := KVEntry{Key: e.Key, Value: e.Value, Updated: e.Updated}
+		if e.Expires.Valid {
+			entry.Expires = &e.Expires.Time
+		}
+		response.Body.Entries = append(response.Body.Entries, entry)
+	}
+	return response, nil
+}
+
+// ClearKV deletes a script's keys, or only those matching the pattern
+func ClearKV(ctx context.Context, input *KVRequest) (*struct{}, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	db := ctx.Value(appdb.DbContextKey).(*sql.DB)
+	if input.Pattern == "" {
+		if err := appdb.New(db).DeleteScriptKV(ctx, script.ID); err != nil {
+			return nil, fmt.Errorf("failed to clear kv: %w", err)
+		}
+		middleware.GetLogger(ctx).Info("script kv cleared", "script", script.Name)
+		return &struct{}{}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := appdb.New(db).WithTx(tx)
+
+	entries, err := listKV(ctx, queries, script.ID, input.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if _, err := queries.DeleteKV(ctx, appdb.DeleteKVParams{ScriptID: script.ID, Key: e.Key}); err != nil {
+			return nil, fmt.Errorf("failed to delete key: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("script kv cleared", "script", script.Name, "pattern", input.Pattern, "deleted", len(entries))
+	return &struct{}{}, nil
+}
+
+// listKV returns a script's unexpired keys that match pattern, or all of
+// them if pattern is empty
+func listKV(ctx context.Context, queries *appdb.Queries, scriptID int64, pattern string) ([]appdb.Kv, error) {
+	entries, err := queries.ListKV(ctx, appdb.ListKVParams{
+		ScriptID: scriptID,
+		Now:      appdb.NullTime(time.Now().UTC().Truncate(time.Second)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kv: %w", err)
+	}
+	if pattern == "" {
+		return entries, nil
+	}
+	matched := entries[:0]
+	for _, e := range entries {
+		if tcl.Match(pattern, e.Key) {
+			matched = append(matched, e)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,90 @@
+package scripthandler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ytjohn/toolmin/pkg/server/scripthandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestScriptKV(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	scripthandler.RegisterScriptHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	resp := api.Post("/api/v1/scripts", admin, map[string]any{
+		"name":    "seen",
+		"content": "kv incr visits\nkv set last:$who now -ttl 3600\nkv set last:admin never",
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", resp.Code, resp.Body.String())
+	}
+	for range 2 {
+		resp := api.Post("/api/v1/scripts/seen/run", user, map[string]any{"vars": map[string]string{"who": "bob"}})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("run: status %d: %s", resp.Code, resp.Body.String())
+		}
+	}
+
+	list := func(query string) []scripthandler.KVEntry {
+		t.Helper()
+		resp := api.Get("/api/v1/scripts/seen/kv"+query, admin)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("list: status %d: %s", resp.Code, resp.Body.String())
+		}
+		var body struct {
+			Entries []scripthandler.KVEntry `json:"entries"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return body.Entries
+	}
+
+	if resp := api.Get("/api/v1/scripts/seen/kv", user); resp.Code != http.StatusForbidden {
+		t.Errorf("list as user: status %d, want 403", resp.Code)
+	}
+	if resp := api.Get("/api/v1/scripts/nosuch/kv", admin); resp.Code != http.StatusNotFound {
+		t.Errorf("list missing script: status %d, want 404", resp.Code)
+	}
+	if resp := api.Delete("/api/v1/scripts/seen/kv", user); resp.Code != http.StatusForbidden {
+		t.Errorf("clear as user: status %d, want 403", resp.Code)
+	}
+
+	entries := list("")
+	if len(entries) != 3 {
+		t.Fatalf("entries = %+v, want 3", entries)
+	}
+	if entries[0].Key != "last:admin" || entries[0].Expires != nil {
+		t.Errorf("first entry = %+v, want last:admin without an expiry", entries[0])
+	}
+	if entries[1].Key != "last:bob" || entries[1].Expires == nil {
+		t.Errorf("second entry = %+v, want last:bob with an expiry", entries[1])
+	}
+	if entries[2].Key != "visits" || entries[2].Value != "2" {
+		t.Errorf("third entry = %+v, want visits = 2", entries[2])
+	}
+	if entries := list("?pattern=last:*"); len(entries) != 2 {
+		t.Errorf("entries matching last:* = %+v, want 2", entries)
+	}
+
+	if resp := api.Delete("/api/v1/scripts/seen/kv?pattern=last:*", admin); resp.Code != http.StatusNoContent {
+		t.Fatalf("clear pattern: status %d: %s", resp.Code, resp.Body.String())
+	}
+	if entries := list(""); len(entries) != 1 || entries[0].Key != "visits" {
+		t.Errorf("entries after clearing last:* = %+v, want only visits", entries)
+	}
+	if resp := api.Delete("/api/v1/scripts/seen/kv", admin); resp.Code != http.StatusNoContent {
+		t.Fatalf("clear: status %d: %s", resp.Code, resp.Body.String())
+	}
+	if entries := list(""); len(entries) != 0 {
+		t.Errorf("entries after clear = %+v, want none", entries)
+	}
+}
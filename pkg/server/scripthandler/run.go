@@ -0,0 +1,143 @@
+package scripthandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// RunError describes an interpreter error
+type RunError struct {
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty" doc:"Line of the failing command, starting at 1"`
+	Column  int    `json:"column,omitempty" doc:"Column of the failing command, starting at 1"`
+	Command string `json:"command,omitempty" doc:"Source of the failing command"`
+	Stack   string `json:"stack,omitempty" doc:"Tcl stack trace (errorInfo)"`
+}
+
+// RunResult is the outcome of a test run
+type RunResult struct {
+	Stdout      string    `json:"stdout"`
+	Stderr      string    `json:"stderr"`
+	Value       string    `json:"value" doc:"Result of the last command, or the value passed to return"`
+	ContentType string    `json:"contentType" doc:"Content type /tools would serve the output as"`
+	DurationMs  float64   `json:"durationMs" doc:"Elapsed run time in milliseconds"`
+	Error       *RunError `json:"error,omitempty" doc:"Set when the script failed"`
+}
+
+type RunResponse struct {
+	Body RunResult `json:"body"`
+}
+
+type RunScriptRequest struct {
+	Name string `path:"name" doc:"Script name"`
+	Body struct {
+		Vars map[string]string `json:"vars,omitempty" doc:"Variables to set before running"`
+	} `json:"body"`
+}
+
+type RunContentRequest struct {
+	Body struct {
+		Content string            `json:"content" required:"true" doc:"Tcl source to run"`
+		Vars    map[string]string `json:"vars,omitempty" doc:"Variables to set before running"`
+	} `json:"body"`
+}
+
+// registerRunHandlers registers the script test run endpoints
+func registerRunHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "runScript",
+		Method:      "POST",
+		Path:        "/api/v1/scripts/{name}/run",
+		Summary:     "Run a saved script with the given variables",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, RunScript)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "runContent",
+		Method:      "POST",
+		Path:        "/api/v1/scripts/run",
+		Summary:     "Run unsaved script content (admin only)",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, RunContent)
+}
+
+func RunScript(ctx context.Context, input *RunScriptRequest) (*RunResponse, error) {
+	user, err := middleware.RequireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !CanRun(user, script.AccessLevel) {
+		return nil, huma.Error404NotFound("script not found")
+	}
+
+	vars, err := CheckVars(ctx, script, input.Body.Vars)
+	if err != nil {
+		return nil, err
+	}
+	return run(engine.WithUserID(ctx, user.ID), script, vars)
+}
+
+func RunContent(ctx context.Context, input *RunContentRequest) (*RunResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	script := appdb.Script{
+		Name:        "(unsaved)",
+		Content:     input.Body.Content,
+		AccessLevel: "admin",
+	}
+	return run(ctx, script, input.Body.Vars)
+}
+
+// run executes script and reports interpreter errors in the result rather
+// than as an HTTP error.
+func run(ctx context.Context, script appdb.Script, vars map[string]string) (*RunResponse, error) {
+	eng := ctx.Value(middleware.EngineKey).(*engine.Engine)
+
+	result, err := eng.Run(ctx, script, vars)
+	var tclErr *tcl.Error
+	if err != nil && !errors.As(err, &tclErr) {
+		switch {
+		case errors.Is(err, engine.ErrBusy):
+			return nil, huma.Error429TooManyRequests(err.Error())
+		case engine.IsTimeout(err):
+			// A queued run gave up waiting for a slot
+			return nil, huma.Error504GatewayTimeout(err.Error())
+		}
+		return nil, fmt.Errorf("failed to run script: %w", err)
+	}
+
+	response := &RunResponse{Body: RunResult{
+		Stdout:      result.Stdout,
+		Stderr:      result.Stderr,
+		Value:       result.Value,
+		ContentType: result.ContentType,
+		DurationMs:  float64(result.Duration) / float64(time.Millisecond),
+	}}
+	if tclErr != nil {
+		response.Body.Error = &RunError{
+			Message: tclErr.Message,
+			Line:    tclErr.Line,
+			Column:  tclErr.Column,
+			Command: tclErr.Command,
+			Stack:   tclErr.Info,
+		}
+	}
+	return response, nil
+}
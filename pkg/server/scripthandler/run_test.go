@@ -0,0 +1,109 @@
+package scripthandler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ytjohn/toolmin/pkg/server/scripthandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestRunScript(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	scripthandler.RegisterScriptHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	resp := api.Post("/api/v1/scripts", admin, map[string]any{
+		"name":    "greet",
+		"content": "puts \"Hello, $name\"\nexpr {$n * 2}",
+	})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", resp.Code, resp.Body.String())
+	}
+
+	decode := func(t *testing.T, body []byte) scripthandler.RunResult {
+		t.Helper()
+		var result scripthandler.RunResult
+		if err := json.Unmarshal(body, &result); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return result
+	}
+
+	t.Run("saved script", func(t *testing.T) {
+		resp := api.Post("/api/v1/scripts/greet/run", user, map[string]any{
+			"vars": map[string]string{"name": "world", "n": "21"},
+		})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", resp.Code, resp.Body.String())
+		}
+		result := decode(t, resp.Body.Bytes())
+		if result.Stdout != "Hello, world\n" || result.Value != "42" || result.Error != nil {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("invalid vars", func(t *testing.T) {
+		resp := api.Post("/api/v1/scripts", admin, map[string]any{
+			"name":    "typed",
+			"content": "expr {$n * 2}",
+			"params": []map[string]any{
+				{"name": "n", "type": "int", "required": true},
+				{"name": "level", "type": "enum", "enum": []string{"low", "high"}, "default": "low"},
+			},
+		})
+		if resp.Code != http.StatusCreated {
+			t.Fatalf("create: status %d: %s", resp.Code, resp.Body.String())
+		}
+
+		resp = api.Post("/api/v1/scripts/typed/run", user, map[string]any{
+			"vars": map[string]string{"n": "two", "level": "max"},
+		})
+		if resp.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("status %d, want 422: %s", resp.Code, resp.Body.String())
+		}
+		body := resp.Body.String()
+		for _, want := range []string{"body.vars.n", "must be an integer", "body.vars.level", "must be one of low, high"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("response %s does not mention %q", body, want)
+			}
+		}
+
+		resp = api.Post("/api/v1/scripts/typed/run", user, map[string]any{
+			"vars": map[string]string{"n": "21"},
+		})
+		if result := decode(t, resp.Body.Bytes()); resp.Code != http.StatusOK || result.Value != "42" {
+			t.Errorf("status %d, result %+v", resp.Code, result)
+		}
+	})
+
+	t.Run("unsaved content with error", func(t *testing.T) {
+		resp := api.Post("/api/v1/scripts/run", admin, map[string]any{
+			"content": "puts ok\nif {1} {\n  nosuch\n}",
+		})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", resp.Code, resp.Body.String())
+		}
+		result := decode(t, resp.Body.Bytes())
+		if result.Stdout != "ok\n" {
+			t.Errorf("stdout = %q", result.Stdout)
+		}
+		if result.Error == nil || result.Error.Line != 3 || result.Error.Column != 3 {
+			t.Errorf("error = %+v, want line 3 column 3", result.Error)
+		}
+	})
+
+	t.Run("unsaved content as user", func(t *testing.T) {
+		resp := api.Post("/api/v1/scripts/run", user, map[string]any{"content": "puts hi"})
+		if resp.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want 403", resp.Code)
+		}
+	})
+}
@@ -0,0 +1,249 @@
+package scripthandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/pmezard/go-difflib/difflib"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+)
+
+// ScriptVersion is a saved revision of a script
+type ScriptVersion struct {
+	Version     int64     `json:"version" doc:"Version number, starting at 1"`
+	Content     string    `json:"content,omitempty" doc:"Tcl source, omitted from listings"`
+	AccessLevel string    `json:"accessLevel" enum:"public,user,admin"`
+	UserID      int64     `json:"userId,omitempty" doc:"User who saved the version"`
+	Username    string    `json:"username,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+type ListVersionsResponse struct {
+	Body struct {
+		Versions []ScriptVersion `json:"versions"`
+	} `json:"body"`
+}
+
+type VersionPath struct {
+	Name    string `path:"name" doc:"Script name"`
+	Version int64  `path:"version" minimum:"1" doc:"Version number"`
+}
+
+type VersionResponse struct {
+	Body ScriptVersion `json:"body"`
+}
+
+type DiffRequest struct {
+	Name string `path:"name" doc:"Script name"`
+	From int64  `query:"from" required:"true" minimum:"1" doc:"Version to compare from"`
+	To   int64  `query:"to" minimum:"0" doc:"Version to compare to, defaults to the latest"`
+}
+
+type DiffResponse struct {
+	Body struct {
+		From int64  `json:"from"`
+		To   int64  `json:"to"`
+		Diff string `json:"diff" doc:"Unified diff, empty if the versions are the same"`
+	} `json:"body"`
+}
+
+type RollbackRequest struct {
+	Name string `path:"name" doc:"Script name"`
+	Body struct {
+		Version int64 `json:"version" required:"true" minimum:"1" doc:"Version to restore"`
+	} `json:"body"`
+}
+
+// registerVersionHandlers registers the script version endpoints
+func registerVersionHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "listScriptVersions",
+		Method:      "GET",
+		Path:        "/api/v1/scripts/{name}/versions",
+		Summary:     "List a script's versions, newest first (admin only)",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ListVersions)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "getScriptVersion",
+		Method:      "GET",
+		Path:        "/api/v1/scripts/{name}/versions/{version}",
+		Summary:     "Get a version of a script (admin only)",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, GetVersion)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "diffScriptVersions",
+		Method:      "GET",
+		Path:        "/api/v1/scripts/{name}/diff",
+		Summary:     "Show a unified diff between two versions of a script (admin only)",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, DiffVersions)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "rollbackScript",
+		Method:      "POST",
+		Path:        "/api/v1/scripts/{name}/rollback",
+		Summary:     "Restore an earlier version of a script as its newest version (admin only)",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, Rollback)
+}
+
+func ListVersions(ctx context.Context, input *ScriptPath) (*ListVersionsResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	versions, err := queries.ListScriptVersions(ctx, script.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+
+	response := &ListVersionsResponse{}
+	response.Body.Versions = make([]ScriptVersion, 0, len(versions))
+	for _, v := range versions {
+		response.Body.Versions = append(response.Body.Versions, ScriptVersion{
+			Version:     v.Version,
+			AccessLevel: v.AccessLevel,
+			UserID:      v.UserID.Int64,
+			Username:    v.Username.String,
+			Created:     v.Created,
+		})
+	}
+	return response, nil
+}
+
+func GetVersion(ctx context.Context, input *VersionPath) (*VersionResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+	v, err := loadVersion(ctx, appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB)), script.ID, input.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VersionResponse{Body: ScriptVersion{
+		Version:     v.Version,
+		Content:     v.Content,
+		AccessLevel: v.AccessLevel,
+		UserID:      v.UserID.Int64,
+		Created:     v.Created,
+	}}, nil
+}
+
+func DiffVersions(ctx context.Context, input *DiffRequest) (*DiffResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+
+	to := input.To
+	if to == 0 {
+		if to, err = queries.GetLatestScriptVersion(ctx, script.ID); err != nil {
+			return nil, fmt.Errorf("failed to get latest version: %w", err)
+		}
+	}
+	from, err := loadVersion(ctx, queries, script.ID, input.From)
+	if err != nil {
+		return nil, err
+	}
+	target, err := loadVersion(ctx, queries, script.ID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from.Content),
+		B:        difflib.SplitLines(target.Content),
+		FromFile: fmt.Sprintf("%s@v%d", script.Name, from.Version),
+		ToFile:   fmt.Sprintf("%s@v%d", script.Name, target.Version),
+		Context:  3,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff versions: %w", err)
+	}
+
+	response := &DiffResponse{}
+	response.Body.From = from.Version
+	response.Body.To = target.Version
+	response.Body.Diff = diff
+	return response, nil
+}
+
+func Rollback(ctx context.Context, input *RollbackRequest) (*ScriptResponse, error) {
+	user, err := middleware.RequireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logger := middleware.GetLogger(ctx)
+
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	db := ctx.Value(appdb.DbContextKey).(*sql.DB)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := appdb.New(db).WithTx(tx)
+
+	v, err := loadVersion(ctx, queries, script.ID, input.Body.Version)
+	if err != nil {
+		return nil, err
+	}
+	script, err = updateScript(ctx, queries, user, appdb.UpdateScriptParams{
+		Name:        script.Name,
+		Content:     v.Content,
+		AccessLevel: v.AccessLevel,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to roll back script: %w", err)
+	}
+	forgetParsed(ctx, script.ID)
+
+	logger.Info("script rolled back", "script", script.Name, "version", v.Version)
+	return scriptResponse(ctx, user, script)
+}
+
+// loadVersion fetches a version of a script, mapping a missing version to 404
+func loadVersion(ctx context.Context, queries *appdb.Queries, scriptID, version int64) (appdb.ScriptVersion, error) {
+	v, err := queries.GetScriptVersion(ctx, appdb.GetScriptVersionParams{ScriptID: scriptID, Version: version})
+	if errors.Is(err, sql.ErrNoRows) {
+		return v, huma.Error404NotFound(fmt.Sprintf("version %d not found", version))
+	}
+	if err != nil {
+		return v, fmt.Errorf("failed to get version: %w", err)
+	}
+	return v, nil
+}
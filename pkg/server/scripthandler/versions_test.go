@@ -0,0 +1,131 @@
+package scripthandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/server/scripthandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestScriptVersions(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	scripthandler.RegisterScriptHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	// A script saved before versions were recorded
+	if _, err := appdb.New(testDB.DB).CreateScript(context.Background(), appdb.CreateScriptParams{
+		Name: "legacy", Content: "puts old", AccessLevel: "user",
+	}); err != nil {
+		t.Fatalf("create legacy script: %v", err)
+	}
+
+	steps := []struct {
+		method string
+		path   string
+		body   map[string]any
+	}{
+		{"POST", "/api/v1/scripts", map[string]any{"name": "greet", "content": "set name world\nputs \"Hello, $name\""}},
+		{"PUT", "/api/v1/scripts/greet", map[string]any{"content": "set name toolmin\nputs \"Hello, $name\""}},
+		// Saving the same content again doesn't add a version
+		{"PUT", "/api/v1/scripts/greet", map[string]any{"content": "set name toolmin\nputs \"Hello, $name\""}},
+		{"PUT", "/api/v1/scripts/legacy", map[string]any{"content": "puts new"}},
+	}
+	for _, step := range steps {
+		if resp := api.Do(step.method, step.path, admin, step.body); resp.Code >= 300 {
+			t.Fatalf("%s %s: status %d: %s", step.method, step.path, resp.Code, resp.Body.String())
+		}
+	}
+
+	listVersions := func(t *testing.T, name string) []scripthandler.ScriptVersion {
+		t.Helper()
+		resp := api.Get("/api/v1/scripts/"+name+"/versions", admin)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("list versions: status %d: %s", resp.Code, resp.Body.String())
+		}
+		var body struct {
+			Versions []scripthandler.ScriptVersion `json:"versions"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode versions: %v", err)
+		}
+		return body.Versions
+	}
+
+	t.Run("list", func(t *testing.T) {
+		versions := listVersions(t, "greet")
+		if len(versions) != 2 || versions[0].Version != 2 || versions[1].Version != 1 {
+			t.Fatalf("versions = %+v, want 2 then 1", versions)
+		}
+		if versions[0].Username == "" {
+			t.Errorf("version 2 has no author")
+		}
+		if got := listVersions(t, "legacy"); len(got) != 2 {
+			t.Errorf("legacy script has %d versions, want the original and the update", len(got))
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		resp := api.Get("/api/v1/scripts/greet/versions/1", admin)
+		var v scripthandler.ScriptVersion
+		if err := json.Unmarshal(resp.Body.Bytes(), &v); err != nil {
+			t.Fatalf("decode version: %v", err)
+		}
+		if !strings.Contains(v.Content, "world") {
+			t.Errorf("version 1 content = %q", v.Content)
+		}
+		if resp := api.Get("/api/v1/scripts/greet/versions/9", admin); resp.Code != http.StatusNotFound {
+			t.Errorf("missing version: status %d, want 404", resp.Code)
+		}
+		if resp := api.Get("/api/v1/scripts/greet/versions/1", user); resp.Code != http.StatusForbidden {
+			t.Errorf("get as user: status %d, want 403", resp.Code)
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		resp := api.Get("/api/v1/scripts/greet/diff?from=1", admin)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("diff: status %d: %s", resp.Code, resp.Body.String())
+		}
+		var body struct {
+			To   int64  `json:"to"`
+			Diff string `json:"diff"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode diff: %v", err)
+		}
+		want := "--- greet@v1\n+++ greet@v2\n@@ -1,2 +1,2 @@\n-set name world\n+set name toolmin\n puts \"Hello, $name\"\n"
+		if body.To != 2 || body.Diff != want {
+			t.Errorf("diff to v%d =\n%s\nwant\n%s", body.To, body.Diff, want)
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		resp := api.Post("/api/v1/scripts/greet/rollback", admin, map[string]any{"version": 1})
+		if resp.Code != http.StatusOK {
+			t.Fatalf("rollback: status %d: %s", resp.Code, resp.Body.String())
+		}
+		var script scripthandler.Script
+		if err := json.Unmarshal(resp.Body.Bytes(), &script); err != nil {
+			t.Fatalf("decode script: %v", err)
+		}
+		if !strings.Contains(script.Content, "world") {
+			t.Errorf("content after rollback = %q", script.Content)
+		}
+		if versions := listVersions(t, "greet"); len(versions) != 3 {
+			t.Errorf("got %d versions after rollback, want 3", len(versions))
+		}
+		if resp := api.Post("/api/v1/scripts/greet/rollback", admin, map[string]any{"version": 9}); resp.Code != http.StatusNotFound {
+			t.Errorf("rollback to missing version: status %d, want 404", resp.Code)
+		}
+	})
+}
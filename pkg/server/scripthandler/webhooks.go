@@ -0,0 +1,222 @@
+package scripthandler
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/secrets"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+)
+
+// Webhook is a URL other systems can POST to to run a script
+type Webhook struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path" doc:"Path to POST signed requests to"`
+	Secret    string    `json:"secret,omitempty" doc:"HMAC-SHA256 signing secret, only returned when it is generated"`
+	SecretKey string    `json:"secretKey" doc:"Name of the secret holding the signing secret"`
+	Created   time.Time `json:"created"`
+}
+
+type WebhookResponse struct {
+	Body Webhook `json:"body"`
+}
+
+// registerWebhookHandlers registers the script webhook endpoints
+func registerWebhookHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "getScriptWebhook",
+		Method:      "GET",
+		Path:        "/api/v1/scripts/{name}/webhook",
+		Summary:     "Get a script's webhook (admin only)",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, GetWebhook)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "createScriptWebhook",
+		Method:      "POST",
+		Path:        "/api/v1/scripts/{name}/webhook",
+		Summary:     "Create a script's webhook, or generate a new secret for it (admin only)",
+		Description: "The response holds the signing secret. It is stored encrypted and not returned again.",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, CreateWebhook)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "deleteScriptWebhook",
+		Method:      "DELETE",
+		Path:        "/api/v1/scripts/{name}/webhook",
+		Summary:     "Delete a script's webhook and its secret (admin only)",
+		Tags:        []string{"scripts"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, DeleteWebhook)
+}
+
+func GetWebhook(ctx context.Context, input *ScriptPath) (*WebhookResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+	hook, err := loadWebhook(ctx, appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB)), script.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookResponse{Body: toWebhook(hook)}, nil
+}
+
+func CreateWebhook(ctx context.Context, input *ScriptPath) (*WebhookResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	svc, _ := ctx.Value(middleware.SecretsKey).(*secrets.Service)
+	if svc == nil {
+		return nil, huma.Error503ServiceUnavailable("webhooks are disabled: no secrets passphrase configured")
+	}
+	logger := middleware.GetLogger(ctx)
+
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	db := ctx.Value(appdb.DbContextKey).(*sql.DB)
+	hook, err := appdb.New(db).GetScriptWebhook(ctx, script.ID)
+	if err == nil {
+		if _, err := svc.Update(ctx, hook.SecretKey, secret); err != nil {
+			return nil, fmt.Errorf("failed to update webhook secret: %w", err)
+		}
+		logger.Info("webhook secret rotated", "script", script.Name, "webhook", hook.ID)
+		response := &WebhookResponse{Body: toWebhook(hook)}
+		response.Body.Secret = secret
+		return response, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	secretKey := "webhook." + id
+	sealed, err := svc.Encrypt(secretKey, []byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := appdb.New(db).WithTx(tx)
+
+	if _, err := queries.CreateSecret(ctx, appdb.CreateSecretParams{Key: secretKey, Value: sealed}); err != nil {
+		return nil, fmt.Errorf("failed to create webhook secret: %w", err)
+	}
+	hook, err = queries.CreateWebhook(ctx, appdb.CreateWebhookParams{
+		ID:        id,
+		ScriptID:  script.ID,
+		SecretKey: secretKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit webhook: %w", err)
+	}
+
+	logger.Info("webhook created", "script", script.Name, "webhook", hook.ID)
+	response := &WebhookResponse{Body: toWebhook(hook)}
+	response.Body.Secret = secret
+	return response, nil
+}
+
+func DeleteWebhook(ctx context.Context, input *ScriptPath) (*struct{}, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	script, err := LoadScript(ctx, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	db := ctx.Value(appdb.DbContextKey).(*sql.DB)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	queries := appdb.New(db).WithTx(tx)
+
+	hook, err := loadWebhook(ctx, queries, script.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := deleteWebhook(ctx, queries, hook); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("webhook deleted", "script", script.Name, "webhook", hook.ID)
+	return &struct{}{}, nil
+}
+
+// loadWebhook fetches a script's webhook, mapping a missing row to a 404
+func loadWebhook(ctx context.Context, queries *appdb.Queries, scriptID int64) (appdb.Webhook, error) {
+	hook, err := queries.GetScriptWebhook(ctx, scriptID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return hook, huma.Error404NotFound("script has no webhook")
+	}
+	if err != nil {
+		return hook, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return hook, nil
+}
+
+// deleteWebhook removes a webhook and its signing secret
+func deleteWebhook(ctx context.Context, queries *appdb.Queries, hook appdb.Webhook) error {
+	if err := queries.DeleteSecret(ctx, hook.SecretKey); err != nil {
+		return fmt.Errorf("failed to delete webhook secret: %w", err)
+	}
+	if err := queries.DeleteScriptWebhook(ctx, hook.ScriptID); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func toWebhook(hook appdb.Webhook) Webhook {
+	return Webhook{
+		ID:        hook.ID,
+		Path:      "/hooks/" + hook.ID,
+		SecretKey: hook.SecretKey,
+		Created:   hook.Created,
+	}
+}
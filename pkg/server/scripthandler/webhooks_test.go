@@ -0,0 +1,124 @@
+package scripthandler_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/secrets"
+	"github.com/ytjohn/toolmin/pkg/server/scripthandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestScriptWebhooks(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	scripthandler.RegisterScriptHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	ctx := context.Background()
+	svc, err := secrets.New(ctx, testDB.DB, "test passphrase")
+	if err != nil {
+		t.Fatalf("Failed to create secrets service: %v", err)
+	}
+	if resp := api.Post("/api/v1/scripts", admin, map[string]any{"name": "deploy", "content": "puts $ref"}); resp.Code != http.StatusCreated {
+		t.Fatalf("create script: status %d: %s", resp.Code, resp.Body.String())
+	}
+
+	createWebhook := func(t *testing.T) scripthandler.Webhook {
+		t.Helper()
+		resp := api.Post("/api/v1/scripts/deploy/webhook", admin)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("create webhook: status %d: %s", resp.Code, resp.Body.String())
+		}
+		var hook scripthandler.Webhook
+		if err := json.Unmarshal(resp.Body.Bytes(), &hook); err != nil {
+			t.Fatalf("decode webhook: %v", err)
+		}
+		return hook
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		want   int
+	}{
+		{"get before create", "GET", "/api/v1/scripts/deploy/webhook", admin, http.StatusNotFound},
+		{"create as user", "POST", "/api/v1/scripts/deploy/webhook", user, http.StatusForbidden},
+		{"create for missing script", "POST", "/api/v1/scripts/nosuch/webhook", admin, http.StatusNotFound},
+		{"delete missing", "DELETE", "/api/v1/scripts/deploy/webhook", admin, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := api.Do(tt.method, tt.path, tt.auth)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.want, resp.Body.String())
+			}
+		})
+	}
+
+	var hook scripthandler.Webhook
+	t.Run("create stores the secret", func(t *testing.T) {
+		hook = createWebhook(t)
+		if hook.Secret == "" || hook.Path != "/hooks/"+hook.ID {
+			t.Fatalf("webhook = %+v", hook)
+		}
+		stored, err := svc.Get(ctx, hook.SecretKey)
+		if err != nil || stored != hook.Secret {
+			t.Errorf("stored secret = %q, %v; want %q", stored, err, hook.Secret)
+		}
+	})
+
+	t.Run("get hides the secret", func(t *testing.T) {
+		resp := api.Get("/api/v1/scripts/deploy/webhook", admin)
+		var got scripthandler.Webhook
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Fatalf("decode webhook: %v", err)
+		}
+		if got.ID != hook.ID || got.Secret != "" {
+			t.Errorf("webhook = %+v", got)
+		}
+	})
+
+	t.Run("create again rotates the secret", func(t *testing.T) {
+		rotated := createWebhook(t)
+		if rotated.ID != hook.ID || rotated.Secret == hook.Secret {
+			t.Fatalf("rotated webhook = %+v, was %+v", rotated, hook)
+		}
+		if stored, _ := svc.Get(ctx, hook.SecretKey); stored != rotated.Secret {
+			t.Errorf("stored secret wasn't rotated")
+		}
+	})
+
+	t.Run("delete removes the secret", func(t *testing.T) {
+		if resp := api.Delete("/api/v1/scripts/deploy/webhook", admin); resp.Code != http.StatusNoContent {
+			t.Fatalf("status = %d: %s", resp.Code, resp.Body.String())
+		}
+		if _, err := svc.Get(ctx, hook.SecretKey); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("secret still stored: %v", err)
+		}
+	})
+
+	t.Run("deleting the script removes its webhook", func(t *testing.T) {
+		hook := createWebhook(t)
+		if resp := api.Delete("/api/v1/scripts/deploy", admin); resp.Code != http.StatusNoContent {
+			t.Fatalf("status = %d: %s", resp.Code, resp.Body.String())
+		}
+		if _, err := appdb.New(testDB.DB).GetWebhook(ctx, hook.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("webhook still stored: %v", err)
+		}
+		if _, err := svc.Get(ctx, hook.SecretKey); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("secret still stored: %v", err)
+		}
+	})
+}
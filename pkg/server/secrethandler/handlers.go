@@ -0,0 +1,267 @@
+package secrethandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/secrets"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+)
+
+// Secret describes a stored secret. Values are never included.
+type Secret struct {
+	Key     string    `json:"key" doc:"Secret name"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+type SecretPath struct {
+	Key string `path:"key" doc:"Secret name"`
+}
+
+type SecretResponse struct {
+	Body Secret `json:"body"`
+}
+
+type ListSecretsResponse struct {
+	Body struct {
+		Secrets []Secret `json:"secrets"`
+	} `json:"body"`
+}
+
+type CreateSecretRequest struct {
+	Body struct {
+		Key   string `json:"key" required:"true" pattern:"^[A-Za-z0-9_.-]+$" maxLength:"100" doc:"Secret name"`
+		Value string `json:"value" required:"true" doc:"Secret value, stored encrypted"`
+	} `json:"body"`
+}
+
+type UpdateSecretRequest struct {
+	Key  string `path:"key" doc:"Secret name"`
+	Body struct {
+		Value string `json:"value" required:"true" doc:"Secret value, stored encrypted"`
+	} `json:"body"`
+}
+
+type RevealSecretResponse struct {
+	Body struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"body"`
+}
+
+// RegisterSecretHandlers registers the secret management endpoints. All of
+// them are admin only.
+func RegisterSecretHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "listSecrets",
+		Method:      "GET",
+		Path:        "/api/v1/secrets",
+		Summary:     "List secret keys",
+		Tags:        []string{"secrets"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ListSecrets)
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "createSecret",
+		Method:        "POST",
+		Path:          "/api/v1/secrets",
+		Summary:       "Create a secret",
+		Tags:          []string{"secrets"},
+		DefaultStatus: 201,
+		Security:      []map[string][]string{{"bearerAuth": {}}},
+	}, CreateSecret)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "updateSecret",
+		Method:      "PUT",
+		Path:        "/api/v1/secrets/{key}",
+		Summary:     "Replace a secret's value",
+		Tags:        []string{"secrets"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, UpdateSecret)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "deleteSecret",
+		Method:      "DELETE",
+		Path:        "/api/v1/secrets/{key}",
+		Summary:     "Delete a secret",
+		Tags:        []string{"secrets"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, DeleteSecret)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "revealSecret",
+		Method:      "POST",
+		Path:        "/api/v1/secrets/{key}/reveal",
+		Summary:     "Reveal a secret's value (audited)",
+		Tags:        []string{"secrets"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, RevealSecret)
+}
+
+func ListSecrets(ctx context.Context, _ *struct{}) (*ListSecretsResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	keys, err := queries.ListSecretKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	response := &ListSecretsResponse{}
+	response.Body.Secrets = make([]Secret, 0, len(keys))
+	for _, k := range keys {
+		response.Body.Secrets = append(response.Body.Secrets, Secret{
+			Key:     k.Key,
+			Created: k.Created,
+			Updated: k.Updated,
+		})
+	}
+	return response, nil
+}
+
+func CreateSecret(ctx context.Context, input *CreateSecretRequest) (*SecretResponse, error) {
+	user, err := middleware.RequireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := service(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := svc.Create(ctx, input.Body.Key, input.Body.Value)
+	if appdb.IsUniqueViolation(err) {
+		return nil, huma.Error409Conflict(fmt.Sprintf("secret %q already exists", input.Body.Key))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secret: %w", err)
+	}
+
+	audit(ctx, user, "secret.create", secret.Key)
+	return &SecretResponse{Body: toSecret(secret)}, nil
+}
+
+func UpdateSecret(ctx context.Context, input *UpdateSecretRequest) (*SecretResponse, error) {
+	user, err := middleware.RequireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := service(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := svc.Update(ctx, input.Key, input.Body.Value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("secret not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update secret: %w", err)
+	}
+
+	audit(ctx, user, "secret.update", secret.Key)
+	return &SecretResponse{Body: toSecret(secret)}, nil
+}
+
+func DeleteSecret(ctx context.Context, input *SecretPath) (*struct{}, error) {
+	user, err := middleware.RequireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	if _, err := queries.GetSecret(ctx, input.Key); errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("secret not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get secret: %w", err)
+	}
+	if err := queries.DeleteSecret(ctx, input.Key); err != nil {
+		return nil, fmt.Errorf("failed to delete secret: %w", err)
+	}
+
+	audit(ctx, user, "secret.delete", input.Key)
+	return &struct{}{}, nil
+}
+
+func RevealSecret(ctx context.Context, input *SecretPath) (*RevealSecretResponse, error) {
+	user, err := middleware.RequireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := service(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	if _, err := queries.GetSecret(ctx, input.Key); errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("secret not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	// A reveal that can't be audited doesn't happen
+	if err := writeAudit(ctx, user, "secret.reveal", input.Key); err != nil {
+		return nil, fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	value, err := svc.Get(ctx, input.Key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("secret not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reveal secret: %w", err)
+	}
+
+	response := &RevealSecretResponse{}
+	response.Body.Key = input.Key
+	response.Body.Value = value
+	return response, nil
+}
+
+// service returns the secrets service, or a 503 if no passphrase is configured
+func service(ctx context.Context) (*secrets.Service, error) {
+	svc, _ := ctx.Value(middleware.SecretsKey).(*secrets.Service)
+	if svc == nil {
+		return nil, huma.Error503ServiceUnavailable("secrets are disabled: no passphrase configured")
+	}
+	return svc, nil
+}
+
+// audit logs a change to a secret and records it in the audit log. The
+// change is already made, so a failure to record it is only logged.
+func audit(ctx context.Context, user appdb.User, action, key string) {
+	if err := writeAudit(ctx, user, action, key); err != nil {
+		middleware.GetLogger(ctx).Error("failed to write audit log", "action", action, "key", key, "error", err)
+	}
+}
+
+// writeAudit logs an action on a secret and records it in the audit log
+func writeAudit(ctx context.Context, user appdb.User, action, key string) error {
+	logger := middleware.GetLogger(ctx)
+	logger.Info("audit", "action", action, "key", key, "user_id", user.ID, "email", user.Email)
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	return queries.CreateAuditLog(ctx, appdb.CreateAuditLogParams{
+		UserID: user.ID,
+		Action: action,
+		Target: key,
+	})
+}
+
+func toSecret(s appdb.Secret) Secret {
+	return Secret{
+		Key:     s.Key,
+		Created: s.Created,
+		Updated: s.Updated,
+	}
+}
@@ -0,0 +1,110 @@
+package secrethandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ytjohn/toolmin/pkg/server/secrethandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestSecretHandlers(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	secrethandler.RegisterSecretHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	resp := api.Post("/api/v1/secrets", admin, map[string]any{"key": "api_token", "value": "hunter2"})
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", resp.Code, resp.Body.String())
+	}
+	if strings.Contains(resp.Body.String(), "hunter2") {
+		t.Errorf("create response leaked the value: %s", resp.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		body   any
+		want   int
+	}{
+		{"list as user", "GET", "/api/v1/secrets", user, nil, http.StatusForbidden},
+		{"create as user", "POST", "/api/v1/secrets", user, map[string]any{"key": "x", "value": "y"}, http.StatusForbidden},
+		{"create duplicate", "POST", "/api/v1/secrets", admin, map[string]any{"key": "api_token", "value": "y"}, http.StatusConflict},
+		{"reveal as user", "POST", "/api/v1/secrets/api_token/reveal", user, nil, http.StatusForbidden},
+		{"reveal missing", "POST", "/api/v1/secrets/nosuch/reveal", admin, nil, http.StatusNotFound},
+		{"update", "PUT", "/api/v1/secrets/api_token", admin, map[string]any{"value": "changed"}, http.StatusOK},
+		{"update missing", "PUT", "/api/v1/secrets/nosuch", admin, map[string]any{"value": "changed"}, http.StatusNotFound},
+		{"delete missing", "DELETE", "/api/v1/secrets/nosuch", admin, nil, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []any{tt.auth}
+			if tt.body != nil {
+				args = append(args, tt.body)
+			}
+			resp := api.Do(tt.method, tt.path, args...)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.want, resp.Body.String())
+			}
+		})
+	}
+
+	resp = api.Get("/api/v1/secrets", admin)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("list: status %d", resp.Code)
+	}
+	if strings.Contains(resp.Body.String(), "changed") || strings.Contains(resp.Body.String(), "value") {
+		t.Errorf("listing exposed values: %s", resp.Body.String())
+	}
+
+	resp = api.Post("/api/v1/secrets/api_token/reveal", admin)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("reveal: status %d", resp.Code)
+	}
+	var revealed struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &revealed); err != nil {
+		t.Fatalf("decode reveal: %v", err)
+	}
+	if revealed.Value != "changed" {
+		t.Errorf("revealed value = %q, want changed", revealed.Value)
+	}
+
+	var audited int
+	err := testDB.QueryRowContext(context.Background(),
+		"SELECT COUNT(*) FROM audit_log WHERE action = 'secret.reveal' AND target = 'api_token'").Scan(&audited)
+	if err != nil || audited != 1 {
+		t.Errorf("reveal audit entries = %d, %v", audited, err)
+	}
+
+	if resp := api.Delete("/api/v1/secrets/api_token", admin); resp.Code != http.StatusNoContent {
+		t.Errorf("delete: status %d", resp.Code)
+	}
+
+	// Reveals fail closed when they can't be audited
+	if resp := api.Post("/api/v1/secrets", admin, map[string]any{"key": "unaudited", "value": "s3cret"}); resp.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", resp.Code, resp.Body.String())
+	}
+	if _, err := testDB.ExecContext(context.Background(), "DROP TABLE audit_log"); err != nil {
+		t.Fatalf("drop audit_log: %v", err)
+	}
+	resp = api.Post("/api/v1/secrets/unaudited/reveal", admin)
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("unaudited reveal: status %d, want 500", resp.Code)
+	}
+	if strings.Contains(resp.Body.String(), "s3cret") {
+		t.Errorf("unaudited reveal leaked the value: %s", resp.Body.String())
+	}
+}
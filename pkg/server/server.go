@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humago"
@@ -17,8 +18,20 @@ import (
 	"github.com/ytjohn/toolmin/pkg/about"
 	appdb "github.com/ytjohn/toolmin/pkg/appdb"
 	"github.com/ytjohn/toolmin/pkg/auth"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/scheduler"
+	"github.com/ytjohn/toolmin/pkg/secrets"
 	"github.com/ytjohn/toolmin/pkg/server/authhandler"
+	"github.com/ytjohn/toolmin/pkg/server/exechandler"
+	"github.com/ytjohn/toolmin/pkg/server/libraryhandler"
 	"github.com/ytjohn/toolmin/pkg/server/middleware"
+	"github.com/ytjohn/toolmin/pkg/server/pagehandler"
+	"github.com/ytjohn/toolmin/pkg/server/schedulehandler"
+	"github.com/ytjohn/toolmin/pkg/server/scripthandler"
+	"github.com/ytjohn/toolmin/pkg/server/secrethandler"
+	"github.com/ytjohn/toolmin/pkg/server/templatehandler"
+	"github.com/ytjohn/toolmin/pkg/server/toolhandler"
+	"github.com/ytjohn/toolmin/pkg/server/varhandler"
 )
 
 //go:embed web
@@ -62,6 +75,10 @@ type Server struct {
 	log        *slog.Logger
 	mainRouter *http.ServeMux
 	db         *sql.DB
+	engine     *engine.Engine
+
+	tokenService *auth.TokenService
+	secrets      *secrets.Service
 }
 
 // Config holds server configuration
@@ -70,6 +87,12 @@ type Config struct {
 	Port          int
 	Debug         bool
 	WebContentDir string
+	// SecretsPassphrase unlocks the secrets table. Secrets are disabled if empty.
+	SecretsPassphrase string
+	// ScriptLimits are the default execution limits for scripts
+	ScriptLimits engine.Limits
+	// RunRetention is how long script run history is kept; zero keeps it forever
+	RunRetention time.Duration
 }
 
 // New creates a new server instance
@@ -95,8 +118,9 @@ func (s *Server) chooseFileSystem() FileSystem {
 	return EmbeddedFS{fs: subFS}
 }
 
-// spaFileServer creates a file server that falls back to index.html
-func spaFileServer(staticFS http.FileSystem) http.Handler {
+// spaFileServer creates a file server that falls back to index.html. Page
+// templates with no file are rendered from the pages table if one exists.
+func spaFileServer(staticFS http.FileSystem, pages *pagehandler.Handler) http.Handler {
 	fileServer := http.FileServer(staticFS)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
@@ -109,6 +133,11 @@ func spaFileServer(staticFS http.FileSystem) http.Handler {
 			return
 		}
 
+		// Then a page stored in the database
+		if slug, ok := pagehandler.TemplateSlug(path); ok && pages.ServePage(w, r, slug) {
+			return
+		}
+
 		// Check if it's a directory
 		if path != "/" {
 			f, err = staticFS.Open(path + "/index.html")
@@ -125,6 +154,21 @@ func spaFileServer(staticFS http.FileSystem) http.Handler {
 	})
 }
 
+// setupSecrets derives the secrets key, refusing to continue if the
+// passphrase doesn't match the one the database was set up with
+func (s *Server) setupSecrets() error {
+	if s.config.SecretsPassphrase == "" {
+		s.log.Warn("no secrets passphrase configured, secrets are disabled")
+		return nil
+	}
+	svc, err := secrets.New(context.Background(), s.db, s.config.SecretsPassphrase)
+	if err != nil {
+		return fmt.Errorf("failed to unlock secrets: %w", err)
+	}
+	s.secrets = svc
+	return nil
+}
+
 // setupAPI configures the API routes
 func (s *Server) setupAPI() *http.ServeMux {
 	apiRouter := http.NewServeMux()
@@ -147,6 +191,7 @@ func (s *Server) setupAPI() *http.ServeMux {
 		fmt.Printf("Failed to initialize token service: %v", err)
 		return nil
 	}
+	s.tokenService = tokenService
 
 	api := humago.New(apiRouter, config)
 
@@ -156,6 +201,8 @@ func (s *Server) setupAPI() *http.ServeMux {
 	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
 		ctx = huma.WithValue(ctx, middleware.TokenServiceKey, tokenService)
 		ctx = huma.WithValue(ctx, middleware.KeyManagerKey, tokenService.GetKeyManager())
+		ctx = huma.WithValue(ctx, middleware.EngineKey, s.engine)
+		ctx = huma.WithValue(ctx, middleware.SecretsKey, s.secrets)
 		next(ctx)
 	})
 	api.UseMiddleware(middleware.WithAuth)
@@ -171,12 +218,31 @@ func (s *Server) setupAPI() *http.ServeMux {
 	}, GetVersion)
 
 	authhandler.RegisterAuthHandlers(api)
+	exechandler.RegisterExecHandlers(api)
+	libraryhandler.RegisterLibraryHandlers(api)
+	pagehandler.RegisterPageHandlers(api)
+	schedulehandler.RegisterScheduleHandlers(api)
+	scripthandler.RegisterScriptHandlers(api)
+	secrethandler.RegisterSecretHandlers(api)
+	templatehandler.RegisterTemplateHandlers(api)
+	varhandler.RegisterVarHandlers(api)
 
 	return apiRouter
 }
 
 // Start initializes and starts the server
 func (s *Server) Start() error {
+	if err := s.setupSecrets(); err != nil {
+		return err
+	}
+	s.engine = engine.New(s.db, s.secrets, s.log)
+	s.engine.SetLimits(s.config.ScriptLimits)
+	if tools, err := fs.Sub(s.chooseFileSystem(), "templates/tools"); err == nil {
+		s.engine.SetTemplates(tools)
+	}
+	go s.engine.PruneInBackground(s.config.RunRetention)
+	go scheduler.New(s.db, s.engine, s.log).Run(context.Background())
+
 	// Setup API with database context
 	apiRouter := s.setupAPI()
 
@@ -192,6 +258,10 @@ func (s *Server) Start() error {
 
 	s.mainRouter.Handle("/api/v1/", apiRouter)
 
+	// Tools run scripts from the database
+	toolhandler.New(s.db, s.tokenService, s.engine, s.log).Register(s.mainRouter)
+	toolhandler.NewHookHandler(s.db, s.secrets, s.engine, s.log).Register(s.mainRouter)
+
 	// Setup static file serving
 	fs := s.chooseFileSystem()
 	staticFS := http.FS(fs)
@@ -200,7 +270,7 @@ func (s *Server) Start() error {
 		fsType = "local"
 	}
 	s.log.Info("Using filesystem", "type", fsType, "path", s.config.WebContentDir)
-	s.mainRouter.Handle("/", spaFileServer(staticFS))
+	s.mainRouter.Handle("/", spaFileServer(staticFS, pagehandler.New(s.db, s.log)))
 
 	// Start server
 	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
@@ -0,0 +1,211 @@
+package templatehandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+)
+
+// Template is an html/template snippet scripts can fill in with render
+type Template struct {
+	Name    string    `json:"name" doc:"Template name, as passed to render"`
+	Content string    `json:"content" doc:"Go html/template source"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+type TemplatePath struct {
+	Name string `path:"name" doc:"Template name"`
+}
+
+type TemplateResponse struct {
+	Body Template `json:"body"`
+}
+
+type ListTemplatesResponse struct {
+	Body struct {
+		Templates []Template `json:"templates"`
+	} `json:"body"`
+}
+
+type CreateTemplateRequest struct {
+	Body struct {
+		Name    string `json:"name" required:"true" pattern:"^[A-Za-z0-9_.-]+$" maxLength:"100" doc:"Template name, as passed to render"`
+		Content string `json:"content" required:"true" doc:"Go html/template source"`
+	} `json:"body"`
+}
+
+type UpdateTemplateRequest struct {
+	Name string `path:"name" doc:"Template name"`
+	Body struct {
+		Content string `json:"content" required:"true" doc:"Go html/template source"`
+	} `json:"body"`
+}
+
+// RegisterTemplateHandlers registers the template endpoints. Any
+// authenticated user can read templates, only admins can change them.
+func RegisterTemplateHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "listTemplates",
+		Method:      "GET",
+		Path:        "/api/v1/templates",
+		Summary:     "List templates",
+		Tags:        []string{"templates"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ListTemplates)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "getTemplate",
+		Method:      "GET",
+		Path:        "/api/v1/templates/{name}",
+		Summary:     "Get a template",
+		Tags:        []string{"templates"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, GetTemplate)
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "createTemplate",
+		Method:        "POST",
+		Path:          "/api/v1/templates",
+		Summary:       "Create a template (admin only)",
+		Tags:          []string{"templates"},
+		DefaultStatus: 201,
+		Security:      []map[string][]string{{"bearerAuth": {}}},
+	}, CreateTemplate)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "updateTemplate",
+		Method:      "PUT",
+		Path:        "/api/v1/templates/{name}",
+		Summary:     "Update a template (admin only)",
+		Tags:        []string{"templates"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, UpdateTemplate)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "deleteTemplate",
+		Method:      "DELETE",
+		Path:        "/api/v1/templates/{name}",
+		Summary:     "Delete a template (admin only)",
+		Tags:        []string{"templates"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, DeleteTemplate)
+}
+
+func ListTemplates(ctx context.Context, _ *struct{}) (*ListTemplatesResponse, error) {
+	if _, err := middleware.RequireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	templates, err := queries.ListTemplates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	response := &ListTemplatesResponse{}
+	response.Body.Templates = make([]Template, 0, len(templates))
+	for _, t := range templates {
+		response.Body.Templates = append(response.Body.Templates, toTemplate(t))
+	}
+	return response, nil
+}
+
+func GetTemplate(ctx context.Context, input *TemplatePath) (*TemplateResponse, error) {
+	if _, err := middleware.RequireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	t, err := queries.GetTemplate(ctx, input.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("template not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return &TemplateResponse{Body: toTemplate(t)}, nil
+}
+
+func CreateTemplate(ctx context.Context, input *CreateTemplateRequest) (*TemplateResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := engine.ParseTemplate(input.Body.Name, input.Body.Content); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	t, err := queries.CreateTemplate(ctx, appdb.CreateTemplateParams{
+		Name:    input.Body.Name,
+		Content: input.Body.Content,
+	})
+	if appdb.IsUniqueViolation(err) {
+		return nil, huma.Error409Conflict(fmt.Sprintf("template %q already exists", input.Body.Name))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("template created", "name", t.Name)
+	return &TemplateResponse{Body: toTemplate(t)}, nil
+}
+
+func UpdateTemplate(ctx context.Context, input *UpdateTemplateRequest) (*TemplateResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := engine.ParseTemplate(input.Name, input.Body.Content); err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	t, err := queries.UpdateTemplate(ctx, appdb.UpdateTemplateParams{
+		Content: input.Body.Content,
+		Name:    input.Name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("template not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("template updated", "name", t.Name)
+	return &TemplateResponse{Body: toTemplate(t)}, nil
+}
+
+func DeleteTemplate(ctx context.Context, input *TemplatePath) (*struct{}, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	if _, err := queries.GetTemplate(ctx, input.Name); errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("template not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	if err := queries.DeleteTemplate(ctx, input.Name); err != nil {
+		return nil, fmt.Errorf("failed to delete template: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("template deleted", "name", input.Name)
+	return &struct{}{}, nil
+}
+
+func toTemplate(t appdb.Template) Template {
+	return Template{
+		Name:    t.Name,
+		Content: t.Content,
+		Created: t.Created,
+		Updated: t.Updated,
+	}
+}
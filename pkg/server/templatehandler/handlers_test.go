@@ -0,0 +1,82 @@
+package templatehandler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ytjohn/toolmin/pkg/server/templatehandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestTemplateHandlers(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	templatehandler.RegisterTemplateHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	banner := map[string]any{"name": "banner", "content": `<h1>{{.title}}</h1>`}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		body   any
+		want   int
+	}{
+		{"create", "POST", "/api/v1/templates", admin, banner, http.StatusCreated},
+		{"create as user", "POST", "/api/v1/templates", user, map[string]any{"name": "x", "content": "x"}, http.StatusForbidden},
+		{"create duplicate", "POST", "/api/v1/templates", admin, banner, http.StatusConflict},
+		{"create bad syntax", "POST", "/api/v1/templates", admin, map[string]any{"name": "x", "content": "{{.title"}, http.StatusUnprocessableEntity},
+		{"create bad name", "POST", "/api/v1/templates", admin, map[string]any{"name": "a/b", "content": "x"}, http.StatusUnprocessableEntity},
+		{"get as user", "GET", "/api/v1/templates/banner", user, nil, http.StatusOK},
+		{"get missing", "GET", "/api/v1/templates/nosuch", user, nil, http.StatusNotFound},
+		{"list anonymous", "GET", "/api/v1/templates", "", nil, http.StatusUnauthorized},
+		{"update", "PUT", "/api/v1/templates/banner", admin, map[string]any{"content": `<h2>{{.title}}</h2>`}, http.StatusOK},
+		{"update bad syntax", "PUT", "/api/v1/templates/banner", admin, map[string]any{"content": "{{end}}"}, http.StatusUnprocessableEntity},
+		{"update missing", "PUT", "/api/v1/templates/nosuch", admin, map[string]any{"content": "x"}, http.StatusNotFound},
+		{"delete as user", "DELETE", "/api/v1/templates/banner", user, nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []any
+			if tt.auth != "" {
+				args = append(args, tt.auth)
+			}
+			if tt.body != nil {
+				args = append(args, tt.body)
+			}
+			resp := api.Do(tt.method, tt.path, args...)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.want, resp.Body.String())
+			}
+		})
+	}
+
+	resp := api.Get("/api/v1/templates", user)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("list status = %d: %s", resp.Code, resp.Body.String())
+	}
+	var list struct {
+		Templates []templatehandler.Template `json:"templates"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode templates: %v", err)
+	}
+	if len(list.Templates) != 1 || list.Templates[0].Content != `<h2>{{.title}}</h2>` {
+		t.Errorf("templates = %+v, want the updated banner", list.Templates)
+	}
+
+	if resp := api.Delete("/api/v1/templates/banner", admin); resp.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d: %s", resp.Code, resp.Body.String())
+	}
+	if resp := api.Delete("/api/v1/templates/banner", admin); resp.Code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want 404", resp.Code)
+	}
+}
@@ -0,0 +1,207 @@
+// Package toolhandler serves scripts from the database at /tools/{name} so
+// their output can be swapped into a page by htmx, streams their output as
+// Server-Sent Events at /tools/{name}/stream, and runs them for signed
+// webhook requests at /hooks/{id}.
+package toolhandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/auth"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/params"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// maxFormMemory is the amount of a multipart form kept in memory
+const maxFormMemory = 1 << 20
+
+// Handler runs scripts for /tools/{name} requests
+type Handler struct {
+	db           *sql.DB
+	tokenService *auth.TokenService
+	engine       *engine.Engine
+	log          *slog.Logger
+}
+
+// New creates a new tool handler
+func New(db *sql.DB, tokenService *auth.TokenService, eng *engine.Engine, log *slog.Logger) *Handler {
+	return &Handler{
+		db:           db,
+		tokenService: tokenService,
+		engine:       eng,
+		log:          log,
+	}
+}
+
+// Register mounts the tool routes on mux
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.Handle("/tools/{name}", h)
+	mux.HandleFunc("GET /tools/{name}/stream", h.ServeStream)
+}
+
+// ServeHTTP looks up the script named in the path, checks its access level
+// and runs it with the request's query and form parameters as variables.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	logger := h.log.With("path", r.URL.Path, "method", r.Method, "script", r.PathValue("name"))
+
+	ctx, script, vars, ok := h.prepare(w, r, logger)
+	if !ok {
+		return
+	}
+
+	result, err := h.engine.Run(ctx, script, vars)
+	if writeResult(w, logger, result, err) {
+		logger.Info("tool completed", "duration_ms", time.Since(start).Milliseconds())
+	}
+}
+
+// prepare loads the script named in the path, authorizes the request and
+// checks its variables against the script's params. If it reports false it
+// has already written the error response.
+func (h *Handler) prepare(w http.ResponseWriter, r *http.Request, logger *slog.Logger) (context.Context, appdb.Script, map[string]string, bool) {
+	script, err := appdb.New(h.db).GetScript(r.Context(), r.PathValue("name"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "tool not found", http.StatusNotFound)
+		return nil, script, nil, false
+	}
+	if err != nil {
+		logger.Error("failed to load script", "error", err)
+		http.Error(w, "failed to load tool", http.StatusInternalServerError)
+		return nil, script, nil, false
+	}
+
+	ctx, status := h.authorize(r, script.AccessLevel)
+	if status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return nil, script, nil, false
+	}
+
+	vars, err := requestVars(r)
+	if err != nil {
+		http.Error(w, "invalid form data", http.StatusBadRequest)
+		return nil, script, nil, false
+	}
+	vars, problems, err := params.Check(ctx, appdb.New(h.db), script.ID, vars)
+	if err != nil {
+		logger.Error("failed to check params", "error", err)
+		http.Error(w, "failed to load tool", http.StatusInternalServerError)
+		return nil, script, nil, false
+	}
+	if len(problems) > 0 {
+		writeViolations(w, problems)
+		return nil, script, nil, false
+	}
+	return ctx, script, vars, true
+}
+
+// writeResult writes a run's output, or its error with the matching
+// status. It reports whether the run succeeded.
+func writeResult(w http.ResponseWriter, logger *slog.Logger, result *engine.Result, err error) bool {
+	if err != nil {
+		if errors.Is(err, engine.ErrBusy) {
+			logger.Warn("tool busy", "error", err)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return false
+		}
+		// Queued runs can time out before the script starts
+		var tclErr *tcl.Error
+		if !errors.As(err, &tclErr) && !engine.IsTimeout(err) {
+			logger.Error("failed to run script", "error", err)
+			http.Error(w, "failed to run tool", http.StatusInternalServerError)
+			return false
+		}
+		logger.Warn("script error", "error", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return false
+	}
+
+	// Scripts that don't print anything are served their return value
+	body := result.Stdout
+	if body == "" {
+		body = result.Value
+	}
+
+	w.Header().Set("Content-Type", result.ContentType+"; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if _, err := w.Write([]byte(body)); err != nil {
+		logger.Debug("failed to write response", "error", err)
+	}
+	return true
+}
+
+// writeViolations responds 422 with a line for each invalid parameter
+func writeViolations(w http.ResponseWriter, problems []params.Violation) {
+	lines := make([]string, 0, len(problems))
+	for _, v := range problems {
+		lines = append(lines, v.Error())
+	}
+	http.Error(w, "invalid parameters:\n"+strings.Join(lines, "\n"), http.StatusUnprocessableEntity)
+}
+
+// errorStatus maps a script error to an HTTP status. Scripts stopped for
+// running too long or too many commands get 504, too much output or
+// memory gets 413.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, engine.ErrOutputLimit), errors.Is(err, engine.ErrMemoryLimit):
+		return http.StatusRequestEntityTooLarge
+	case engine.IsTimeout(err), errors.Is(err, engine.ErrCommandLimit):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// authorize checks the request's bearer token against the script's access
+// level and returns the HTTP status to respond with. On success the
+// returned context attributes the run to the user, if there is one.
+func (h *Handler) authorize(r *http.Request, accessLevel string) (context.Context, int) {
+	ctx := r.Context()
+	authHeader := r.Header.Get("Authorization")
+	user, _, err := middleware.Authenticate(ctx, h.tokenService, h.db, authHeader)
+	if err != nil {
+		// Public tools don't need a token, but record who ran them if given one
+		if accessLevel == "public" {
+			return ctx, http.StatusOK
+		}
+		return ctx, http.StatusUnauthorized
+	}
+	if accessLevel == "admin" && user.Role != "admin" {
+		return ctx, http.StatusForbidden
+	}
+	return engine.WithUserID(ctx, user.ID), http.StatusOK
+}
+
+// requestVars collects query string and form parameters. Parameters given more
+// than once are passed to the script as a Tcl list.
+func requestVars(r *http.Request) (map[string]string, error) {
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err = r.ParseMultipartForm(maxFormMemory)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string, len(r.Form))
+	for key, values := range r.Form {
+		if len(values) == 1 {
+			vars[key] = values[0]
+			continue
+		}
+		vars[key] = tcl.FormatList(values)
+	}
+	return vars, nil
+}
@@ -0,0 +1,216 @@
+package toolhandler_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/auth"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/params"
+	"github.com/ytjohn/toolmin/pkg/server/toolhandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestTools(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+
+	tokenService, err := auth.NewTokenService(testDB.DB)
+	if err != nil {
+		t.Fatalf("Failed to create token service: %v", err)
+	}
+
+	scripts := []appdb.CreateScriptParams{
+		{Name: "hello", AccessLevel: "public", Content: `puts "Hello, $name"`},
+		{Name: "page", AccessLevel: "user", Content: `response type html; return "<b>$a</b>"`},
+		{Name: "status", AccessLevel: "admin", Content: `response type json; puts {{"ok": true}}`},
+		{Name: "broken", AccessLevel: "public", Content: "set x 1\nnosuch"},
+		{Name: "spin", AccessLevel: "public", Content: "while 1 {}"},
+		{Name: "flood", AccessLevel: "public", Content: "while 1 { puts [string repeat x 100] }"},
+		{Name: "repeat", AccessLevel: "public", Content: "string repeat a $n"},
+		{Name: "typed", AccessLevel: "public", Content: `puts "$count $verbose $mode"`},
+	}
+	for _, s := range scripts {
+		script, err := queries.CreateScript(ctx, s)
+		if err != nil {
+			t.Fatalf("Failed to create script %s: %v", s.Name, err)
+		}
+		if s.Name != "typed" {
+			continue
+		}
+		no, fast := "no", "fast"
+		if err := params.Save(ctx, queries, script.ID, []params.Param{
+			{Name: "count", Type: params.TypeInt, Required: true},
+			{Name: "verbose", Type: params.TypeBool, Default: &no},
+			{Name: "mode", Type: params.TypeEnum, Enum: []string{"fast", "slow"}, Default: &fast},
+		}); err != nil {
+			t.Fatalf("Failed to save params: %v", err)
+		}
+	}
+
+	tokens := map[string]string{}
+	for _, role := range []string{"user", "admin"} {
+		user, err := queries.CreateUser(ctx, appdb.CreateUserParams{
+			Username: role,
+			Email:    role + "@example.com",
+			Password: "unused",
+			Role:     role,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+		token, err := tokenService.CreateAccessToken(user.ID)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		tokens[role] = "Bearer " + token
+	}
+
+	mux := http.NewServeMux()
+	eng := engine.New(testDB.DB, nil, nil)
+	eng.SetLimits(engine.Limits{Timeout: 50 * time.Millisecond, MaxOutput: 1000, MaxValue: 1 << 20})
+	toolhandler.New(testDB.DB, tokenService, eng, slog.Default()).Register(mux)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		form        url.Values
+		auth        string
+		wantStatus  int
+		wantType    string
+		wantBody    string
+		bodyContain bool
+	}{
+		{"public with query", "GET", "/tools/hello?name=world", nil, "", 200, "text/plain", "Hello, world\n", false},
+		{"form parameters", "POST", "/tools/hello", url.Values{"name": {"form"}}, "", 200, "text/plain", "Hello, form\n", false},
+		{"user without token", "GET", "/tools/page?a=1", nil, "", 401, "", "", false},
+		{"user with token", "GET", "/tools/page?a=1", nil, tokens["user"], 200, "text/html", "<b>1</b>", false},
+		{"admin as user", "GET", "/tools/status", nil, tokens["user"], 403, "", "", false},
+		{"admin as admin", "GET", "/tools/status", nil, tokens["admin"], 200, "application/json", `{"ok": true}` + "\n", false},
+		{"invalid token", "GET", "/tools/page", nil, "Bearer nope", 401, "", "", false},
+		{"missing tool", "GET", "/tools/nosuch", nil, "", 404, "", "", false},
+		{"script error", "GET", "/tools/broken", nil, "", 500, "", "line 2", true},
+		{"time limit", "GET", "/tools/spin", nil, "", 504, "", "time limit", true},
+		{"output limit", "GET", "/tools/flood", nil, "", 413, "", "output limit", true},
+		{"memory limit", "GET", "/tools/repeat?n=99999999999", nil, "", 413, "", "memory limit", true},
+		{"param defaults", "GET", "/tools/typed?count=3", nil, "", 200, "text/plain", "3 0 fast\n", false},
+		{"param conversion", "GET", "/tools/typed?count=+7&verbose=on&mode=slow", nil, "", 200, "text/plain", "7 1 slow\n", false},
+		{"param violations", "GET", "/tools/typed?verbose=maybe&mode=x", nil, "", 422, "", "count: is required\nverbose: must be true or false\nmode: must be one of fast, slow", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.form != nil {
+				body = strings.NewReader(tt.form.Encode())
+			}
+			req := httptest.NewRequest(tt.method, tt.path, body)
+			if tt.form != nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if tt.wantType != "" && !strings.HasPrefix(rr.Header().Get("Content-Type"), tt.wantType) {
+				t.Errorf("content type = %q, want %q", rr.Header().Get("Content-Type"), tt.wantType)
+			}
+			if tt.bodyContain {
+				if !strings.Contains(rr.Body.String(), tt.wantBody) {
+					t.Errorf("body = %q, want it to contain %q", rr.Body.String(), tt.wantBody)
+				}
+			} else if tt.wantBody != "" && rr.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rr.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestBusyTool(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+	tokenService, err := auth.NewTokenService(testDB.DB)
+	if err != nil {
+		t.Fatalf("Failed to create token service: %v", err)
+	}
+
+	// The script runs until the test sets the kv key "open"
+	script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{
+		Name:        "gated",
+		AccessLevel: "public",
+		Content:     `kv incr started; while {![kv get open 0]} {}; return done`,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if err := queries.UpsertScriptConcurrency(ctx, appdb.UpsertScriptConcurrencyParams{ScriptID: script.ID, MaxConcurrent: 1}); err != nil {
+		t.Fatalf("Failed to set concurrency: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	toolhandler.New(testDB.DB, tokenService, engine.New(testDB.DB, nil, nil), slog.Default()).Register(mux)
+	get := func(ctx context.Context) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/tools/gated", nil).WithContext(ctx))
+		return rr
+	}
+
+	first := make(chan *httptest.ResponseRecorder, 1)
+	go func() { first <- get(ctx) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := queries.GetKV(ctx, appdb.GetKVParams{ScriptID: script.ID, Key: "started", Now: appdb.NullTime(time.Now().UTC())})
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("first run didn't start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	rr := get(ctx)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429 (body %q)", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After header")
+	}
+
+	// A queued request that gives up waiting times out
+	if err := queries.UpsertScriptConcurrency(ctx, appdb.UpsertScriptConcurrencyParams{ScriptID: script.ID, MaxConcurrent: 1, Queue: true}); err != nil {
+		t.Fatalf("Failed to set concurrency: %v", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if rr := get(waitCtx); rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("queued status = %d, want 504 (body %q)", rr.Code, rr.Body.String())
+	}
+
+	if err := queries.SetKV(ctx, appdb.SetKVParams{ScriptID: script.ID, Key: "open", Value: "1", Updated: time.Now().UTC()}); err != nil {
+		t.Fatalf("Failed to open gate: %v", err)
+	}
+	if rr := <-first; rr.Code != http.StatusOK || rr.Body.String() != "done" {
+		t.Errorf("first run = %d %q, want 200 done", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,244 @@
+package toolhandler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/secrets"
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+// Webhook signature headers. The generic format signs the timestamp and
+// the body, "<timestamp>.<body>", so an old request can't be replayed.
+// GitHub's format signs the body alone. Either way, a signature that was
+// already accepted is rejected until it expires.
+const (
+	TimestampHeader       = "X-Toolmin-Timestamp"
+	SignatureHeader       = "X-Toolmin-Signature"
+	GitHubSignatureHeader = "X-Hub-Signature-256"
+)
+
+// MaxClockSkew is how far the timestamp of a signed webhook request may be
+// from the server's clock
+const MaxClockSkew = 5 * time.Minute
+
+// GitHubReplayWindow is how long a GitHub format signature is remembered.
+// It carries no timestamp, so the same delivery is accepted again after
+// this.
+const GitHubReplayWindow = 7 * 24 * time.Hour
+
+// maxHookBody is the largest request body a webhook accepts
+const maxHookBody = 1 << 20
+
+// HookHandler runs scripts for signed /hooks/{id} requests
+type HookHandler struct {
+	db      *sql.DB
+	secrets *secrets.Service
+	engine  *engine.Engine
+	log     *slog.Logger
+}
+
+// NewHookHandler creates a new webhook handler. Webhook secrets are kept in
+// the secrets table, so webhooks are disabled if secretsService is nil.
+func NewHookHandler(db *sql.DB, secretsService *secrets.Service, eng *engine.Engine, log *slog.Logger) *HookHandler {
+	return &HookHandler{
+		db:      db,
+		secrets: secretsService,
+		engine:  eng,
+		log:     log,
+	}
+}
+
+// Register mounts the webhook route on mux
+func (h *HookHandler) Register(mux *http.ServeMux) {
+	mux.Handle("POST /hooks/{id}", h)
+}
+
+// ServeHTTP verifies the request's signature and runs the webhook's script
+// with the payload as variables. The signature is the authorization, so
+// the script's access level doesn't apply.
+func (h *HookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	logger := h.log.With("path", r.URL.Path, "method", r.Method)
+	if h.secrets == nil {
+		http.Error(w, "webhooks are disabled: no secrets passphrase configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	queries := appdb.New(h.db)
+	hook, err := queries.GetWebhook(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("failed to load webhook", "error", err)
+		http.Error(w, "failed to load webhook", http.StatusInternalServerError)
+		return
+	}
+	logger = logger.With("script", hook.ScriptName)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHookBody))
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	secret, err := h.secrets.Get(r.Context(), hook.SecretKey)
+	if err != nil {
+		logger.Error("failed to read webhook secret", "error", err)
+		http.Error(w, "failed to load webhook", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	signature, expires, err := verifySignature(r.Header, body, secret, now)
+	if err != nil {
+		logger.Warn("rejected webhook", "error", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err := queries.DeleteExpiredWebhookDeliveries(r.Context(), now.UTC()); err != nil {
+		logger.Error("failed to prune webhook deliveries", "error", err)
+		http.Error(w, "failed to record webhook delivery", http.StatusInternalServerError)
+		return
+	}
+	err = queries.CreateWebhookDelivery(r.Context(), appdb.CreateWebhookDeliveryParams{
+		WebhookID: hook.ID,
+		Signature: signature,
+		Expires:   expires.UTC(),
+	})
+	if appdb.IsUniqueViolation(err) {
+		logger.Warn("rejected webhook", "error", "replayed signature")
+		http.Error(w, "request was already delivered", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logger.Error("failed to record webhook delivery", "error", err)
+		http.Error(w, "failed to record webhook delivery", http.StatusInternalServerError)
+		return
+	}
+
+	vars, err := payloadVars(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	script, err := queries.GetScript(r.Context(), hook.ScriptName)
+	if err != nil {
+		logger.Error("failed to load script", "error", err)
+		http.Error(w, "failed to load tool", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.engine.Run(engine.WithSource(r.Context(), engine.SourceWebhook), script, vars)
+	if writeResult(w, logger, result, err) {
+		logger.Info("webhook completed", "duration_ms", time.Since(start).Milliseconds())
+	}
+}
+
+// verifySignature checks the request's HMAC-SHA256 signature, in either
+// the generic or the GitHub format. It returns the signature and when it
+// can be forgotten: generic signatures are no longer accepted by then, and
+// GitHub ones have been kept for GitHubReplayWindow.
+func verifySignature(header http.Header, body []byte, secret string, now time.Time) (string, time.Time, error) {
+	if signature := header.Get(SignatureHeader); signature != "" {
+		timestamp := header.Get(TimestampHeader)
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return "", time.Time{}, errors.New("missing or invalid " + TimestampHeader + " header")
+		}
+		signed := time.Unix(unix, 0)
+		skew := now.Sub(signed)
+		if skew < -MaxClockSkew || skew > MaxClockSkew {
+			return "", time.Time{}, errors.New("request timestamp is outside the allowed window")
+		}
+		digest, err := checkSignature(signature, secret, []byte(timestamp+"."), body)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return digest, signed.Add(MaxClockSkew), nil
+	}
+	if signature := header.Get(GitHubSignatureHeader); signature != "" {
+		digest, err := checkSignature(signature, secret, body)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return digest, now.Add(GitHubReplayWindow), nil
+	}
+	return "", time.Time{}, errors.New("missing signature")
+}
+
+// checkSignature compares a "sha256=<hex>" signature with the HMAC of parts
+// and returns the HMAC in lower case hex, so that the same signature can't
+// pass as a new one with its case changed
+func checkSignature(signature, secret string, parts ...[]byte) (string, error) {
+	digest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return "", errors.New("signature must start with sha256=")
+	}
+	got, err := hex.DecodeString(digest)
+	if err != nil {
+		return "", errors.New("signature is not hex encoded")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	for _, p := range parts {
+		mac.Write(p)
+	}
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return "", errors.New("signature does not match")
+	}
+	return hex.EncodeToString(got), nil
+}
+
+// payloadVars turns a webhook body into script variables. The raw body is
+// always in $payload. Each field of a JSON object becomes a variable, with
+// nested objects as dicts and arrays as lists. Form fields become variables
+// as they do for tools.
+func payloadVars(contentType string, body []byte) (map[string]string, error) {
+	vars := map[string]string{}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var payload any
+		if err := decoder.Decode(&payload); err != nil {
+			return nil, errors.New("invalid JSON payload")
+		}
+		if fields, ok := payload.(map[string]any); ok {
+			for name, value := range fields {
+				vars[name] = engine.TclValue(value)
+			}
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, errors.New("invalid form data")
+		}
+		for name, values := range form {
+			if len(values) == 1 {
+				vars[name] = values[0]
+				continue
+			}
+			vars[name] = tcl.FormatList(values)
+		}
+	}
+	vars["payload"] = string(body)
+	return vars, nil
+}
@@ -0,0 +1,151 @@
+package toolhandler_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/secrets"
+	"github.com/ytjohn/toolmin/pkg/server/toolhandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestHooks(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+
+	svc, err := secrets.New(ctx, testDB.DB, "test passphrase")
+	if err != nil {
+		t.Fatalf("Failed to create secrets service: %v", err)
+	}
+	// Webhooks run regardless of the script's access level
+	script, err := queries.CreateScript(ctx, appdb.CreateScriptParams{
+		Name:        "deploy",
+		AccessLevel: "admin",
+		Content:     `puts "$ref [dict get $repository name] [llength $commits] [string length $payload]"`,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	const secret = "hook secret"
+	if _, err := svc.Create(ctx, "webhook.abc", secret); err != nil {
+		t.Fatalf("Failed to create secret: %v", err)
+	}
+	if _, err := queries.CreateWebhook(ctx, appdb.CreateWebhookParams{ID: "abc", ScriptID: script.ID, SecretKey: "webhook.abc"}); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	toolhandler.NewHookHandler(testDB.DB, svc, engine.New(testDB.DB, svc, nil), slog.Default()).Register(mux)
+
+	sign := func(parts ...string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		for _, p := range parts {
+			mac.Write([]byte(p))
+		}
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	payload := `{"ref": "main", "repository": {"name": "toolmin", "private": false}, "commits": [1, 2, 3]}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	jsonType := "application/json"
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+	}{
+		{"generic signature", "POST", "/hooks/abc", payload,
+			map[string]string{"Content-Type": jsonType, toolhandler.TimestampHeader: now, toolhandler.SignatureHeader: sign(now, ".", payload)},
+			200, "main toolmin 3 " + strconv.Itoa(len(payload)) + "\n"},
+		{"github signature", "POST", "/hooks/abc", payload,
+			map[string]string{"Content-Type": jsonType, toolhandler.GitHubSignatureHeader: sign(payload)},
+			200, "main toolmin 3 " + strconv.Itoa(len(payload)) + "\n"},
+		{"form payload", "POST", "/hooks/abc", "ref=dev&repository=name+x&commits=a",
+			map[string]string{"Content-Type": "application/x-www-form-urlencoded", toolhandler.GitHubSignatureHeader: sign("ref=dev&repository=name+x&commits=a")},
+			200, "dev x 1 35\n"},
+		{"replayed generic signature", "POST", "/hooks/abc", payload,
+			map[string]string{"Content-Type": jsonType, toolhandler.TimestampHeader: now, toolhandler.SignatureHeader: sign(now, ".", payload)}, 409, ""},
+		{"replayed github signature", "POST", "/hooks/abc", payload,
+			map[string]string{"Content-Type": jsonType, toolhandler.GitHubSignatureHeader: sign(payload)}, 409, ""},
+		{"replayed upper case signature", "POST", "/hooks/abc", payload,
+			map[string]string{"Content-Type": jsonType, toolhandler.GitHubSignatureHeader: "sha256=" + strings.ToUpper(strings.TrimPrefix(sign(payload), "sha256="))}, 409, ""},
+		{"missing signature", "POST", "/hooks/abc", payload,
+			map[string]string{"Content-Type": jsonType}, 401, ""},
+		{"wrong secret", "POST", "/hooks/abc", payload,
+			map[string]string{"Content-Type": jsonType, toolhandler.GitHubSignatureHeader: "sha256=" + strings.Repeat("0", 64)}, 401, ""},
+		{"tampered body", "POST", "/hooks/abc", payload + " ",
+			map[string]string{"Content-Type": jsonType, toolhandler.GitHubSignatureHeader: sign(payload)}, 401, ""},
+		{"stale timestamp", "POST", "/hooks/abc", payload,
+			map[string]string{"Content-Type": jsonType, toolhandler.TimestampHeader: stale, toolhandler.SignatureHeader: sign(stale, ".", payload)}, 401, ""},
+		{"timestamp not signed", "POST", "/hooks/abc", payload,
+			map[string]string{"Content-Type": jsonType, toolhandler.TimestampHeader: now, toolhandler.SignatureHeader: sign(stale, ".", payload)}, 401, ""},
+		{"missing timestamp", "POST", "/hooks/abc", payload,
+			map[string]string{"Content-Type": jsonType, toolhandler.SignatureHeader: sign(".", payload)}, 401, ""},
+		{"invalid json", "POST", "/hooks/abc", "{",
+			map[string]string{"Content-Type": jsonType, toolhandler.GitHubSignatureHeader: sign("{")}, 400, ""},
+		{"unknown webhook", "POST", "/hooks/nosuch", payload, nil, 404, ""},
+		{"get", "GET", "/hooks/abc", "", nil, 405, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if tt.wantBody != "" && rr.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rr.Body.String(), tt.wantBody)
+			}
+		})
+	}
+
+	t.Run("runs are recorded", func(t *testing.T) {
+		runs, err := queries.ListScriptRuns(ctx, appdb.ListScriptRunsParams{ScriptID: script.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("ListScriptRuns error: %v", err)
+		}
+		if len(runs) != 3 {
+			t.Errorf("got %d runs, want 3", len(runs))
+		}
+		for _, run := range runs {
+			if run.Source != engine.SourceWebhook {
+				t.Errorf("run source = %q, want %q", run.Source, engine.SourceWebhook)
+			}
+		}
+	})
+
+	t.Run("disabled without secrets", func(t *testing.T) {
+		mux := http.NewServeMux()
+		toolhandler.NewHookHandler(testDB.DB, nil, engine.New(testDB.DB, nil, nil), slog.Default()).Register(mux)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("POST", "/hooks/abc", strings.NewReader(payload)))
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want 503", rr.Code)
+		}
+	})
+}
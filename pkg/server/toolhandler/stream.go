@@ -0,0 +1,129 @@
+package toolhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ytjohn/toolmin/pkg/engine"
+)
+
+// Server-Sent Event names, for use with sse-swap and sse-close in the htmx
+// SSE extension
+const (
+	// EventOutput carries a line the script wrote with puts
+	EventOutput = "output"
+	// EventStderr carries a line the script wrote with puts stderr
+	EventStderr = "stderr"
+	// EventDone is the last event, carrying a StreamStatus
+	EventDone = "done"
+)
+
+// StreamStatus is the data of the done event
+type StreamStatus struct {
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"durationMs"`
+	Value      string  `json:"value,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// ServeStream runs a tool like ServeHTTP, but sends its output as
+// Server-Sent Events while it runs: an event per line, then a done event
+// with the run's status. Lines are HTML escaped unless the script chose
+// response type html, so they can be swapped into a page. The run is
+// cancelled if the client disconnects.
+//
+// The stream opens once the run has started, so a run that can't start,
+// such as one over the script's concurrency limit, gets a plain error
+// response with the same status as ServeHTTP.
+func (h *Handler) ServeStream(w http.ResponseWriter, r *http.Request) {
+	logger := h.log.With("path", r.URL.Path, "method", r.Method, "script", r.PathValue("name"))
+
+	ctx, script, vars, ok := h.prepare(w, r, logger)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	send := func(event, data string) {
+		if ctx.Err() != nil {
+			return
+		}
+		err := writeEvent(w, event, data)
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			logger.Debug("client went away", "error", err)
+			cancel()
+		}
+	}
+	opened := false
+	ctx = engine.WithStart(ctx, func() {
+		opened = true
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		// Stop proxies such as nginx from holding events back
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		send("", "") // opens the stream before the first line
+	})
+	ctx = engine.WithLines(ctx, func(line engine.Line) {
+		text := line.Text
+		if line.ContentType != engine.ContentTypeHTML {
+			text = html.EscapeString(text)
+		}
+		event := EventOutput
+		if line.Stderr {
+			event = EventStderr
+		}
+		send(event, text)
+	})
+
+	result, err := h.engine.Run(ctx, script, vars)
+	if !opened {
+		writeResult(w, logger, result, err)
+		return
+	}
+	status := StreamStatus{
+		Status:     engine.Status(err),
+		DurationMs: float64(result.Duration) / float64(time.Millisecond),
+		Value:      result.Value,
+	}
+	if err != nil {
+		status.Error = err.Error()
+		if errors.Is(ctx.Err(), context.Canceled) {
+			logger.Info("tool stream cancelled", "duration_ms", result.Duration.Milliseconds())
+			return
+		}
+		logger.Warn("script error", "error", err)
+	} else {
+		logger.Info("tool stream completed", "duration_ms", result.Duration.Milliseconds())
+	}
+	data, _ := json.Marshal(status)
+	send(EventDone, string(data))
+}
+
+// writeEvent writes a Server-Sent Event. An empty event is written as a
+// comment, which clients ignore.
+func writeEvent(w http.ResponseWriter, event, data string) error {
+	if event == "" {
+		_, err := fmt.Fprint(w, ": stream\n\n")
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", strings.TrimSuffix(line, "\r"))
+	}
+	b.WriteString("\n")
+	_, err := fmt.Fprint(w, b.String())
+	return err
+}
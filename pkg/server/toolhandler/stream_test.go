@@ -0,0 +1,248 @@
+package toolhandler_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/auth"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/server/toolhandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+type event struct {
+	name string
+	data string
+}
+
+// readEvent reads the next named event, skipping comments
+func readEvent(t *testing.T, r *bufio.Reader) (event, bool) {
+	t.Helper()
+	var e event
+	var data []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return e, false
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if e.name != "" {
+				e.data = strings.Join(data, "\n")
+				return e, true
+			}
+		case strings.HasPrefix(line, "event: "):
+			e.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = append(data, strings.TrimPrefix(line, "data: "))
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	queries := appdb.New(testDB.DB)
+	tokenService, err := auth.NewTokenService(testDB.DB)
+	if err != nil {
+		t.Fatalf("Failed to create token service: %v", err)
+	}
+
+	scripts := map[string]int64{}
+	for _, s := range []appdb.CreateScriptParams{
+		{Name: "count", AccessLevel: "public", Content: "foreach i {1 2} { puts \"line $i <b>\" }\nputs stderr oops\nputs -nonewline end\nreturn 3"},
+		{Name: "markup", AccessLevel: "public", Content: "response type html\nputs <b>$name</b>"},
+		{Name: "broken", AccessLevel: "public", Content: "puts before\nnosuch"},
+		{Name: "spin", AccessLevel: "public", Content: "puts started\nwhile 1 {}"},
+		{Name: "private", AccessLevel: "user", Content: "puts hi"},
+		{Name: "single", AccessLevel: "public", Content: "puts started\nwhile 1 {}"},
+	} {
+		script, err := queries.CreateScript(ctx, s)
+		if err != nil {
+			t.Fatalf("Failed to create script %s: %v", s.Name, err)
+		}
+		scripts[s.Name] = script.ID
+	}
+
+	if err := queries.UpsertScriptConcurrency(ctx, appdb.UpsertScriptConcurrencyParams{ScriptID: scripts["single"], MaxConcurrent: 1}); err != nil {
+		t.Fatalf("Failed to set concurrency: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	eng := engine.New(testDB.DB, nil, nil)
+	eng.SetLimits(engine.Limits{Timeout: 10 * time.Second})
+	toolhandler.New(testDB.DB, tokenService, eng, slog.Default()).Register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	stream := func(t *testing.T, path string) []event {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+			t.Errorf("content type = %q", got)
+		}
+		var events []event
+		r := bufio.NewReader(resp.Body)
+		for {
+			e, ok := readEvent(t, r)
+			if !ok {
+				return events
+			}
+			events = append(events, e)
+		}
+	}
+
+	done := func(t *testing.T, e event) toolhandler.StreamStatus {
+		t.Helper()
+		if e.name != toolhandler.EventDone {
+			t.Fatalf("last event = %q, want done", e.name)
+		}
+		var status toolhandler.StreamStatus
+		if err := json.Unmarshal([]byte(e.data), &status); err != nil {
+			t.Fatalf("decode done event %q: %v", e.data, err)
+		}
+		return status
+	}
+
+	t.Run("lines then done", func(t *testing.T) {
+		events := stream(t, "/tools/count/stream")
+		want := []event{
+			{"output", "line 1 &lt;b&gt;"},
+			{"output", "line 2 &lt;b&gt;"},
+			{"stderr", "oops"},
+			{"output", "end"},
+		}
+		if len(events) != len(want)+1 {
+			t.Fatalf("events = %+v", events)
+		}
+		for i, e := range want {
+			if events[i] != e {
+				t.Errorf("event %d = %+v, want %+v", i, events[i], e)
+			}
+		}
+		status := done(t, events[len(want)])
+		if status.Status != engine.StatusOK || status.Value != "3" || status.Error != "" {
+			t.Errorf("done = %+v", status)
+		}
+	})
+
+	t.Run("html is not escaped", func(t *testing.T) {
+		events := stream(t, "/tools/markup/stream?name=x")
+		if len(events) != 2 || events[0].data != "<b>x</b>" {
+			t.Errorf("events = %+v", events)
+		}
+	})
+
+	t.Run("script error", func(t *testing.T) {
+		events := stream(t, "/tools/broken/stream")
+		if len(events) != 2 || events[0].data != "before" {
+			t.Fatalf("events = %+v", events)
+		}
+		status := done(t, events[1])
+		if status.Status != engine.StatusError || !strings.Contains(status.Error, "line 2") {
+			t.Errorf("done = %+v", status)
+		}
+	})
+
+	t.Run("errors before streaming", func(t *testing.T) {
+		for path, want := range map[string]int{
+			"/tools/nosuch/stream":  http.StatusNotFound,
+			"/tools/private/stream": http.StatusUnauthorized,
+		} {
+			resp, err := http.Get(server.URL + path)
+			if err != nil {
+				t.Fatalf("GET %s: %v", path, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != want {
+				t.Errorf("%s: status = %d, want %d", path, resp.StatusCode, want)
+			}
+		}
+	})
+
+	t.Run("disconnect cancels the run", func(t *testing.T) {
+		reqCtx, cancel := context.WithCancel(ctx)
+		req, _ := http.NewRequestWithContext(reqCtx, "GET", server.URL+"/tools/spin/stream", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		e, ok := readEvent(t, bufio.NewReader(resp.Body))
+		if !ok || e.data != "started" {
+			t.Fatalf("first event = %+v", e)
+		}
+		cancel()
+		resp.Body.Close()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			runs, err := queries.ListScriptRuns(ctx, appdb.ListScriptRunsParams{ScriptID: scripts["spin"], Limit: 1})
+			if err != nil {
+				t.Fatalf("Failed to list runs: %v", err)
+			}
+			if len(runs) == 1 {
+				if runs[0].Status != engine.StatusCancelled {
+					t.Errorf("run status = %q, want cancelled", runs[0].Status)
+				}
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("run was not stopped after the client disconnected")
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+
+	t.Run("runs that can't start get a status", func(t *testing.T) {
+		reqCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(reqCtx, "GET", server.URL+"/tools/single/stream", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		defer resp.Body.Close()
+		if e, ok := readEvent(t, bufio.NewReader(resp.Body)); !ok || e.data != "started" {
+			t.Fatalf("first event = %+v", e)
+		}
+
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/tools/single/stream", nil))
+		if rr.Code != http.StatusTooManyRequests {
+			t.Errorf("status = %d, want 429 (body %q)", rr.Code, rr.Body.String())
+		}
+		if rr.Header().Get("Retry-After") == "" {
+			t.Error("missing Retry-After header")
+		}
+
+		// A queued request that gives up waiting times out
+		if err := queries.UpsertScriptConcurrency(ctx, appdb.UpsertScriptConcurrencyParams{ScriptID: scripts["single"], MaxConcurrent: 1, Queue: true}); err != nil {
+			t.Fatalf("Failed to set concurrency: %v", err)
+		}
+		waitCtx, cancelWait := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancelWait()
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/tools/single/stream", nil).WithContext(waitCtx))
+		if rr.Code != http.StatusGatewayTimeout {
+			t.Errorf("queued status = %d, want 504 (body %q)", rr.Code, rr.Body.String())
+		}
+	})
+}
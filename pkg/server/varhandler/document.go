@@ -0,0 +1,214 @@
+package varhandler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"gopkg.in/yaml.v3"
+)
+
+// Document formats accepted by the bulk endpoints
+const (
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+	FormatDotenv = "dotenv"
+)
+
+var contentTypes = map[string]string{
+	FormatJSON:   "application/json",
+	FormatYAML:   "application/yaml",
+	FormatDotenv: "text/plain",
+}
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// detectFormat picks a document format from an explicit format name or,
+// failing that, the request's Content-Type.
+func detectFormat(format, contentType string) (string, error) {
+	if format != "" {
+		return format, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		return FormatJSON, nil
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML, nil
+	case "text/plain", "application/x-env", "text/x-env":
+		return FormatDotenv, nil
+	}
+	return "", fmt.Errorf("cannot tell document format from content type %q, set ?format=json|yaml|dotenv", contentType)
+}
+
+// parseDocument reads a flat map of keys to scalar values
+func parseDocument(format string, data []byte) (map[string]string, error) {
+	var vars map[string]string
+	switch format {
+	case FormatJSON:
+		var doc map[string]any
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("invalid json document: %w", err)
+		}
+		vars = make(map[string]string, len(doc))
+		for k, v := range doc {
+			s, err := scalarString(v)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k, err)
+			}
+			vars[k] = s
+		}
+	case FormatYAML:
+		var err error
+		if vars, err = parseYAML(data); err != nil {
+			return nil, err
+		}
+	case FormatDotenv:
+		var err error
+		if vars, err = parseDotenv(data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	for k := range vars {
+		if !keyPattern.MatchString(k) {
+			return nil, fmt.Errorf("invalid key %q: keys may only contain letters, digits, '_', '.' and '-'", k)
+		}
+	}
+	return vars, nil
+}
+
+func scalarString(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("value must be a string, number or boolean, not %T", v)
+}
+
+// parseYAML reads a YAML mapping, keeping scalars exactly as written so
+// values like 010 or 2024-01-01 aren't reinterpreted
+func parseYAML(data []byte) (map[string]string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid yaml document: %w", err)
+	}
+	vars := map[string]string{}
+	if len(root.Content) == 0 {
+		return vars, nil
+	}
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid yaml document: expected a mapping of keys to values")
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		k, v := doc.Content[i], doc.Content[i+1]
+		if v.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("key %q: value must be a string, number or boolean", k.Value)
+		}
+		if v.Tag == "!!null" {
+			vars[k.Value] = ""
+			continue
+		}
+		vars[k.Value] = v.Value
+	}
+	return vars, nil
+}
+
+// parseDotenv reads KEY=value lines. Values may be single quoted (literal)
+// or double quoted (with \n, \r, \t, \" and \\ escapes). Unlike most dotenv
+// loaders, $VARIABLES are never expanded, so server environment variables
+// can't leak into stored vars.
+func parseDotenv(data []byte) (map[string]string, error) {
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=value", n)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = unescapeDotenv(value[1 : len(value)-1])
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		vars[key] = value
+	}
+	return vars, scanner.Err()
+}
+
+func unescapeDotenv(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func escapeDotenv(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s)
+}
+
+// formatDocument writes vars in the given format, ordered by key
+func formatDocument(format string, vars []appdb.Var) ([]byte, error) {
+	switch format {
+	case FormatJSON, FormatYAML:
+		doc := make(map[string]string, len(vars))
+		for _, v := range vars {
+			doc[v.Key] = v.Value
+		}
+		if format == FormatJSON {
+			return json.MarshalIndent(doc, "", "  ")
+		}
+		return yaml.Marshal(doc)
+	case FormatDotenv:
+		var b bytes.Buffer
+		for _, v := range vars {
+			fmt.Fprintf(&b, "%s=\"%s\"\n", v.Key, escapeDotenv(v.Value))
+		}
+		return b.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
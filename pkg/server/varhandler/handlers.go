@@ -0,0 +1,305 @@
+package varhandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+)
+
+// Var is a configuration variable available to scripts
+type Var struct {
+	Key     string    `json:"key" doc:"Variable name"`
+	Value   string    `json:"value"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+type VarPath struct {
+	Key string `path:"key" doc:"Variable name"`
+}
+
+type VarResponse struct {
+	Body Var `json:"body"`
+}
+
+type ListVarsResponse struct {
+	Body struct {
+		Vars []Var `json:"vars"`
+	} `json:"body"`
+}
+
+type CreateVarRequest struct {
+	Body struct {
+		Key   string `json:"key" required:"true" pattern:"^[A-Za-z0-9_.-]+$" maxLength:"100" doc:"Variable name"`
+		Value string `json:"value" doc:"Variable value"`
+	} `json:"body"`
+}
+
+type UpdateVarRequest struct {
+	Key  string `path:"key" doc:"Variable name"`
+	Body struct {
+		Value string `json:"value" doc:"Variable value"`
+	} `json:"body"`
+}
+
+type ImportVarsRequest struct {
+	Format      string `query:"format" enum:"json,yaml,dotenv" doc:"Document format. Taken from Content-Type if not set"`
+	ContentType string `header:"Content-Type"`
+	RawBody     []byte `contentType:"text/plain"`
+}
+
+type ExportVarsRequest struct {
+	Format string `query:"format" enum:"json,yaml,dotenv" default:"json" doc:"Document format"`
+}
+
+type ExportVarsResponse struct {
+	ContentType string `header:"Content-Type"`
+	Body        []byte
+}
+
+// RegisterVarHandlers registers the variable endpoints. Any authenticated
+// user can read variables, only admins can change them.
+func RegisterVarHandlers(api huma.API) {
+	huma.Register(api, huma.Operation{
+		OperationID: "listVars",
+		Method:      "GET",
+		Path:        "/api/v1/vars",
+		Summary:     "List variables",
+		Tags:        []string{"vars"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ListVars)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "exportVars",
+		Method:      "GET",
+		Path:        "/api/v1/vars/export",
+		Summary:     "Export all variables as a JSON, YAML or dotenv document",
+		Tags:        []string{"vars"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ExportVars)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "getVar",
+		Method:      "GET",
+		Path:        "/api/v1/vars/{key}",
+		Summary:     "Get a variable",
+		Tags:        []string{"vars"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, GetVar)
+
+	huma.Register(api, huma.Operation{
+		OperationID:   "createVar",
+		Method:        "POST",
+		Path:          "/api/v1/vars",
+		Summary:       "Create a variable (admin only)",
+		Tags:          []string{"vars"},
+		DefaultStatus: 201,
+		Security:      []map[string][]string{{"bearerAuth": {}}},
+	}, CreateVar)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "importVars",
+		Method:      "PUT",
+		Path:        "/api/v1/vars",
+		Summary:     "Create or update variables from a JSON, YAML or dotenv document (admin only)",
+		Description: "All variables in the document are written in one transaction. Variables not in the document are left alone.",
+		Tags:        []string{"vars"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, ImportVars)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "updateVar",
+		Method:      "PUT",
+		Path:        "/api/v1/vars/{key}",
+		Summary:     "Update a variable (admin only)",
+		Tags:        []string{"vars"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, UpdateVar)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "deleteVar",
+		Method:      "DELETE",
+		Path:        "/api/v1/vars/{key}",
+		Summary:     "Delete a variable (admin only)",
+		Tags:        []string{"vars"},
+		Security:    []map[string][]string{{"bearerAuth": {}}},
+	}, DeleteVar)
+}
+
+func ListVars(ctx context.Context, _ *struct{}) (*ListVarsResponse, error) {
+	if _, err := middleware.RequireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	vars, err := queries.ListVars(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vars: %w", err)
+	}
+
+	response := &ListVarsResponse{}
+	response.Body.Vars = make([]Var, 0, len(vars))
+	for _, v := range vars {
+		response.Body.Vars = append(response.Body.Vars, toVar(v))
+	}
+	return response, nil
+}
+
+func GetVar(ctx context.Context, input *VarPath) (*VarResponse, error) {
+	if _, err := middleware.RequireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	v, err := queries.GetVar(ctx, input.Key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("var not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get var: %w", err)
+	}
+	return &VarResponse{Body: toVar(v)}, nil
+}
+
+func CreateVar(ctx context.Context, input *CreateVarRequest) (*VarResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	v, err := queries.CreateVar(ctx, appdb.CreateVarParams{
+		Key:   input.Body.Key,
+		Value: input.Body.Value,
+	})
+	if appdb.IsUniqueViolation(err) {
+		return nil, huma.Error409Conflict(fmt.Sprintf("var %q already exists", input.Body.Key))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create var: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("var created", "key", v.Key)
+	return &VarResponse{Body: toVar(v)}, nil
+}
+
+func UpdateVar(ctx context.Context, input *UpdateVarRequest) (*VarResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	v, err := queries.UpdateVar(ctx, appdb.UpdateVarParams{
+		Key:   input.Key,
+		Value: input.Body.Value,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("var not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update var: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("var updated", "key", v.Key)
+	return &VarResponse{Body: toVar(v)}, nil
+}
+
+func DeleteVar(ctx context.Context, input *VarPath) (*struct{}, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	if _, err := queries.GetVar(ctx, input.Key); errors.Is(err, sql.ErrNoRows) {
+		return nil, huma.Error404NotFound("var not found")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get var: %w", err)
+	}
+	if err := queries.DeleteVar(ctx, input.Key); err != nil {
+		return nil, fmt.Errorf("failed to delete var: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("var deleted", "key", input.Key)
+	return &struct{}{}, nil
+}
+
+func ImportVars(ctx context.Context, input *ImportVarsRequest) (*ListVarsResponse, error) {
+	if _, err := middleware.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	format, err := detectFormat(input.Format, input.ContentType)
+	if err != nil {
+		return nil, huma.Error415UnsupportedMediaType(err.Error())
+	}
+	doc, err := parseDocument(format, input.RawBody)
+	if err != nil {
+		return nil, huma.Error422UnprocessableEntity(err.Error())
+	}
+
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	db := ctx.Value(appdb.DbContextKey).(*sql.DB)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := appdb.New(db).WithTx(tx)
+	response := &ListVarsResponse{}
+	response.Body.Vars = make([]Var, 0, len(keys))
+	for _, k := range keys {
+		v, err := queries.UpsertVar(ctx, appdb.UpsertVarParams{Key: k, Value: doc[k]})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write var %q: %w", k, err)
+		}
+		response.Body.Vars = append(response.Body.Vars, toVar(v))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit vars: %w", err)
+	}
+
+	middleware.GetLogger(ctx).Info("vars imported", "format", format, "count", len(keys))
+	return response, nil
+}
+
+func ExportVars(ctx context.Context, input *ExportVarsRequest) (*ExportVarsResponse, error) {
+	if _, err := middleware.RequireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	queries := appdb.New(ctx.Value(appdb.DbContextKey).(*sql.DB))
+	vars, err := queries.ListVars(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vars: %w", err)
+	}
+
+	body, err := formatDocument(input.Format, vars)
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+	return &ExportVarsResponse{
+		ContentType: contentTypes[input.Format],
+		Body:        body,
+	}, nil
+}
+
+func toVar(v appdb.Var) Var {
+	return Var{
+		Key:     v.Key,
+		Value:   v.Value,
+		Created: v.Created,
+		Updated: v.Updated,
+	}
+}
@@ -0,0 +1,138 @@
+package varhandler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ytjohn/toolmin/pkg/server/varhandler"
+	"github.com/ytjohn/toolmin/pkg/testutil"
+)
+
+func TestVarHandlers(t *testing.T) {
+	testDB := testutil.NewTestDB(t)
+	defer testDB.Close()
+
+	api, tokenService := testutil.NewTestAPI(t, testDB.DB)
+	varhandler.RegisterVarHandlers(api)
+
+	admin := testutil.CreateTestUser(t, testDB.DB, tokenService, "admin")
+	user := testutil.CreateTestUser(t, testDB.DB, tokenService, "user")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   string
+		body   any
+		want   int
+	}{
+		{"create", "POST", "/api/v1/vars", admin, map[string]any{"key": "region", "value": "us-east"}, http.StatusCreated},
+		{"create as user", "POST", "/api/v1/vars", user, map[string]any{"key": "x", "value": "y"}, http.StatusForbidden},
+		{"create duplicate", "POST", "/api/v1/vars", admin, map[string]any{"key": "region", "value": "y"}, http.StatusConflict},
+		{"get as user", "GET", "/api/v1/vars/region", user, nil, http.StatusOK},
+		{"get missing", "GET", "/api/v1/vars/nosuch", user, nil, http.StatusNotFound},
+		{"list anonymous", "GET", "/api/v1/vars", "", nil, http.StatusUnauthorized},
+		{"update", "PUT", "/api/v1/vars/region", admin, map[string]any{"value": "eu-west"}, http.StatusOK},
+		{"update missing", "PUT", "/api/v1/vars/nosuch", admin, map[string]any{"value": "x"}, http.StatusNotFound},
+		{"delete as user", "DELETE", "/api/v1/vars/region", user, nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args []any
+			if tt.auth != "" {
+				args = append(args, tt.auth)
+			}
+			if tt.body != nil {
+				args = append(args, tt.body)
+			}
+			resp := api.Do(tt.method, tt.path, args...)
+			if resp.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", resp.Code, tt.want, resp.Body.String())
+			}
+		})
+	}
+
+	listVars := func(t *testing.T) map[string]string {
+		t.Helper()
+		resp := api.Get("/api/v1/vars", user)
+		var body struct {
+			Vars []varhandler.Var `json:"vars"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode list: %v", err)
+		}
+		vars := map[string]string{}
+		for _, v := range body.Vars {
+			vars[v.Key] = v.Value
+		}
+		return vars
+	}
+
+	imports := []struct {
+		name   string
+		header string
+		query  string
+		doc    string
+		want   map[string]string
+	}{
+		{"json", "Content-Type: application/json", "", `{"region": "ap-south", "retries": 3, "debug": true}`,
+			map[string]string{"region": "ap-south", "retries": "3", "debug": "true"}},
+		{"yaml", "Content-Type: application/yaml", "", "zone: \"0010\"\nport: 010\nempty:\n",
+			map[string]string{"zone": "0010", "port": "010", "empty": ""}},
+		{"dotenv", "Content-Type: text/plain", "?format=dotenv", "# comment\nexport HOME_DIR=$HOME\nGREETING=\"hello\\nworld\"\nRAW='a \"b\"'\n",
+			map[string]string{"HOME_DIR": "$HOME", "GREETING": "hello\nworld", "RAW": `a "b"`}},
+	}
+
+	for _, tt := range imports {
+		t.Run("import "+tt.name, func(t *testing.T) {
+			resp := api.Put("/api/v1/vars"+tt.query, admin, tt.header, strings.NewReader(tt.doc))
+			if resp.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", resp.Code, resp.Body.String())
+			}
+			vars := listVars(t)
+			for k, want := range tt.want {
+				if vars[k] != want {
+					t.Errorf("%s = %q, want %q", k, vars[k], want)
+				}
+			}
+		})
+	}
+
+	t.Run("import is all or nothing", func(t *testing.T) {
+		resp := api.Put("/api/v1/vars", admin, "Content-Type: application/json",
+			strings.NewReader(`{"fresh": "1", "bad key": "2"}`))
+		if resp.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("status = %d, want 422", resp.Code)
+		}
+		if _, ok := listVars(t)["fresh"]; ok {
+			t.Errorf("var from rejected document was stored")
+		}
+	})
+
+	t.Run("import as user", func(t *testing.T) {
+		resp := api.Put("/api/v1/vars", user, "Content-Type: application/json", strings.NewReader(`{"a": "1"}`))
+		if resp.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want 403", resp.Code)
+		}
+	})
+
+	t.Run("export dotenv round trips", func(t *testing.T) {
+		resp := api.Get("/api/v1/vars/export?format=dotenv", user)
+		if resp.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", resp.Code, resp.Body.String())
+		}
+		before := listVars(t)
+		if resp := api.Put("/api/v1/vars?format=dotenv", admin, strings.NewReader(resp.Body.String())); resp.Code != http.StatusOK {
+			t.Fatalf("reimport status = %d: %s", resp.Code, resp.Body.String())
+		}
+		after := listVars(t)
+		for k, v := range before {
+			if after[k] != v {
+				t.Errorf("%s changed from %q to %q", k, v, after[k])
+			}
+		}
+	})
+}
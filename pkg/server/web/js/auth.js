@@ -73,4 +73,11 @@ window.AuthService = {
             this.clearTokens();
         }
     }
-}; 
\ No newline at end of file
+}; 
+// Send the access token with htmx tool requests, so tools on pages can use
+// the user's access level
+document.addEventListener('htmx:configRequest', (event) => {
+    if (event.detail.path.startsWith('/tools/') && AuthService.isAuthenticated()) {
+        event.detail.headers['Authorization'] = `Bearer ${AuthService.getAccessToken()}`;
+    }
+});
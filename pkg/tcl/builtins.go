@@ -0,0 +1,1020 @@
+package tcl
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WrongArgs returns the standard error for a command called with the wrong
+// number of arguments.
+func WrongArgs(name, usage string) error {
+	if usage == "" {
+		return fmt.Errorf("wrong # args: should be \"%s\"", name)
+	}
+	return fmt.Errorf("wrong # args: should be \"%s %s\"", name, usage)
+}
+
+func registerCore(i *Interp) {
+	for name, fn := range map[string]CommandFunc{
+		"set":      cmdSet,
+		"unset":    cmdUnset,
+		"incr":     cmdIncr,
+		"append":   cmdAppend,
+		"puts":     cmdPuts,
+		"expr":     cmdExpr,
+		"if":       cmdIf,
+		"while":    cmdWhile,
+		"for":      cmdFor,
+		"foreach":  cmdForeach,
+		"lmap":     cmdForeach,
+		"break":    cmdBreak,
+		"continue": cmdContinue,
+		"return":   cmdReturn,
+		"error":    cmdError,
+		"throw":    cmdThrow,
+		"catch":    cmdCatch,
+		"try":      cmdTry,
+		"proc":     cmdProc,
+		"rename":   cmdRename,
+		"eval":     cmdEval,
+		"uplevel":  cmdUplevel,
+		"upvar":    cmdUpvar,
+		"global":   cmdGlobal,
+		"subst":    cmdSubst,
+		"switch":   cmdSwitch,
+		"info":     cmdInfo,
+		"apply":    cmdApply,
+	} {
+		i.Register(name, fn)
+	}
+}
+
+func cmdSet(i *Interp, args []string) (string, error) {
+	switch len(args) {
+	case 2:
+		return i.GetVar(args[1])
+	case 3:
+		if err := i.SetVar(args[1], args[2]); err != nil {
+			return "", err
+		}
+		return args[2], nil
+	}
+	return "", WrongArgs(args[0], "varName ?newValue?")
+}
+
+func cmdUnset(i *Interp, args []string) (string, error) {
+	names := args[1:]
+	complain := true
+	for len(names) > 0 {
+		if names[0] == "-nocomplain" {
+			complain = false
+			names = names[1:]
+			continue
+		}
+		if names[0] == "--" {
+			names = names[1:]
+		}
+		break
+	}
+	for _, name := range names {
+		if err := i.UnsetVar(name); err != nil && complain {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+func cmdIncr(i *Interp, args []string) (string, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return "", WrongArgs(args[0], "varName ?increment?")
+	}
+	by := int64(1)
+	if len(args) == 3 {
+		var err error
+		if by, err = toInt(args[2]); err != nil {
+			return "", err
+		}
+	}
+	cur := int64(0)
+	if i.VarExists(args[1]) {
+		s, err := i.GetVar(args[1])
+		if err != nil {
+			return "", err
+		}
+		if cur, err = toInt(s); err != nil {
+			return "", err
+		}
+	}
+	res := strconv.FormatInt(cur+by, 10)
+	return res, i.SetVar(args[1], res)
+}
+
+func cmdAppend(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "varName ?value ...?")
+	}
+	cur := ""
+	if i.VarExists(args[1]) {
+		var err error
+		if cur, err = i.GetVar(args[1]); err != nil {
+			return "", err
+		}
+	}
+	size := int64(len(cur))
+	for _, a := range args[2:] {
+		size += int64(len(a))
+	}
+	if err := i.checkSize(size); err != nil {
+		return "", err
+	}
+	res := cur + strings.Join(args[2:], "")
+	return res, i.SetVar(args[1], res)
+}
+
+func cmdPuts(i *Interp, args []string) (string, error) {
+	a := args[1:]
+	newline := true
+	if len(a) > 0 && a[0] == "-nonewline" {
+		newline = false
+		a = a[1:]
+	}
+	var w io.Writer
+	switch len(a) {
+	case 1:
+		w = i.Stdout
+	case 2:
+		switch a[0] {
+		case "stdout":
+			w = i.Stdout
+		case "stderr":
+			w = i.Stderr
+		default:
+			return "", fmt.Errorf("can not find channel named \"%s\"", a[0])
+		}
+		a = a[1:]
+	default:
+		return "", WrongArgs(args[0], "?-nonewline? ?channelId? string")
+	}
+	s := a[0]
+	if newline {
+		s += "\n"
+	}
+	if _, err := io.WriteString(w, s); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+func cmdExpr(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "arg ?arg ...?")
+	}
+	return i.Expr(strings.Join(args[1:], " "))
+}
+
+func cmdIf(i *Interp, args []string) (string, error) {
+	a := args[1:]
+	for {
+		if len(a) == 0 {
+			return "", fmt.Errorf("wrong # args: no expression after \"%s\" argument", args[0])
+		}
+		cond, err := i.ExprBool(a[0])
+		if err != nil {
+			return "", err
+		}
+		a = a[1:]
+		if len(a) > 0 && a[0] == "then" {
+			a = a[1:]
+		}
+		if len(a) == 0 {
+			return "", fmt.Errorf("wrong # args: no script following expression")
+		}
+		if cond {
+			return i.EvalBody(a[0])
+		}
+		a = a[1:]
+		if len(a) == 0 {
+			return "", nil
+		}
+		switch a[0] {
+		case "elseif":
+			a = a[1:]
+			continue
+		case "else":
+			a = a[1:]
+			if len(a) != 1 {
+				return "", fmt.Errorf("wrong # args: no script following \"else\" argument")
+			}
+			return i.EvalBody(a[0])
+		}
+		if len(a) == 1 {
+			return i.EvalBody(a[0])
+		}
+		return "", fmt.Errorf("wrong # args: extra words after \"else\" clause in \"if\" command")
+	}
+}
+
+// loopBody evaluates a loop body and reports whether the loop should
+// stop.
+func (i *Interp) loopBody(body string) (stop bool, err error) {
+	// Checked here too, as an empty body never evaluates a command
+	if err := i.step(); err != nil {
+		return true, err
+	}
+	_, err = i.EvalBody(body)
+	switch codeOf(err) {
+	case codeOK, codeContinue:
+		return false, nil
+	case codeBreak:
+		return true, nil
+	}
+	return true, err
+}
+
+func cmdWhile(i *Interp, args []string) (string, error) {
+	if len(args) != 3 {
+		return "", WrongArgs(args[0], "test command")
+	}
+	for {
+		ok, err := i.ExprBool(args[1])
+		if err != nil || !ok {
+			return "", err
+		}
+		if stop, err := i.loopBody(args[2]); stop {
+			return "", err
+		}
+	}
+}
+
+func cmdFor(i *Interp, args []string) (string, error) {
+	if len(args) != 5 {
+		return "", WrongArgs(args[0], "start test next command")
+	}
+	if _, err := i.EvalBody(args[1]); err != nil {
+		return "", err
+	}
+	for {
+		ok, err := i.ExprBool(args[2])
+		if err != nil || !ok {
+			return "", err
+		}
+		if stop, err := i.loopBody(args[4]); stop {
+			return "", err
+		}
+		if _, err := i.EvalBody(args[3]); err != nil {
+			return "", err
+		}
+	}
+}
+
+// cmdForeach implements both foreach and lmap.
+func cmdForeach(i *Interp, args []string) (string, error) {
+	if len(args) < 4 || len(args)%2 != 0 {
+		return "", WrongArgs(args[0], "varList list ?varList list ...? command")
+	}
+	body := args[len(args)-1]
+	type group struct {
+		vars  []string
+		elems []string
+	}
+	var groups []group
+	iterations := 0
+	for n := 1; n < len(args)-1; n += 2 {
+		vars, err := SplitList(args[n])
+		if err != nil {
+			return "", err
+		}
+		if len(vars) == 0 {
+			return "", fmt.Errorf("%s varlist is empty", args[0])
+		}
+		elems, err := SplitList(args[n+1])
+		if err != nil {
+			return "", err
+		}
+		groups = append(groups, group{vars, elems})
+		if it := (len(elems) + len(vars) - 1) / len(vars); it > iterations {
+			iterations = it
+		}
+	}
+	var collected []string
+	var size int64
+	for it := 0; it < iterations; it++ {
+		for _, g := range groups {
+			for k, v := range g.vars {
+				val := ""
+				if idx := it*len(g.vars) + k; idx < len(g.elems) {
+					val = g.elems[idx]
+				}
+				if err := i.SetVar(v, val); err != nil {
+					return "", err
+				}
+			}
+		}
+		res, err := i.EvalBody(body)
+		switch codeOf(err) {
+		case codeOK:
+			if args[0] == "lmap" {
+				size += int64(len(res)) + 1
+				if err := i.checkSize(size); err != nil {
+					return "", err
+				}
+				collected = append(collected, res)
+			}
+		case codeContinue:
+		case codeBreak:
+			it = iterations
+		default:
+			return "", err
+		}
+	}
+	if args[0] == "lmap" {
+		return FormatList(collected), nil
+	}
+	return "", nil
+}
+
+func cmdBreak(i *Interp, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", WrongArgs(args[0], "")
+	}
+	return "", errBreak
+}
+
+func cmdContinue(i *Interp, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", WrongArgs(args[0], "")
+	}
+	return "", errContinue
+}
+
+var codeNames = map[string]int{
+	"ok": codeOK, "error": codeError, "return": codeReturn, "break": codeBreak, "continue": codeContinue,
+}
+
+func parseCode(s string) (int, error) {
+	if c, ok := codeNames[s]; ok {
+		return c, nil
+	}
+	if c, ok := ParseInt(s); ok {
+		return int(c), nil
+	}
+	return 0, fmt.Errorf("bad completion code \"%s\": must be ok, error, return, break, continue, or an integer", s)
+}
+
+func cmdReturn(i *Interp, args []string) (string, error) {
+	a := args[1:]
+	f := &flow{code: codeReturn, retCode: codeOK}
+	for len(a) >= 2 && strings.HasPrefix(a[0], "-") {
+		switch a[0] {
+		case "-code":
+			c, err := parseCode(a[1])
+			if err != nil {
+				return "", err
+			}
+			f.retCode = c
+		case "-level", "-errorinfo", "-errorcode", "-options":
+			// Accepted for compatibility; only -code affects the result.
+		default:
+			return "", fmt.Errorf("bad option \"%s\"", a[0])
+		}
+		a = a[2:]
+	}
+	switch len(a) {
+	case 0:
+	case 1:
+		f.value = a[0]
+	default:
+		return "", WrongArgs(args[0], "?-code code? ?result?")
+	}
+	return "", f
+}
+
+func cmdError(i *Interp, args []string) (string, error) {
+	if len(args) < 2 || len(args) > 4 {
+		return "", WrongArgs(args[0], "message ?errorInfo? ?errorCode?")
+	}
+	e := &Error{Message: args[1]}
+	if len(args) > 2 && args[2] != "" {
+		e.Info = args[2]
+	}
+	return "", e
+}
+
+func cmdThrow(i *Interp, args []string) (string, error) {
+	if len(args) != 3 {
+		return "", WrongArgs(args[0], "type message")
+	}
+	return "", &Error{Message: args[2]}
+}
+
+// catchable reports whether a script may intercept err. Cancellation and
+// running out of commands or memory must always reach the host.
+func (i *Interp) catchable(err error) bool {
+	return i.ctx.Err() == nil && !i.exhausted() && !i.overflowed
+}
+
+// options builds the return options dictionary for a completion.
+func options(code int, err error) string {
+	d := NewDict()
+	d.Set("-code", strconv.Itoa(code))
+	d.Set("-level", "0")
+	if e, ok := err.(*Error); ok {
+		info := e.Info
+		if info == "" {
+			info = e.Message
+		}
+		d.Set("-errorinfo", info)
+		d.Set("-errorline", strconv.Itoa(e.Line))
+	}
+	return d.String()
+}
+
+func cmdCatch(i *Interp, args []string) (string, error) {
+	if len(args) < 2 || len(args) > 4 {
+		return "", WrongArgs(args[0], "script ?resultVarName? ?optionVarName?")
+	}
+	res, err := i.EvalBody(args[1])
+	if err != nil && !i.catchable(err) {
+		return "", err
+	}
+	code := codeOf(err)
+	switch e := err.(type) {
+	case *flow:
+		res = e.value
+		if e.code == codeReturn {
+			code = codeReturn
+		}
+	case nil:
+	default:
+		res = toError(err).Message
+	}
+	if len(args) > 2 {
+		if err := i.SetVar(args[2], res); err != nil {
+			return "", err
+		}
+	}
+	if len(args) > 3 {
+		if err := i.SetVar(args[3], options(code, err)); err != nil {
+			return "", err
+		}
+	}
+	return strconv.Itoa(code), nil
+}
+
+// cmdTry implements try body ?on code varList script ...? ?trap pattern
+// varList script ...? ?finally script?
+func cmdTry(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "body ?handler ...? ?finally script?")
+	}
+	res, err := i.EvalBody(args[1])
+	if err != nil && !i.catchable(err) {
+		return "", err
+	}
+	code := codeOf(err)
+	msg := res
+	if err != nil {
+		if f, ok := err.(*flow); ok {
+			msg = f.value
+		} else {
+			msg = toError(err).Message
+		}
+	}
+
+	finally := ""
+	handled := false
+	a := args[2:]
+	for len(a) > 0 {
+		switch a[0] {
+		case "finally":
+			if len(a) != 2 {
+				return "", WrongArgs(args[0], "body ?handler ...? ?finally script?")
+			}
+			finally = a[1]
+			a = nil
+			continue
+		case "on", "trap":
+			if len(a) < 4 {
+				return "", fmt.Errorf("wrong # args to %s clause: must be \"... %s %s varList script\"", a[0], a[0], map[string]string{"on": "code", "trap": "pattern"}[a[0]])
+			}
+			matches := false
+			if a[0] == "on" {
+				c, perr := parseCode(a[1])
+				if perr != nil {
+					return "", perr
+				}
+				matches = c == code
+			} else {
+				matches = code == codeError
+			}
+			if matches && !handled {
+				handled = true
+				vars, verr := SplitList(a[2])
+				if verr != nil {
+					return "", verr
+				}
+				if len(vars) > 0 {
+					if err := i.SetVar(vars[0], msg); err != nil {
+						return "", err
+					}
+				}
+				if len(vars) > 1 {
+					if err := i.SetVar(vars[1], options(code, err)); err != nil {
+						return "", err
+					}
+				}
+				res, err = i.EvalBody(a[3])
+			}
+			a = a[4:]
+		default:
+			return "", fmt.Errorf("bad handler \"%s\": must be finally, on, or trap", a[0])
+		}
+	}
+	if finally != "" {
+		if _, ferr := i.EvalBody(finally); ferr != nil {
+			return "", ferr
+		}
+	}
+	return res, err
+}
+
+// procedure is a command defined with proc.
+type procedure struct {
+	name   string
+	params []param
+	body   string
+	script *Script
+	base   pos
+}
+
+type param struct {
+	name   string
+	def    string
+	hasDef bool
+}
+
+func parseParams(list string) ([]param, error) {
+	specs, err := SplitList(list)
+	if err != nil {
+		return nil, err
+	}
+	params := make([]param, 0, len(specs))
+	for _, spec := range specs {
+		parts, err := SplitList(spec)
+		if err != nil {
+			return nil, err
+		}
+		switch len(parts) {
+		case 1:
+			params = append(params, param{name: parts[0]})
+		case 2:
+			params = append(params, param{name: parts[0], def: parts[1], hasDef: true})
+		default:
+			return nil, fmt.Errorf("too many fields in argument specifier \"%s\"", spec)
+		}
+	}
+	return params, nil
+}
+
+func (p *procedure) usage() string {
+	var b strings.Builder
+	b.WriteString(p.name)
+	for n, prm := range p.params {
+		switch {
+		case prm.name == "args" && n == len(p.params)-1:
+			b.WriteString(" ?arg ...?")
+		case prm.hasDef:
+			b.WriteString(" ?" + prm.name + "?")
+		default:
+			b.WriteString(" " + prm.name)
+		}
+	}
+	return b.String()
+}
+
+func cmdProc(i *Interp, args []string) (string, error) {
+	if len(args) != 4 {
+		return "", WrongArgs(args[0], "name args body")
+	}
+	params, err := parseParams(args[2])
+	if err != nil {
+		return "", err
+	}
+	base := i.bodyBase(args[3])
+	s, err := Parse(args[3])
+	if err != nil {
+		return "", rebase(err, base)
+	}
+	p := &procedure{
+		name:   strings.TrimLeft(args[1], ":"),
+		params: params,
+		body:   args[3],
+		script: s,
+		base:   base,
+	}
+	i.commands[p.name] = &cmdEntry{
+		fn: func(i *Interp, args []string) (string, error) {
+			return i.callProc(p, args)
+		},
+		proc: p,
+	}
+	return "", nil
+}
+
+func (i *Interp) callProc(p *procedure, args []string) (string, error) {
+	f := newFrame()
+	f.proc, f.args = p, args
+	given := args[1:]
+	for n, prm := range p.params {
+		switch {
+		case prm.name == "args" && n == len(p.params)-1:
+			f.vars["args"] = &variable{value: FormatList(given), defined: true}
+			given = nil
+		case len(given) > 0:
+			f.vars[prm.name] = &variable{value: given[0], defined: true}
+			given = given[1:]
+		case prm.hasDef:
+			f.vars[prm.name] = &variable{value: prm.def, defined: true}
+		default:
+			return "", fmt.Errorf("wrong # args: should be \"%s\"", p.usage())
+		}
+	}
+	if len(given) > 0 {
+		return "", fmt.Errorf("wrong # args: should be \"%s\"", p.usage())
+	}
+
+	i.frames = append(i.frames, f)
+	res, err := i.evalScript(p.script, p.base)
+	i.frames = i.frames[:len(i.frames)-1]
+
+	if fl, ok := err.(*flow); ok {
+		if fl.code == codeReturn {
+			return i.returnResult(fl)
+		}
+		return "", &Error{Message: fl.Error()}
+	}
+	return res, err
+}
+
+func cmdRename(i *Interp, args []string) (string, error) {
+	if len(args) != 3 {
+		return "", WrongArgs(args[0], "oldName newName")
+	}
+	c, ok := i.lookupCommand(args[1])
+	if !ok {
+		return "", fmt.Errorf("can't rename \"%s\": command doesn't exist", args[1])
+	}
+	delete(i.commands, strings.TrimLeft(args[1], ":"))
+	delete(i.commands, args[1])
+	if args[2] != "" {
+		if _, exists := i.lookupCommand(args[2]); exists {
+			return "", fmt.Errorf("can't rename to \"%s\": command already exists", args[2])
+		}
+		i.commands[strings.TrimLeft(args[2], ":")] = c
+	}
+	return "", nil
+}
+
+func cmdEval(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "arg ?arg ...?")
+	}
+	if len(args) == 2 {
+		return i.EvalBody(args[1])
+	}
+	if err := i.checkList(args[1:]); err != nil {
+		return "", err
+	}
+	return i.EvalBody(strings.Join(args[1:], " "))
+}
+
+// parseLevel interprets an uplevel/upvar level argument and returns the
+// index of the target frame.
+func (i *Interp) parseLevel(s string) (int, bool) {
+	cur := len(i.frames) - 1
+	if strings.HasPrefix(s, "#") {
+		n, err := strconv.Atoi(s[1:])
+		if err != nil || n < 0 || n > cur {
+			return 0, false
+		}
+		return n, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > cur {
+		return 0, false
+	}
+	return cur - n, true
+}
+
+func cmdUplevel(i *Interp, args []string) (string, error) {
+	a := args[1:]
+	target, ok := i.parseLevel("1")
+	if len(a) > 1 {
+		if t, isLevel := i.parseLevel(a[0]); isLevel {
+			target, ok = t, true
+			a = a[1:]
+		} else if strings.HasPrefix(a[0], "#") || (a[0] != "" && a[0][0] >= '0' && a[0][0] <= '9') {
+			return "", fmt.Errorf("bad level \"%s\"", a[0])
+		}
+	}
+	if len(a) == 0 {
+		return "", WrongArgs(args[0], "?level? command ?arg ...?")
+	}
+	if !ok {
+		return "", fmt.Errorf("bad level \"1\"")
+	}
+	saved := i.frames
+	i.frames = append([]*frame(nil), saved[:target+1]...)
+	defer func() { i.frames = saved }()
+	return i.EvalBody(strings.Join(a, " "))
+}
+
+func cmdUpvar(i *Interp, args []string) (string, error) {
+	a := args[1:]
+	target, ok := i.parseLevel("1")
+	if len(a)%2 == 1 {
+		target, ok = i.parseLevel(a[0])
+		if !ok {
+			return "", fmt.Errorf("bad level \"%s\"", a[0])
+		}
+		a = a[1:]
+	}
+	if len(a) == 0 {
+		return "", WrongArgs(args[0], "?level? otherVar localVar ?otherVar localVar ...?")
+	}
+	if !ok {
+		return "", fmt.Errorf("bad level \"1\"")
+	}
+	for n := 0; n < len(a); n += 2 {
+		if err := i.link(i.frames[target], a[n], a[n+1]); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+func cmdGlobal(i *Interp, args []string) (string, error) {
+	if len(i.frames) == 1 {
+		return "", nil
+	}
+	for _, name := range args[1:] {
+		local := name
+		if idx := strings.LastIndex(name, "::"); idx >= 0 {
+			local = name[idx+2:]
+		}
+		if err := i.link(i.global(), strings.TrimLeft(name, ":"), local); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+func cmdSubst(i *Interp, args []string) (string, error) {
+	a := args[1:]
+	noBackslash, noCommands, noVariables := false, false, false
+	for len(a) > 1 {
+		switch a[0] {
+		case "-nobackslashes":
+			noBackslash = true
+		case "-nocommands":
+			noCommands = true
+		case "-novariables":
+			noVariables = true
+		default:
+			return "", fmt.Errorf("bad option \"%s\": must be -nobackslashes, -nocommands, or -novariables", a[0])
+		}
+		a = a[1:]
+	}
+	if len(a) != 1 {
+		return "", WrongArgs(args[0], "?-nobackslashes? ?-nocommands? ?-novariables? string")
+	}
+	if !noBackslash && !noCommands && !noVariables {
+		return i.Subst(a[0])
+	}
+	s := a[0]
+	var b strings.Builder
+	p := &parser{src: s}
+	for !p.eof() {
+		c := s[p.pos]
+		switch {
+		case c == '\\' && !noBackslash:
+			t, n := backslash(s[p.pos:])
+			b.WriteString(t)
+			p.pos += n
+		case c == '$' && !noVariables:
+			v, ok, err := p.parseVar()
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				b.WriteByte('$')
+				p.pos++
+				continue
+			}
+			val, err := i.substPart(&Script{src: s}, &v, pos{})
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(val)
+		case c == '[' && !noCommands:
+			p.pos++
+			sub, err := p.parseScript(true)
+			if err != nil {
+				return "", err
+			}
+			val, err := i.evalScript(sub, pos{})
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(val)
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return b.String(), nil
+}
+
+func cmdSwitch(i *Interp, args []string) (string, error) {
+	a := args[1:]
+	mode, nocase := "-exact", false
+	for len(a) > 0 && strings.HasPrefix(a[0], "-") {
+		opt := a[0]
+		a = a[1:]
+		if opt == "--" {
+			break
+		}
+		switch opt {
+		case "-exact", "-glob", "-regexp":
+			mode = opt
+		case "-nocase":
+			nocase = true
+		default:
+			return "", fmt.Errorf("bad option \"%s\": must be -exact, -glob, -nocase, -regexp, or --", opt)
+		}
+	}
+	if len(a) < 2 {
+		return "", WrongArgs(args[0], "?-option ...? string ?pattern body ...? ?default body?")
+	}
+	subject := a[0]
+	clauses := a[1:]
+	if len(clauses) == 1 {
+		var err error
+		if clauses, err = SplitList(clauses[0]); err != nil {
+			return "", err
+		}
+	}
+	if len(clauses)%2 != 0 {
+		return "", fmt.Errorf("extra switch pattern with no body")
+	}
+	for n := 0; n < len(clauses); n += 2 {
+		pattern := clauses[n]
+		matched := n == len(clauses)-2 && pattern == "default"
+		if !matched {
+			var err error
+			if matched, err = switchMatch(mode, nocase, pattern, subject); err != nil {
+				return "", err
+			}
+		}
+		if !matched {
+			continue
+		}
+		// A body of "-" falls through to the next body.
+		for n < len(clauses) && clauses[n+1] == "-" {
+			n += 2
+		}
+		if n >= len(clauses) {
+			return "", fmt.Errorf("no body specified for pattern \"%s\"", pattern)
+		}
+		return i.EvalBody(clauses[n+1])
+	}
+	return "", nil
+}
+
+func switchMatch(mode string, nocase bool, pattern, s string) (bool, error) {
+	switch mode {
+	case "-glob":
+		return globMatch(pattern, s, nocase), nil
+	case "-regexp":
+		re, err := compileRegexp(pattern, nocase)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(s), nil
+	}
+	if nocase {
+		return strings.EqualFold(pattern, s), nil
+	}
+	return pattern == s, nil
+}
+
+func cmdInfo(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "subcommand ?arg ...?")
+	}
+	pattern := ""
+	if len(args) > 2 {
+		pattern = args[2]
+	}
+	match := func(name string) bool {
+		return pattern == "" || globMatch(pattern, name, false)
+	}
+	switch args[1] {
+	case "exists":
+		if len(args) != 3 {
+			return "", WrongArgs("info exists", "varName")
+		}
+		return boolString(i.VarExists(args[2])), nil
+	case "commands", "procs":
+		var names []string
+		for _, name := range i.Commands() {
+			if args[1] == "procs" && i.commands[name].proc == nil {
+				continue
+			}
+			if match(name) {
+				names = append(names, name)
+			}
+		}
+		return FormatList(names), nil
+	case "vars", "locals":
+		return FormatList(varNames(i.current(), pattern)), nil
+	case "globals":
+		return FormatList(varNames(i.global(), pattern)), nil
+	case "level":
+		if len(args) == 2 {
+			return strconv.Itoa(len(i.frames) - 1), nil
+		}
+		n, err := toInt(args[2])
+		if err != nil {
+			return "", err
+		}
+		idx := int(n)
+		if idx <= 0 {
+			idx += len(i.frames) - 1
+		}
+		if idx <= 0 || idx >= len(i.frames) {
+			return "", fmt.Errorf("bad level \"%s\"", args[2])
+		}
+		return FormatList(i.frames[idx].args), nil
+	case "body", "args":
+		if len(args) != 3 {
+			return "", WrongArgs("info "+args[1], "procname")
+		}
+		c, ok := i.lookupCommand(args[2])
+		if !ok || c.proc == nil {
+			return "", fmt.Errorf("\"%s\" isn't a procedure", args[2])
+		}
+		if args[1] == "body" {
+			return c.proc.body, nil
+		}
+		names := make([]string, len(c.proc.params))
+		for n, prm := range c.proc.params {
+			names[n] = prm.name
+		}
+		return FormatList(names), nil
+	case "default":
+		if len(args) != 5 {
+			return "", WrongArgs("info default", "procname arg varname")
+		}
+		c, ok := i.lookupCommand(args[2])
+		if !ok || c.proc == nil {
+			return "", fmt.Errorf("\"%s\" isn't a procedure", args[2])
+		}
+		for _, prm := range c.proc.params {
+			if prm.name == args[3] {
+				if !prm.hasDef {
+					return "0", i.SetVar(args[4], "")
+				}
+				return "1", i.SetVar(args[4], prm.def)
+			}
+		}
+		return "", fmt.Errorf("procedure \"%s\" doesn't have an argument \"%s\"", args[2], args[3])
+	}
+	return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be args, body, commands, default, exists, globals, level, locals, procs, or vars", args[1])
+}
+
+// cmdApply implements apply {args body} ?arg ...?
+func cmdApply(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "lambdaExpr ?arg ...?")
+	}
+	lambda, err := SplitList(args[1])
+	if err != nil {
+		return "", err
+	}
+	if len(lambda) != 2 && len(lambda) != 3 {
+		return "", fmt.Errorf("can't interpret \"%s\" as a lambda expression", args[1])
+	}
+	params, err := parseParams(lambda[0])
+	if err != nil {
+		return "", err
+	}
+	s, err := i.parseBody(lambda[1])
+	if err != nil {
+		return "", err
+	}
+	p := &procedure{name: "apply lambdaExpr", params: params, body: lambda[1], script: s}
+	return i.callProc(p, args[1:])
+}
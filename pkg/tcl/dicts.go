@@ -0,0 +1,355 @@
+package tcl
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func registerDicts(i *Interp) {
+	i.Register("dict", cmdDict)
+	i.Register("array", cmdArray)
+}
+
+var dictSubcommands = "append, create, exists, for, get, getdef, incr, keys, lappend, merge, remove, replace, set, size, unset, or values"
+
+func cmdDict(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "subcommand ?arg ...?")
+	}
+	sub, a := args[1], args[2:]
+	usage := func(u string) error { return WrongArgs("dict "+sub, u) }
+	switch sub {
+	case "create":
+		if len(a)%2 != 0 {
+			return "", usage("?key value ...?")
+		}
+		d := NewDict()
+		for n := 0; n < len(a); n += 2 {
+			d.Set(a[n], a[n+1])
+		}
+		return d.String(), nil
+	case "get":
+		if len(a) < 1 {
+			return "", usage("dictionary ?key ...?")
+		}
+		v, ok, err := dictPath(a[0], a[1:])
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf("key \"%s\" not known in dictionary", a[len(a)-1])
+		}
+		return v, nil
+	case "getdef", "getwithdefault":
+		if len(a) < 3 {
+			return "", usage("dictionary ?key ...? key default")
+		}
+		v, ok, err := dictPath(a[0], a[1:len(a)-1])
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return a[len(a)-1], nil
+		}
+		return v, nil
+	case "exists":
+		if len(a) < 2 {
+			return "", usage("dictionary key ?key ...?")
+		}
+		_, ok, err := dictPath(a[0], a[1:])
+		return boolString(ok && err == nil), nil
+	case "keys", "values":
+		if len(a) != 1 && len(a) != 2 {
+			return "", usage("dictionary ?pattern?")
+		}
+		d, err := ParseDict(a[0])
+		if err != nil {
+			return "", err
+		}
+		var out []string
+		for _, k := range d.Keys() {
+			v, _ := d.Get(k)
+			item := k
+			if sub == "values" {
+				item = v
+			}
+			if len(a) == 2 && !globMatch(a[1], item, false) {
+				continue
+			}
+			out = append(out, item)
+		}
+		return FormatList(out), nil
+	case "size":
+		if len(a) != 1 {
+			return "", usage("dictionary")
+		}
+		d, err := ParseDict(a[0])
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(d.Len()), nil
+	case "merge":
+		out := NewDict()
+		for _, s := range a {
+			d, err := ParseDict(s)
+			if err != nil {
+				return "", err
+			}
+			for _, k := range d.Keys() {
+				v, _ := d.Get(k)
+				out.Set(k, v)
+			}
+		}
+		return out.String(), nil
+	case "remove":
+		if len(a) < 1 {
+			return "", usage("dictionary ?key ...?")
+		}
+		d, err := ParseDict(a[0])
+		if err != nil {
+			return "", err
+		}
+		for _, k := range a[1:] {
+			d.Delete(k)
+		}
+		return d.String(), nil
+	case "replace":
+		if len(a) < 1 || len(a)%2 != 1 {
+			return "", usage("dictionary ?key value ...?")
+		}
+		d, err := ParseDict(a[0])
+		if err != nil {
+			return "", err
+		}
+		for n := 1; n < len(a); n += 2 {
+			d.Set(a[n], a[n+1])
+		}
+		return d.String(), nil
+	case "set", "unset":
+		min := 3
+		if sub == "unset" {
+			min = 2
+		}
+		if len(a) < min {
+			if sub == "set" {
+				return "", usage("dictVarName key ?key ...? value")
+			}
+			return "", usage("dictVarName key ?key ...?")
+		}
+		cur := ""
+		if i.VarExists(a[0]) {
+			var err error
+			if cur, err = i.GetVar(a[0]); err != nil {
+				return "", err
+			}
+		}
+		var res string
+		var err error
+		if sub == "set" {
+			res, err = dictSetPath(cur, a[1:len(a)-1], a[len(a)-1], false)
+		} else {
+			res, err = dictSetPath(cur, a[1:], "", true)
+		}
+		if err != nil {
+			return "", err
+		}
+		return res, i.SetVar(a[0], res)
+	case "incr", "append", "lappend":
+		if len(a) < 2 {
+			return "", usage("dictVarName key ?value ...?")
+		}
+		cur := ""
+		if i.VarExists(a[0]) {
+			var err error
+			if cur, err = i.GetVar(a[0]); err != nil {
+				return "", err
+			}
+		}
+		d, err := ParseDict(cur)
+		if err != nil {
+			return "", err
+		}
+		old, _ := d.Get(a[1])
+		var v string
+		switch sub {
+		case "incr":
+			if len(a) > 3 {
+				return "", usage("dictVarName key ?increment?")
+			}
+			by := int64(1)
+			if len(a) == 3 {
+				if by, err = toInt(a[2]); err != nil {
+					return "", err
+				}
+			}
+			n := int64(0)
+			if old != "" {
+				if n, err = toInt(old); err != nil {
+					return "", err
+				}
+			}
+			v = strconv.FormatInt(n+by, 10)
+		case "append":
+			v = old + strings.Join(a[2:], "")
+		case "lappend":
+			elems, err := SplitList(old)
+			if err != nil {
+				return "", err
+			}
+			v = FormatList(append(elems, a[2:]...))
+		}
+		d.Set(a[1], v)
+		res := d.String()
+		return res, i.SetVar(a[0], res)
+	case "for":
+		if len(a) != 3 {
+			return "", usage("{keyVarName valueVarName} dictionary script")
+		}
+		vars, err := SplitList(a[0])
+		if err != nil {
+			return "", err
+		}
+		if len(vars) != 2 {
+			return "", fmt.Errorf("must have exactly two variable names")
+		}
+		d, err := ParseDict(a[1])
+		if err != nil {
+			return "", err
+		}
+		for _, k := range d.Keys() {
+			v, _ := d.Get(k)
+			if err := i.SetVar(vars[0], k); err != nil {
+				return "", err
+			}
+			if err := i.SetVar(vars[1], v); err != nil {
+				return "", err
+			}
+			if stop, err := i.loopBody(a[2]); stop {
+				return "", err
+			}
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be %s", sub, dictSubcommands)
+}
+
+// dictPath follows keys through nested dictionaries.
+func dictPath(s string, keys []string) (string, bool, error) {
+	for _, k := range keys {
+		d, err := ParseDict(s)
+		if err != nil {
+			return "", false, err
+		}
+		v, ok := d.Get(k)
+		if !ok {
+			return "", false, nil
+		}
+		s = v
+	}
+	return s, true, nil
+}
+
+func dictSetPath(s string, keys []string, v string, remove bool) (string, error) {
+	d, err := ParseDict(s)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 1 {
+		if remove {
+			d.Delete(keys[0])
+		} else {
+			d.Set(keys[0], v)
+		}
+		return d.String(), nil
+	}
+	inner, ok := d.Get(keys[0])
+	if !ok && remove {
+		return d.String(), nil
+	}
+	nv, err := dictSetPath(inner, keys[1:], v, remove)
+	if err != nil {
+		return "", err
+	}
+	d.Set(keys[0], nv)
+	return d.String(), nil
+}
+
+func cmdArray(i *Interp, args []string) (string, error) {
+	if len(args) < 3 {
+		return "", WrongArgs(args[0], "subcommand arrayName ?arg ...?")
+	}
+	sub, name := args[1], args[2]
+	v := i.lookup(name, false)
+	isArray := v != nil && v.defined && v.isArray
+	pattern := ""
+	if len(args) > 3 {
+		pattern = args[3]
+	}
+	names := func() []string {
+		if !isArray {
+			return nil
+		}
+		var keys []string
+		for k := range v.array {
+			if pattern == "" || globMatch(pattern, k, false) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	switch sub {
+	case "exists":
+		return boolString(isArray), nil
+	case "size":
+		if !isArray {
+			return "0", nil
+		}
+		return strconv.Itoa(len(v.array)), nil
+	case "names":
+		return FormatList(names()), nil
+	case "get":
+		var out []string
+		for _, k := range names() {
+			out = append(out, k, v.array[k])
+		}
+		if err := i.checkList(out); err != nil {
+			return "", err
+		}
+		return FormatList(out), nil
+	case "set":
+		if len(args) != 4 {
+			return "", WrongArgs("array set", "arrayName list")
+		}
+		d, err := ParseDict(args[3])
+		if err != nil {
+			return "", err
+		}
+		if v != nil && v.defined && !v.isArray {
+			return "", fmt.Errorf("can't set \"%s(%s)\": variable isn't array", name, "")
+		}
+		v = i.lookup(name, true)
+		if !v.isArray {
+			v.isArray, v.array = true, make(map[string]string)
+		}
+		v.defined = true
+		for _, k := range d.Keys() {
+			v.array[k], _ = d.Get(k)
+		}
+		return "", nil
+	case "unset":
+		if !isArray {
+			return "", nil
+		}
+		if pattern == "" {
+			return "", i.UnsetVar(name)
+		}
+		for _, k := range names() {
+			delete(v.array, k)
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be exists, get, names, set, size, or unset", sub)
+}
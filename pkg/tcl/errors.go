@@ -0,0 +1,116 @@
+package tcl
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error is a script error. Line and Column locate the command that raised
+// it, relative to the outermost script passed to Eval.
+type Error struct {
+	Message string
+	Line    int
+	Column  int
+	// Command is the text of the command that failed, truncated.
+	Command string
+	// Info accumulates a Tcl style stack trace, the same text a script sees
+	// in errorInfo.
+	Info string
+	// Err is the underlying Go error, if any.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrCancelled is reported when the context passed to EvalContext is done
+// before the script finishes.
+var ErrCancelled = errors.New("script cancelled")
+
+// ErrCommandLimit is reported when a script runs more commands than
+// Interp.MaxCommands allows.
+var ErrCommandLimit = errors.New("command limit exceeded")
+
+// ErrMemoryLimit is reported when a script builds a value larger than
+// Interp.MaxValue allows.
+var ErrMemoryLimit = errors.New("memory limit exceeded")
+
+// Completion codes, as seen by catch and return -code.
+const (
+	codeOK = iota
+	codeError
+	codeReturn
+	codeBreak
+	codeContinue
+)
+
+// flow carries non-error completion codes (return, break, continue) up
+// the Go call stack.
+type flow struct {
+	code int
+	// retCode is the code the enclosing proc completes with after return
+	retCode int
+	value   string
+}
+
+func (f *flow) Error() string {
+	switch f.code {
+	case codeBreak:
+		return `invoked "break" outside of a loop`
+	case codeContinue:
+		return `invoked "continue" outside of a loop`
+	default:
+		return `invoked "return" outside of a proc`
+	}
+}
+
+var (
+	errBreak    = &flow{code: codeBreak}
+	errContinue = &flow{code: codeContinue}
+)
+
+func sprintf(format string, args ...any) string {
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// toError converts any error returned by a command into an *Error.
+func toError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Message: err.Error(), Err: err}
+}
+
+// codeOf returns the completion code of a command error.
+func codeOf(err error) int {
+	if err == nil {
+		return codeOK
+	}
+	if f, ok := err.(*flow); ok {
+		return f.code
+	}
+	return codeError
+}
+
+// truncate shortens command text for error messages the same way tclsh
+// does in errorInfo.
+func truncate(s string) string {
+	const max = 150
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "..."
+}
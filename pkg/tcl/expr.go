@@ -0,0 +1,808 @@
+package tcl
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+type valKind int
+
+const (
+	valStr valKind = iota
+	valInt
+	valFloat
+)
+
+// value is an operand or result of expr.
+type value struct {
+	kind valKind
+	i    int64
+	f    float64
+	s    string
+}
+
+func intVal(i int64) value     { return value{kind: valInt, i: i} }
+func floatVal(f float64) value { return value{kind: valFloat, f: f} }
+func strVal(s string) value    { return value{kind: valStr, s: s} }
+
+func boolVal(b bool) value {
+	if b {
+		return intVal(1)
+	}
+	return intVal(0)
+}
+
+func (v value) String() string {
+	switch v.kind {
+	case valInt:
+		return strconv.FormatInt(v.i, 10)
+	case valFloat:
+		return FormatFloat(v.f)
+	}
+	return v.s
+}
+
+// numeric converts string operands to numbers where possible.
+func (v value) numeric() (value, bool) {
+	if v.kind != valStr {
+		return v, true
+	}
+	if i, ok := ParseInt(v.s); ok {
+		return intVal(i), true
+	}
+	t := strings.TrimSpace(v.s)
+	if t == "" || strings.EqualFold(t, "nan") {
+		return v, false
+	}
+	if f, ok := ParseFloat(t); ok {
+		return floatVal(f), true
+	}
+	return v, false
+}
+
+func (v value) float() float64 {
+	if v.kind == valInt {
+		return float64(v.i)
+	}
+	return v.f
+}
+
+func (v value) truth() (bool, error) {
+	switch v.kind {
+	case valInt:
+		return v.i != 0, nil
+	case valFloat:
+		return v.f != 0, nil
+	}
+	return toBool(v.s)
+}
+
+// exprNode is a node of a parsed expression.
+type exprNode interface {
+	eval(i *Interp) (value, error)
+}
+
+type (
+	litNode struct{ v value }
+	varNode struct{ p part }
+	cmdNode struct{ s *Script }
+	strNode struct {
+		src   string
+		parts []part
+	}
+	unaryNode struct {
+		op string
+		x  exprNode
+	}
+	binaryNode struct {
+		op   string
+		x, y exprNode
+	}
+	ternaryNode struct{ cond, x, y exprNode }
+	funcNode    struct {
+		name string
+		args []exprNode
+	}
+)
+
+func (n *litNode) eval(*Interp) (value, error) { return n.v, nil }
+
+func (n *varNode) eval(i *Interp) (value, error) {
+	s, err := i.substPart(&Script{}, &n.p, pos{})
+	return strVal(s), err
+}
+
+func (n *cmdNode) eval(i *Interp) (value, error) {
+	s, err := i.evalScript(n.s, pos{})
+	return strVal(s), err
+}
+
+func (n *strNode) eval(i *Interp) (value, error) {
+	if len(n.parts) == 0 {
+		return strVal(""), nil
+	}
+	s, err := i.substParts(&Script{src: n.src}, n.parts, pos{})
+	return strVal(s), err
+}
+
+// Expr evaluates a Tcl expression and returns its result.
+func (i *Interp) Expr(src string) (string, error) {
+	v, err := i.expr(src)
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
+
+// ExprBool evaluates a Tcl expression as a boolean condition.
+func (i *Interp) ExprBool(src string) (bool, error) {
+	v, err := i.expr(src)
+	if err != nil {
+		return false, err
+	}
+	return v.truth()
+}
+
+func (i *Interp) expr(src string) (value, error) {
+	n, ok := i.exprs[src]
+	if !ok {
+		var err error
+		if n, err = parseExpr(src); err != nil {
+			return value{}, err
+		}
+		if len(i.exprs) >= maxCached {
+			clear(i.exprs)
+		}
+		i.exprs[src] = n
+	}
+	return n.eval(i)
+}
+
+type exprParser struct {
+	parser
+}
+
+func parseExpr(src string) (exprNode, error) {
+	p := &exprParser{parser{src: src}}
+	n, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	p.skipWS()
+	if !p.eof() {
+		return nil, p.syntaxError("extra tokens at end of expression")
+	}
+	return n, nil
+}
+
+func (p *exprParser) syntaxError(msg string) error {
+	return fmt.Errorf("syntax error in expression \"%s\": %s", p.src, msg)
+}
+
+func (p *exprParser) skipWS() {
+	for !p.eof() && (isListSpace(p.src[p.pos]) || p.src[p.pos] == '\\' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '\n') {
+		if p.src[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+}
+
+func (p *exprParser) ternary() (exprNode, error) {
+	cond, err := p.binary(1)
+	if err != nil {
+		return nil, err
+	}
+	p.skipWS()
+	if p.eof() || p.src[p.pos] != '?' {
+		return cond, nil
+	}
+	p.pos++
+	x, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	p.skipWS()
+	if p.eof() || p.src[p.pos] != ':' {
+		return nil, p.syntaxError("missing operator \":\"")
+	}
+	p.pos++
+	y, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryNode{cond, x, y}, nil
+}
+
+var binaryPrec = map[string]int{
+	"||": 1, "&&": 2, "|": 3, "^": 4, "&": 5,
+	"in": 6, "ni": 6,
+	"eq": 7, "ne": 7,
+	"==": 8, "!=": 8,
+	"<": 9, ">": 9, "<=": 9, ">=": 9, "lt": 9, "gt": 9, "le": 9, "ge": 9,
+	"<<": 10, ">>": 10,
+	"+": 11, "-": 11,
+	"*": 12, "/": 12, "%": 12,
+	"**": 13,
+}
+
+// peekOp returns the binary operator at the current position, if any.
+func (p *exprParser) peekOp() string {
+	p.skipWS()
+	if p.eof() {
+		return ""
+	}
+	rest := p.src[p.pos:]
+	for _, op := range []string{"**", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||"} {
+		if strings.HasPrefix(rest, op) {
+			return op
+		}
+	}
+	switch rest[0] {
+	case '*', '/', '%', '+', '-', '<', '>', '&', '^', '|':
+		return rest[:1]
+	}
+	if len(rest) >= 2 {
+		switch w := rest[:2]; w {
+		case "eq", "ne", "in", "ni", "lt", "gt", "le", "ge":
+			if len(rest) == 2 || !isVarChar(rest[2]) {
+				return w
+			}
+		}
+	}
+	return ""
+}
+
+func (p *exprParser) binary(minPrec int) (exprNode, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peekOp()
+		prec, ok := binaryPrec[op]
+		if !ok || prec < minPrec {
+			return x, nil
+		}
+		p.pos += len(op)
+		next := prec + 1
+		if op == "**" {
+			next = prec
+		}
+		y, err := p.binary(next)
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryNode{op, x, y}
+	}
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	p.skipWS()
+	if p.eof() {
+		return nil, p.syntaxError("premature end of expression")
+	}
+	switch c := p.src[p.pos]; c {
+	case '-', '+', '!', '~':
+		p.pos++
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{string(c), x}, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	c := p.src[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		n, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		p.skipWS()
+		if p.eof() || p.src[p.pos] != ')' {
+			return nil, p.syntaxError("looking for close parenthesis")
+		}
+		p.pos++
+		return n, nil
+	case c == '$':
+		v, ok, err := p.parseVar()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, p.syntaxError("invalid character \"$\"")
+		}
+		return &varNode{v}, nil
+	case c == '[':
+		p.pos++
+		s, err := p.parseScript(true)
+		if err != nil {
+			return nil, err
+		}
+		return &cmdNode{s}, nil
+	case c == '"':
+		start := p.pos
+		p.pos++
+		parts, err := p.parseParts(func(c byte) bool { return c == '"' })
+		if err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			p.pos = start
+			return nil, p.syntaxError("missing \"")
+		}
+		p.pos++
+		if len(parts) == 1 && parts[0].kind == partText {
+			return &litNode{strVal(parts[0].text)}, nil
+		}
+		return &strNode{src: p.src, parts: parts}, nil
+	case c == '{':
+		text, err := p.parseBraced()
+		if err != nil {
+			return nil, err
+		}
+		return &litNode{strVal(text)}, nil
+	case c >= '0' && c <= '9' || c == '.':
+		return p.number()
+	case isVarChar(c):
+		return p.identifier()
+	}
+	return nil, p.syntaxError(fmt.Sprintf("unexpected character \"%c\"", c))
+}
+
+func (p *exprParser) number() (exprNode, error) {
+	start := p.pos
+	if strings.HasPrefix(p.src[p.pos:], "0x") || strings.HasPrefix(p.src[p.pos:], "0X") ||
+		strings.HasPrefix(p.src[p.pos:], "0b") || strings.HasPrefix(p.src[p.pos:], "0o") {
+		p.pos += 2
+		for !p.eof() && isHex(p.src[p.pos]) {
+			p.pos++
+		}
+	} else {
+		for !p.eof() && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		if !p.eof() && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+			p.pos++
+			if !p.eof() && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+				p.pos++
+			}
+			for !p.eof() && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+				p.pos++
+			}
+		}
+	}
+	text := p.src[start:p.pos]
+	if i, ok := ParseInt(text); ok {
+		return &litNode{intVal(i)}, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return &litNode{floatVal(f)}, nil
+	}
+	return nil, p.syntaxError(fmt.Sprintf("invalid number \"%s\"", text))
+}
+
+func (p *exprParser) identifier() (exprNode, error) {
+	start := p.pos
+	for !p.eof() && isVarChar(p.src[p.pos]) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	p.skipWS()
+	if !p.eof() && p.src[p.pos] == '(' {
+		p.pos++
+		fn := &funcNode{name: name}
+		p.skipWS()
+		if !p.eof() && p.src[p.pos] == ')' {
+			p.pos++
+			return fn, nil
+		}
+		for {
+			arg, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			fn.args = append(fn.args, arg)
+			p.skipWS()
+			if p.eof() {
+				return nil, p.syntaxError("missing close parenthesis at end of function call")
+			}
+			if p.src[p.pos] == ')' {
+				p.pos++
+				return fn, nil
+			}
+			if p.src[p.pos] != ',' {
+				return nil, p.syntaxError("missing function argument separator")
+			}
+			p.pos++
+		}
+	}
+	switch strings.ToLower(name) {
+	case "true", "false", "yes", "no", "on", "off":
+		return &litNode{strVal(name)}, nil
+	case "inf":
+		return &litNode{floatVal(math.Inf(1))}, nil
+	case "nan":
+		return &litNode{floatVal(math.NaN())}, nil
+	}
+	return nil, p.syntaxError(fmt.Sprintf("invalid bareword \"%s\"", name))
+}
+
+func (n *unaryNode) eval(i *Interp) (value, error) {
+	x, err := n.x.eval(i)
+	if err != nil {
+		return x, err
+	}
+	if n.op == "!" {
+		b, err := x.truth()
+		if err != nil {
+			return x, err
+		}
+		return boolVal(!b), nil
+	}
+	x, ok := x.numeric()
+	if !ok {
+		return x, nonNumeric(x, n.op)
+	}
+	switch n.op {
+	case "-":
+		if x.kind == valInt {
+			return intVal(-x.i), nil
+		}
+		return floatVal(-x.f), nil
+	case "~":
+		if x.kind != valInt {
+			return x, fmt.Errorf("can't use floating-point value as operand of \"~\"")
+		}
+		return intVal(^x.i), nil
+	}
+	return x, nil
+}
+
+func (n *ternaryNode) eval(i *Interp) (value, error) {
+	c, err := n.cond.eval(i)
+	if err != nil {
+		return c, err
+	}
+	b, err := c.truth()
+	if err != nil {
+		return c, err
+	}
+	if b {
+		return n.x.eval(i)
+	}
+	return n.y.eval(i)
+}
+
+func nonNumeric(v value, op string) error {
+	if v.s == "" {
+		return fmt.Errorf("can't use empty string as operand of \"%s\"", op)
+	}
+	return fmt.Errorf("can't use non-numeric string \"%s\" as operand of \"%s\"", v.s, op)
+}
+
+func (n *binaryNode) eval(i *Interp) (value, error) {
+	x, err := n.x.eval(i)
+	if err != nil {
+		return x, err
+	}
+	// Logical operators short-circuit.
+	if n.op == "&&" || n.op == "||" {
+		a, err := x.truth()
+		if err != nil {
+			return x, err
+		}
+		if (n.op == "&&" && !a) || (n.op == "||" && a) {
+			return boolVal(a), nil
+		}
+		y, err := n.y.eval(i)
+		if err != nil {
+			return y, err
+		}
+		b, err := y.truth()
+		return boolVal(b), err
+	}
+	y, err := n.y.eval(i)
+	if err != nil {
+		return y, err
+	}
+	switch n.op {
+	case "eq":
+		return boolVal(x.String() == y.String()), nil
+	case "ne":
+		return boolVal(x.String() != y.String()), nil
+	case "lt":
+		return boolVal(x.String() < y.String()), nil
+	case "gt":
+		return boolVal(x.String() > y.String()), nil
+	case "le":
+		return boolVal(x.String() <= y.String()), nil
+	case "ge":
+		return boolVal(x.String() >= y.String()), nil
+	case "in", "ni":
+		elems, err := SplitList(y.String())
+		if err != nil {
+			return y, err
+		}
+		found := false
+		s := x.String()
+		for _, e := range elems {
+			if e == s {
+				found = true
+				break
+			}
+		}
+		return boolVal(found == (n.op == "in")), nil
+	}
+
+	xn, xok := x.numeric()
+	yn, yok := y.numeric()
+	switch n.op {
+	case "==", "!=", "<", ">", "<=", ">=":
+		var c int
+		if xok && yok {
+			c = compareNumbers(xn, yn)
+		} else {
+			c = strings.Compare(x.String(), y.String())
+		}
+		switch n.op {
+		case "==":
+			return boolVal(c == 0), nil
+		case "!=":
+			return boolVal(c != 0), nil
+		case "<":
+			return boolVal(c < 0), nil
+		case ">":
+			return boolVal(c > 0), nil
+		case "<=":
+			return boolVal(c <= 0), nil
+		default:
+			return boolVal(c >= 0), nil
+		}
+	}
+	if !xok {
+		return x, nonNumeric(x, n.op)
+	}
+	if !yok {
+		return y, nonNumeric(y, n.op)
+	}
+	return arith(n.op, xn, yn)
+}
+
+func compareNumbers(x, y value) int {
+	if x.kind == valInt && y.kind == valInt {
+		switch {
+		case x.i < y.i:
+			return -1
+		case x.i > y.i:
+			return 1
+		}
+		return 0
+	}
+	a, b := x.float(), y.float()
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func arith(op string, x, y value) (value, error) {
+	if x.kind == valInt && y.kind == valInt {
+		a, b := x.i, y.i
+		switch op {
+		case "+":
+			return intVal(a + b), nil
+		case "-":
+			return intVal(a - b), nil
+		case "*":
+			return intVal(a * b), nil
+		case "/":
+			if b == 0 {
+				return x, fmt.Errorf("divide by zero")
+			}
+			q := a / b
+			if (a%b != 0) && ((a < 0) != (b < 0)) {
+				q--
+			}
+			return intVal(q), nil
+		case "%":
+			if b == 0 {
+				return x, fmt.Errorf("divide by zero")
+			}
+			r := a % b
+			if r != 0 && ((r < 0) != (b < 0)) {
+				r += b
+			}
+			return intVal(r), nil
+		case "**":
+			return intPow(a, b)
+		case "&":
+			return intVal(a & b), nil
+		case "|":
+			return intVal(a | b), nil
+		case "^":
+			return intVal(a ^ b), nil
+		case "<<":
+			return intVal(a << uint64(b)), nil
+		case ">>":
+			return intVal(a >> uint64(b)), nil
+		}
+	}
+	switch op {
+	case "%", "&", "|", "^", "<<", ">>":
+		return x, fmt.Errorf("can't use floating-point value as operand of \"%s\"", op)
+	}
+	a, b := x.float(), y.float()
+	switch op {
+	case "+":
+		return floatVal(a + b), nil
+	case "-":
+		return floatVal(a - b), nil
+	case "*":
+		return floatVal(a * b), nil
+	case "/":
+		return floatVal(a / b), nil
+	case "**":
+		return floatVal(math.Pow(a, b)), nil
+	}
+	return x, fmt.Errorf("unknown operator \"%s\"", op)
+}
+
+func intPow(a, b int64) (value, error) {
+	if b < 0 {
+		switch a {
+		case 0:
+			return intVal(0), fmt.Errorf("exponentiation of zero by negative power")
+		case 1:
+			return intVal(1), nil
+		case -1:
+			if b%2 == 0 {
+				return intVal(1), nil
+			}
+			return intVal(-1), nil
+		}
+		return intVal(0), nil
+	}
+	r := int64(1)
+	for ; b > 0; b-- {
+		r *= a
+	}
+	return intVal(r), nil
+}
+
+func (n *funcNode) eval(i *Interp) (value, error) {
+	args := make([]value, len(n.args))
+	for k, a := range n.args {
+		v, err := a.eval(i)
+		if err != nil {
+			return v, err
+		}
+		args[k] = v
+	}
+	return callMathFunc(n.name, args)
+}
+
+var unaryMath = map[string]func(float64) float64{
+	"sqrt": math.Sqrt, "exp": math.Exp, "log": math.Log, "log10": math.Log10,
+	"sin": math.Sin, "cos": math.Cos, "tan": math.Tan,
+	"asin": math.Asin, "acos": math.Acos, "atan": math.Atan,
+	"sinh": math.Sinh, "cosh": math.Cosh, "tanh": math.Tanh,
+}
+
+var binaryMath = map[string]func(float64, float64) float64{
+	"pow": math.Pow, "fmod": math.Mod, "hypot": math.Hypot, "atan2": math.Atan2,
+}
+
+func callMathFunc(name string, args []value) (value, error) {
+	nums := make([]value, len(args))
+	for k, a := range args {
+		n, ok := a.numeric()
+		if !ok && name != "bool" {
+			return a, fmt.Errorf("expected number but got \"%s\"", a.s)
+		}
+		nums[k] = n
+	}
+	arity := func(n int) error {
+		if len(nums) != n {
+			if len(nums) < n {
+				return fmt.Errorf("too few arguments for math function \"%s\"", name)
+			}
+			return fmt.Errorf("too many arguments for math function \"%s\"", name)
+		}
+		return nil
+	}
+	if f, ok := unaryMath[name]; ok {
+		if err := arity(1); err != nil {
+			return value{}, err
+		}
+		return floatVal(f(nums[0].float())), nil
+	}
+	if f, ok := binaryMath[name]; ok {
+		if err := arity(2); err != nil {
+			return value{}, err
+		}
+		return floatVal(f(nums[0].float(), nums[1].float())), nil
+	}
+	switch name {
+	case "abs":
+		if err := arity(1); err != nil {
+			return value{}, err
+		}
+		if nums[0].kind == valInt {
+			if nums[0].i < 0 {
+				return intVal(-nums[0].i), nil
+			}
+			return nums[0], nil
+		}
+		return floatVal(math.Abs(nums[0].f)), nil
+	case "int", "wide", "entier":
+		if err := arity(1); err != nil {
+			return value{}, err
+		}
+		if nums[0].kind == valInt {
+			return nums[0], nil
+		}
+		return intVal(int64(nums[0].f)), nil
+	case "double":
+		if err := arity(1); err != nil {
+			return value{}, err
+		}
+		return floatVal(nums[0].float()), nil
+	case "round":
+		if err := arity(1); err != nil {
+			return value{}, err
+		}
+		if nums[0].kind == valInt {
+			return nums[0], nil
+		}
+		return intVal(int64(math.Round(nums[0].f))), nil
+	case "ceil", "floor":
+		if err := arity(1); err != nil {
+			return value{}, err
+		}
+		if name == "ceil" {
+			return floatVal(math.Ceil(nums[0].float())), nil
+		}
+		return floatVal(math.Floor(nums[0].float())), nil
+	case "isqrt":
+		if err := arity(1); err != nil {
+			return value{}, err
+		}
+		return intVal(int64(math.Sqrt(nums[0].float()))), nil
+	case "bool":
+		if err := arity(1); err != nil {
+			return value{}, err
+		}
+		b, err := args[0].truth()
+		return boolVal(b), err
+	case "min", "max":
+		if len(nums) == 0 {
+			return value{}, fmt.Errorf("too few arguments for math function \"%s\"", name)
+		}
+		best := nums[0]
+		for _, v := range nums[1:] {
+			c := compareNumbers(v, best)
+			if (name == "min" && c < 0) || (name == "max" && c > 0) {
+				best = v
+			}
+		}
+		return best, nil
+	case "rand":
+		if err := arity(0); err != nil {
+			return value{}, err
+		}
+		return floatVal(rand.Float64()), nil
+	}
+	return value{}, fmt.Errorf("invalid command name \"tcl::mathfunc::%s\"", name)
+}
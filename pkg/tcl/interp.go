@@ -0,0 +1,502 @@
+// Package tcl implements a small Tcl interpreter in pure Go.
+//
+// It covers the core of the language that toolmin scripts rely on:
+// substitution rules, procs, control flow, lists, dicts, arrays and expr.
+// Host code extends an interpreter by registering Go commands with
+// Register.
+package tcl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"strings"
+)
+
+// CommandFunc implements a Tcl command. args[0] is the command name as
+// invoked. The returned string becomes the command result.
+type CommandFunc func(i *Interp, args []string) (string, error)
+
+// maxNesting bounds recursion through procs, eval and substitutions.
+const maxNesting = 1000
+
+// maxCached bounds the number of bodies and expressions kept parsed.
+const maxCached = 1024
+
+// Interp is a Tcl interpreter. An Interp is not safe for concurrent use.
+type Interp struct {
+	// Stdout receives output written by puts; defaults to io.Discard.
+	Stdout io.Writer
+	// Stderr receives output written by puts stderr; defaults to io.Discard.
+	Stderr io.Writer
+	// MaxCommands bounds the number of commands a single top-level
+	// evaluation may run; zero means no limit. Exceeding it fails the
+	// script with ErrCommandLimit.
+	MaxCommands int
+	// MaxValue bounds the size in bytes of a value built by a command or
+	// substitution; zero means no limit. Exceeding it fails the script with
+	// ErrMemoryLimit.
+	MaxValue int
+
+	commands map[string]*cmdEntry
+	// core holds the commands New registered, which Reset restores
+	core   map[string]*cmdEntry
+	frames []*frame
+	evals  []evalState
+	depth  int
+
+	ctx  context.Context
+	done <-chan struct{}
+
+	// running is set during a top-level evaluation, which steps counts
+	running bool
+	steps   int
+	// overflowed is set once a value exceeds MaxValue
+	overflowed bool
+
+	bodies map[string]*Script
+	exprs  map[string]exprNode
+}
+
+type cmdEntry struct {
+	fn   CommandFunc
+	proc *procedure
+}
+
+// evalState records the command currently executing so that nested
+// bodies can be mapped back to their position in the outer source.
+type evalState struct {
+	script *Script
+	cmd    *command
+	base   pos
+}
+
+// pos is an absolute line and column. The zero pos means unknown.
+type pos struct {
+	line, col int
+}
+
+// abs converts a position relative to a script starting at b into an
+// absolute position.
+func (b pos) abs(line, col int) pos {
+	if line == 1 {
+		return pos{b.line, b.col + col - 1}
+	}
+	return pos{b.line + line - 1, col}
+}
+
+// New returns an interpreter with the core commands registered.
+func New() *Interp {
+	i := &Interp{
+		Stdout:   io.Discard,
+		Stderr:   io.Discard,
+		commands: make(map[string]*cmdEntry),
+		frames:   []*frame{newFrame()},
+		ctx:      context.Background(),
+		bodies:   make(map[string]*Script),
+		exprs:    make(map[string]exprNode),
+	}
+	registerCore(i)
+	registerStrings(i)
+	registerLists(i)
+	registerDicts(i)
+	i.core = maps.Clone(i.commands)
+	return i
+}
+
+// Reset returns the interpreter to the state New left it in, so it can run
+// an unrelated script: variables, procs and registered commands are
+// removed and the exported fields are reset. Parsed bodies and expressions
+// are kept, which makes a reset interpreter cheaper to use than a new one.
+// Reset must not be called during an evaluation.
+func (i *Interp) Reset() {
+	i.Stdout, i.Stderr = io.Discard, io.Discard
+	i.MaxCommands, i.MaxValue = 0, 0
+	i.commands = maps.Clone(i.core)
+	i.frames = []*frame{newFrame()}
+	i.evals = i.evals[:0]
+	i.depth = 0
+	i.ctx, i.done = context.Background(), nil
+	i.running, i.steps, i.overflowed = false, 0, false
+}
+
+// Register adds or replaces a command.
+func (i *Interp) Register(name string, fn CommandFunc) {
+	i.commands[name] = &cmdEntry{fn: fn}
+}
+
+// HasCommand reports whether a command with the given name exists.
+func (i *Interp) HasCommand(name string) bool {
+	_, ok := i.lookupCommand(name)
+	return ok
+}
+
+// Commands returns the sorted names of all commands.
+func (i *Interp) Commands() []string {
+	names := make([]string, 0, len(i.commands))
+	for name := range i.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Context returns the context of the evaluation in progress, or
+// context.Background when called outside EvalContext.
+func (i *Interp) Context() context.Context {
+	return i.ctx
+}
+
+// Eval parses and evaluates a script at the global level.
+func (i *Interp) Eval(src string) (string, error) {
+	return i.EvalContext(context.Background(), src)
+}
+
+// EvalContext evaluates a script, abandoning it with ErrCancelled once ctx
+// is done.
+func (i *Interp) EvalContext(ctx context.Context, src string) (string, error) {
+	s, err := Parse(src)
+	if err != nil {
+		return "", err
+	}
+	return i.EvalScript(ctx, s)
+}
+
+// EvalScript evaluates a parsed script at the global level.
+func (i *Interp) EvalScript(ctx context.Context, s *Script) (string, error) {
+	prevCtx, prevDone := i.ctx, i.done
+	i.ctx, i.done = ctx, ctx.Done()
+	defer func() { i.ctx, i.done = prevCtx, prevDone }()
+
+	if !i.running {
+		i.running, i.steps, i.overflowed = true, 0, false
+		defer func() { i.running = false }()
+	}
+
+	saved := i.frames
+	i.frames = []*frame{i.frames[0]}
+	defer func() { i.frames = saved }()
+
+	res, err := i.evalScript(s, pos{1, 1})
+	if err != nil {
+		if f, ok := err.(*flow); ok {
+			if f.code == codeReturn {
+				return i.returnResult(f)
+			}
+			return "", &Error{Message: f.Error()}
+		}
+		return "", err
+	}
+	return res, nil
+}
+
+// returnResult converts a return at script or proc level into the result
+// it produces.
+func (i *Interp) returnResult(f *flow) (string, error) {
+	switch f.retCode {
+	case codeOK:
+		return f.value, nil
+	case codeError:
+		return "", &Error{Message: f.value}
+	case codeReturn:
+		return "", &flow{code: codeReturn, value: f.value}
+	case codeBreak:
+		return "", errBreak
+	case codeContinue:
+		return "", errContinue
+	}
+	return f.value, nil
+}
+
+// Call invokes a command directly with already substituted arguments.
+func (i *Interp) Call(args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	return i.invoke(args)
+}
+
+// EvalBody evaluates a script in the current frame, as control structures
+// do with their bodies. It is intended for use by commands.
+func (i *Interp) EvalBody(body string) (string, error) {
+	base := i.bodyBase(body)
+	s, err := i.parseBody(body)
+	if err != nil {
+		return "", rebase(err, base)
+	}
+	return i.evalScript(s, base)
+}
+
+// rebase moves the position of a parse error in a nested body into the
+// coordinates of the outer source. When the body's position is unknown the
+// error is left for the enclosing command to position.
+func rebase(err error, base pos) error {
+	e := toError(err)
+	if base.line == 0 {
+		e.Line, e.Column = 0, 0
+		return e
+	}
+	p := base.abs(e.Line, e.Column)
+	e.Line, e.Column = p.line, p.col
+	return e
+}
+
+func (i *Interp) parseBody(body string) (*Script, error) {
+	if s, ok := i.bodies[body]; ok {
+		return s, nil
+	}
+	s, err := Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(i.bodies) >= maxCached {
+		clear(i.bodies)
+	}
+	i.bodies[body] = s
+	return s, nil
+}
+
+// bodyBase finds where body appears as a braced word of the command that
+// is currently executing, so that errors inside it report positions in
+// the outer source.
+func (i *Interp) bodyBase(body string) pos {
+	n := len(i.evals)
+	if n == 0 {
+		return pos{}
+	}
+	st := i.evals[n-1]
+	if st.base.line == 0 {
+		return pos{}
+	}
+	for _, w := range st.cmd.words {
+		if w.braced && w.value == body {
+			l, c := position(st.script.src, w.off)
+			return st.base.abs(l, c)
+		}
+	}
+	return pos{}
+}
+
+func (i *Interp) evalScript(s *Script, base pos) (string, error) {
+	result := ""
+	for ci := range s.cmds {
+		res, err := i.evalCommand(s, &s.cmds[ci], base)
+		if err != nil {
+			return "", err
+		}
+		result = res
+	}
+	return result, nil
+}
+
+func (i *Interp) evalCommand(s *Script, cmd *command, base pos) (string, error) {
+	if err := i.step(); err != nil {
+		return "", i.annotate(err, s, cmd, base)
+	}
+	args, err := i.substWords(s, cmd.words, base)
+	if err != nil {
+		return "", i.annotate(err, s, cmd, base)
+	}
+	if len(args) == 0 {
+		return "", nil
+	}
+	i.evals = append(i.evals, evalState{script: s, cmd: cmd, base: base})
+	res, err := i.invoke(args)
+	i.evals = i.evals[:len(i.evals)-1]
+	if err != nil {
+		return "", i.annotate(err, s, cmd, base)
+	}
+	return res, nil
+}
+
+// step accounts for one command or loop iteration. It fails once the
+// context is done or the command budget is spent.
+func (i *Interp) step() error {
+	if i.done != nil {
+		select {
+		case <-i.done:
+			return i.cancelled()
+		default:
+		}
+	}
+	if i.MaxCommands > 0 {
+		i.steps++
+		if i.exhausted() {
+			return &Error{
+				Message: fmt.Sprintf("%v: more than %d commands", ErrCommandLimit, i.MaxCommands),
+				Err:     ErrCommandLimit,
+			}
+		}
+	}
+	return nil
+}
+
+// cancelled reports why the context is done, preferring the cause given
+// to context.WithCancelCause and friends.
+func (i *Interp) cancelled() error {
+	cause := context.Cause(i.ctx)
+	return &Error{
+		Message: fmt.Sprintf("%v: %v", ErrCancelled, cause),
+		Err:     fmt.Errorf("%w: %w", ErrCancelled, cause),
+	}
+}
+
+// exhausted reports whether the command budget has run out
+func (i *Interp) exhausted() bool {
+	return i.MaxCommands > 0 && i.steps > i.MaxCommands
+}
+
+// checkSize fails with ErrMemoryLimit if a value of n bytes would exceed
+// MaxValue. Callers check before allocating the value.
+func (i *Interp) checkSize(n int64) error {
+	if i.MaxValue <= 0 || n <= int64(i.MaxValue) {
+		return nil
+	}
+	i.overflowed = true
+	return &Error{
+		Message: fmt.Sprintf("%v: value of %d bytes is larger than %d", ErrMemoryLimit, n, i.MaxValue),
+		Err:     ErrMemoryLimit,
+	}
+}
+
+// checkList fails with ErrMemoryLimit if elems, formatted as a list, would
+// be larger than MaxValue. Quoting can make the list longer than estimated,
+// which the check of every command's result catches.
+func (i *Interp) checkList(elems []string) error {
+	if i.MaxValue <= 0 {
+		return nil
+	}
+	var size int64
+	for _, e := range elems {
+		size += int64(len(e)) + 1
+	}
+	return i.checkSize(size)
+}
+
+// annotate attaches the failing command's position to err, unless a more
+// deeply nested command already did.
+func (i *Interp) annotate(err error, s *Script, cmd *command, base pos) error {
+	if _, ok := err.(*flow); ok {
+		return err
+	}
+	e := toError(err)
+	text := truncate(s.src[cmd.off:cmd.end])
+	if e.Info == "" {
+		e.Info = e.Message + "\n    while executing\n\"" + text + "\""
+	} else {
+		e.Info += "\n    invoked from within\n\"" + text + "\""
+	}
+	if e.Line == 0 && base.line > 0 {
+		l, c := position(s.src, cmd.off)
+		p := base.abs(l, c)
+		e.Line, e.Column, e.Command = p.line, p.col, text
+	}
+	return e
+}
+
+func (i *Interp) lookupCommand(name string) (*cmdEntry, bool) {
+	c, ok := i.commands[name]
+	if !ok && strings.HasPrefix(name, "::") {
+		c, ok = i.commands[strings.TrimLeft(name, ":")]
+	}
+	return c, ok
+}
+
+func (i *Interp) invoke(args []string) (string, error) {
+	c, ok := i.lookupCommand(args[0])
+	if !ok {
+		return "", fmt.Errorf("invalid command name \"%s\"", args[0])
+	}
+	if i.depth >= maxNesting {
+		return "", fmt.Errorf("too many nested evaluations (infinite loop?)")
+	}
+	i.depth++
+	defer func() { i.depth-- }()
+	res, err := c.fn(i, args)
+	if err != nil {
+		return "", err
+	}
+	// Commands check sizes they can predict before allocating. This catches
+	// the rest before the value can be used to build a larger one.
+	if err := i.checkSize(int64(len(res))); err != nil {
+		return "", err
+	}
+	return res, nil
+}
+
+func (i *Interp) substWords(s *Script, words []word, base pos) ([]string, error) {
+	args := make([]string, 0, len(words))
+	for wi := range words {
+		w := &words[wi]
+		var v string
+		if w.literal {
+			v = w.value
+		} else {
+			var err error
+			if v, err = i.substParts(s, w.parts, base); err != nil {
+				return nil, err
+			}
+		}
+		if w.expand {
+			elems, err := SplitList(v)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, elems...)
+			continue
+		}
+		args = append(args, v)
+	}
+	return args, nil
+}
+
+func (i *Interp) substParts(s *Script, parts []part, base pos) (string, error) {
+	if len(parts) == 1 {
+		return i.substPart(s, &parts[0], base)
+	}
+	var b strings.Builder
+	for pi := range parts {
+		v, err := i.substPart(s, &parts[pi], base)
+		if err != nil {
+			return "", err
+		}
+		if err := i.checkSize(int64(b.Len() + len(v))); err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+	}
+	return b.String(), nil
+}
+
+func (i *Interp) substPart(s *Script, p *part, base pos) (string, error) {
+	switch p.kind {
+	case partVar:
+		if p.hasIndex {
+			idx, err := i.substParts(s, p.index, base)
+			if err != nil {
+				return "", err
+			}
+			return i.getVar(p.text, idx, true)
+		}
+		return i.getVar(p.text, "", false)
+	case partCmd:
+		return i.evalScript(p.script, base)
+	default:
+		return p.text, nil
+	}
+}
+
+// Subst performs backslash, variable and command substitution on s.
+func (i *Interp) Subst(s string) (string, error) {
+	p := &parser{src: s}
+	parts, err := p.parseParts(func(byte) bool { return false })
+	if err != nil {
+		return "", err
+	}
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return i.substParts(&Script{src: s}, parts, pos{})
+}
@@ -0,0 +1,306 @@
+package tcl_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytjohn/toolmin/pkg/tcl"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"set", `set x 5`, "5"},
+		{"variable substitution", `set x 5; set y "x is $x"`, "x is 5"},
+		{"braced variable", `set x 5; set y ${x}px`, "5px"},
+		{"command substitution", `set x [expr {1 + 2}]`, "3"},
+		{"braces suppress substitution", `set x {$y [z]}`, "$y [z]"},
+		{"backslashes", `set x "a\tb\x41é"`, "a\tbAé"},
+		{"line continuation", "set x [list a \\\n    b]", "a b"},
+		{"comments", "# a comment\nset x 1 ;# trailing\n", "1"},
+		{"expand", `set l {b c}; list a {*}$l d`, "a b c d"},
+		{"arrays", `set a(x) 1; set k x; incr a($k); array get a`, "x 2"},
+		{"append", `append s a b; append s c`, "abc"},
+		{"incr missing", `incr n 5`, "5"},
+
+		{"expr precedence", `expr {1 + 2 * 3 ** 2}`, "19"},
+		{"expr float", `expr {7 / 2.0}`, "3.5"},
+		{"expr int division floors", `expr {-7 / 2}`, "-4"},
+		{"expr modulo sign", `expr {-7 % 3}`, "2"},
+		{"expr strings", `expr {"abc" eq "abc" && "a" < "b"}`, "1"},
+		{"expr in", `expr {"b" in {a b c}}`, "1"},
+		{"expr ternary", `set x 3; expr {$x > 2 ? "big" : "small"}`, "big"},
+		{"expr functions", `expr {max(1, 5, 3) + abs(-2) + round(2.6)}`, "10"},
+		{"expr double formatting", `expr {double(2)}`, "2.0"},
+		{"expr short circuit", `set x 0; expr {$x != 0 && [error boom]}`, "0"},
+
+		{"if elseif else", `set x 2; if {$x == 1} {set r one} elseif {$x == 2} {set r two} else {set r other}`, "two"},
+		{"while", `set i 0; set s 0; while {$i < 5} {incr s $i; incr i}; set s`, "10"},
+		{"for with break", `for {set i 0} {$i < 10} {incr i} {if {$i == 3} break}; set i`, "3"},
+		{"foreach continue", `set r {}; foreach x {1 2 3 4} {if {$x % 2} continue; lappend r $x}; set r`, "2 4"},
+		{"foreach pairs", `set r {}; foreach {k v} {a 1 b 2} {append r $k=$v,}; set r`, "a=1,b=2,"},
+		{"lmap", `lmap x {1 2 3} {expr {$x * 2}}`, "2 4 6"},
+		{"switch glob", `switch -glob -- foo.txt {*.go {set r go} *.txt {set r text} default {set r ?}}`, "text"},
+		{"switch fallthrough", `switch b {a - b {set r ab} default {set r d}}`, "ab"},
+
+		{"proc", `proc add {a {b 10}} {return [expr {$a + $b}]}; list [add 1 2] [add 1]`, "3 11"},
+		{"proc args", `proc count {args} {llength $args}; count a b c`, "3"},
+		{"recursion", `proc fib n {if {$n < 2} {return $n}; expr {[fib [expr {$n-1}]] + [fib [expr {$n-2}]]}}; fib 10`, "55"},
+		{"global", `set g 1; proc bump {} {global g; incr g}; bump; set g`, "2"},
+		{"upvar", `proc setit {name} {upvar $name v; set v hi}; setit x; set x`, "hi"},
+		{"uplevel", `proc ctx {} {uplevel {set y 7}}; ctx; set y`, "7"},
+		{"apply", `apply {{x} {expr {$x * $x}}} 4`, "16"},
+
+		{"catch error", `list [catch {error oops} msg] $msg`, "1 oops"},
+		{"catch ok", `list [catch {set x 1} msg] $msg`, "0 1"},
+		{"try on error finally", `set r {}; try {error bad} on error {m} {append r caught:$m} finally {append r ,done}; set r`, "caught:bad,done"},
+		{"return code error", `proc f {} {return -code error nope}; catch f m; set m`, "nope"},
+		{"top level return", "return early\nset x late", "early"},
+
+		{"list quoting", `list a "b c" {} {d{}}`, "a {b c} {} {d{}}"},
+		{"lindex nested", `lindex {a {b c} d} 1 1`, "c"},
+		{"lrange end", `lrange {a b c d} 1 end-1`, "b c"},
+		{"lsort", `lsort -integer -decreasing {3 10 2}`, "10 3 2"},
+		{"lsort unique", `lsort -unique {b a b c a}`, "a b c"},
+		{"lsearch", `list [lsearch {a b c} c] [lsearch -all -inline {a1 b a2} a*]`, "2 {a1 a2}"},
+		{"lreplace", `lreplace {a b c d} 1 2 X`, "a X d"},
+		{"lassign", `lassign {1 2 3} a b; list $a $b`, "1 2"},
+		{"lset", `set l {a {b c}}; lset l 1 0 X; set l`, "a {X c}"},
+		{"join split", `join [split a,b,c ,] -`, "a-b-c"},
+
+		{"string ops", `list [string length héllo] [string toupper abc] [string range abcdef 1 end-1] [string index abc end]`, "5 ABC bcde c"},
+		{"string first last", `list [string first b abcb] [string last b abcb]`, "1 3"},
+		{"string map", `string map {a 1 b 2} abcab`, "12c12"},
+		{"string match", `string match {[a-c]*.txt} b1.txt`, "1"},
+		{"string is", `list [string is integer 42] [string is integer 4x] [string is double 1.5]`, "1 0 1"},
+		{"string trim", `string trim "  x  "`, "x"},
+		{"format", `format "%05.1f|%-4s|%x|%d%%" 3.14159 ab 255 7`, "003.1|ab  |ff|7%"},
+
+		{"dict", `set d [dict create a 1 b 2]; dict set d c 3; dict incr d a; list [dict get $d a] [dict keys $d] [dict size $d]`, "2 {a b c} 3"},
+		{"dict nested", `dict set d x y 1; dict get $d x y`, "1"},
+		{"dict for", `set r {}; dict for {k v} {a 1 b 2} {append r $k$v}; set r`, "a1b2"},
+		{"dict getdef", `dict getdef {a 1} b fallback`, "fallback"},
+
+		{"subst", `set x 1; subst {x=$x [expr {$x+1}]}`, "x=1 2"},
+		{"info exists", `set x 1; list [info exists x] [info exists y]`, "1 0"},
+		{"eval", `eval set x 4`, "4"},
+		{"rename", `proc a {} {return a}; rename a b; b`, "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interp := tcl.New()
+			got, err := interp.Eval(tt.script)
+			if err != nil {
+				t.Fatalf("Eval(%q) error: %v", tt.script, err)
+			}
+			if got != tt.want {
+				t.Errorf("Eval(%q) = %q, want %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		message string
+		line    int
+		column  int
+	}{
+		{"unknown command", "set x 1\nnosuch cmd", `invalid command name "nosuch"`, 2, 1},
+		{"missing variable", "set x 1\n  puts $y", `can't read "y": no such variable`, 2, 3},
+		{"inside if body", "set x 1\nif {$x} {\n    set a 1\n    error boom\n}", "boom", 4, 5},
+		{"inside proc", "proc f {} {\n    expr {1 / 0}\n}\nf", "divide by zero", 2, 5},
+		{"wrong args", "proc f {a} {}\nf", `wrong # args: should be "f a"`, 2, 1},
+		{"missing brace", "set x {\nabc", "missing close-brace", 1, 7},
+		{"missing bracket", "set x [list a", "missing close-bracket", 1, 7},
+		{"break outside loop", "break", `invoked "break" outside of a loop`, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tcl.New().Eval(tt.script)
+			var e *tcl.Error
+			if !errors.As(err, &e) {
+				t.Fatalf("expected *tcl.Error, got %T: %v", err, err)
+			}
+			if e.Message != tt.message {
+				t.Errorf("message = %q, want %q", e.Message, tt.message)
+			}
+			if e.Line != tt.line || e.Column != tt.column {
+				t.Errorf("position = %d:%d, want %d:%d", e.Line, e.Column, tt.line, tt.column)
+			}
+		})
+	}
+}
+
+func TestPuts(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	interp := tcl.New()
+	interp.Stdout = &stdout
+	interp.Stderr = &stderr
+
+	if _, err := interp.Eval("puts hello\nputs -nonewline stdout world\nputs stderr oops"); err != nil {
+		t.Fatalf("Eval error: %v", err)
+	}
+	if got := stdout.String(); got != "hello\nworld" {
+		t.Errorf("stdout = %q", got)
+	}
+	if got := stderr.String(); got != "oops\n" {
+		t.Errorf("stderr = %q", got)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	interp := tcl.New()
+	interp.Register("greet", func(i *tcl.Interp, args []string) (string, error) {
+		if len(args) != 2 {
+			return "", tcl.WrongArgs(args[0], "name")
+		}
+		return "hello " + args[1], nil
+	})
+
+	got, err := interp.Eval(`greet [string totitle world]`)
+	if err != nil {
+		t.Fatalf("Eval error: %v", err)
+	}
+	if got != "hello World" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestReset(t *testing.T) {
+	var stdout bytes.Buffer
+	interp := tcl.New()
+	interp.Stdout = &stdout
+	interp.MaxCommands = 10
+	interp.Register("greet", func(i *tcl.Interp, args []string) (string, error) {
+		return "hello", nil
+	})
+	if _, err := interp.Eval(`set x 1; proc double {n} {expr {$n * 2}}; rename list {}; puts [greet]`); err != nil {
+		t.Fatalf("Eval error: %v", err)
+	}
+
+	interp.Reset()
+	got, err := interp.Eval(`list [info exists x] [info commands double] [info commands greet] [list a b]`)
+	if err != nil {
+		t.Fatalf("Eval after Reset: %v", err)
+	}
+	if got != "0 {} {} {a b}" {
+		t.Errorf("got %q, want variables, procs and commands reset", got)
+	}
+	if _, err := interp.Eval(`for {set i 0} {$i < 100} {incr i} {}; puts again`); err != nil {
+		t.Errorf("MaxCommands survived Reset: %v", err)
+	}
+	if stdout.String() != "hello\n" {
+		t.Errorf("stdout = %q, want output after Reset discarded", stdout.String())
+	}
+}
+
+func TestEvalContextCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// catch must not be able to swallow cancellation.
+	_, err := tcl.New().EvalContext(ctx, `while 1 { catch { incr n } }`)
+	if !errors.Is(err, tcl.ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded in chain, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected position in error, got %v", err)
+	}
+}
+
+// Loops without commands in their body are cancelled too.
+func TestEvalContextCancelEmptyLoop(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := tcl.New().EvalContext(ctx, `while 1 {}`)
+	if !errors.Is(err, tcl.ErrCancelled) {
+		t.Fatalf("expected ErrCancelled, got %v", err)
+	}
+}
+
+func TestMaxCommands(t *testing.T) {
+	interp := tcl.New()
+	interp.MaxCommands = 1000
+
+	// catch must not be able to swallow the limit either.
+	_, err := interp.Eval(`while 1 { catch { incr n } }`)
+	if !errors.Is(err, tcl.ErrCommandLimit) {
+		t.Fatalf("expected ErrCommandLimit, got %v", err)
+	}
+
+	// The budget is per evaluation.
+	if _, err := interp.Eval(`set x 1`); err != nil {
+		t.Errorf("second evaluation: %v", err)
+	}
+}
+
+func TestMaxValue(t *testing.T) {
+	scripts := []string{
+		`string repeat a 99999999999`,
+		`lrepeat 99999999999 a`,
+		`set s a; while 1 { append s $s }`,
+		`set s a; while 1 { set s $s$s }`,
+		`set l a; while 1 { lappend l $l }`,
+		`join [lrepeat 100 [string repeat a 1000]] [string repeat b 1000]`,
+		`format %999999999s x`,
+		`format %.999999999f 1`,
+		`format %*s -999999999 x`,
+		`string map {a aaaaaaaaaa} [string repeat a 10000]`,
+		`set l a; while 1 { set l [concat $l $l] }`,
+		`set s a; while 1 { set s [string cat $s $s] }`,
+		`set l a; while 1 { set l [list $l $l] }`,
+		`set l a; while 1 { set l [lsort [list {*}$l {*}$l]] }`,
+		`set l a; while 1 { set l [lrange [list $l $l] 0 end] }`,
+		`set l a; while 1 { set l [linsert $l 0 $l] }`,
+		`set l a; while 1 { set l [lreplace $l 0 0 $l $l] }`,
+		`set l a; while 1 { set l [lsearch -inline -all [list $l $l] *] }`,
+		`set l a; while 1 { set l [lmap x {1 2} { set l }] }`,
+		`set l a; while 1 { set l [eval list [list $l] [list $l]] }`,
+		`set a(x) a; while 1 { set a(y) [array get a] }`,
+		// catch must not be able to swallow the limit either.
+		`catch { string repeat a 99999999999 }; set x 1`,
+	}
+	for _, src := range scripts {
+		interp := tcl.New()
+		interp.MaxValue = 1 << 16
+		if _, err := interp.Eval(src); !errors.Is(err, tcl.ErrMemoryLimit) {
+			t.Errorf("%s: expected ErrMemoryLimit, got %v", src, err)
+		}
+		// Hitting the limit doesn't outlast the evaluation.
+		if _, err := interp.Eval(`catch { error oops }`); err != nil {
+			t.Errorf("%s: second evaluation: %v", src, err)
+		}
+	}
+}
+
+func TestListRoundTrip(t *testing.T) {
+	elems := []string{"", "a b", "{", "}", `"q"`, "$x", "[cmd]", `back\slash`, "#hash", "tab\there", "line\nbreak"}
+	got, err := tcl.SplitList(tcl.FormatList(elems))
+	if err != nil {
+		t.Fatalf("SplitList error: %v", err)
+	}
+	if len(got) != len(elems) {
+		t.Fatalf("got %d elements, want %d: %q", len(got), len(elems), got)
+	}
+	for n := range elems {
+		if got[n] != elems[n] {
+			t.Errorf("element %d = %q, want %q", n, got[n], elems[n])
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package tcl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SplitList parses a Tcl list into its elements.
+func SplitList(s string) ([]string, error) {
+	var elems []string
+	i := 0
+	for {
+		for i < len(s) && isListSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			return elems, nil
+		}
+		switch s[i] {
+		case '{':
+			depth, start := 0, i
+			for ; i < len(s); i++ {
+				if s[i] == '\\' {
+					i++
+					continue
+				}
+				if s[i] == '{' {
+					depth++
+				} else if s[i] == '}' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unmatched open brace in list")
+			}
+			elems = append(elems, s[start+1:i])
+			i++
+			if i < len(s) && !isListSpace(s[i]) {
+				return nil, fmt.Errorf("list element in braces followed by \"%s\" instead of space", s[i:i+1])
+			}
+		case '"':
+			var b strings.Builder
+			i++
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' {
+					t, n := backslash(s[i:])
+					b.WriteString(t)
+					i += n
+					continue
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unmatched open quote in list")
+			}
+			elems = append(elems, b.String())
+			i++
+			if i < len(s) && !isListSpace(s[i]) {
+				return nil, fmt.Errorf("list element in quotes followed by \"%s\" instead of space", s[i:i+1])
+			}
+		default:
+			var b strings.Builder
+			for i < len(s) && !isListSpace(s[i]) {
+				if s[i] == '\\' {
+					t, n := backslash(s[i:])
+					b.WriteString(t)
+					i += n
+					continue
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			elems = append(elems, b.String())
+		}
+	}
+}
+
+// FormatList builds a well-formed Tcl list from elems.
+func FormatList(elems []string) string {
+	var b strings.Builder
+	for n, e := range elems {
+		if n > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(quoteElement(e, n == 0))
+	}
+	return b.String()
+}
+
+// quoteElement quotes a single list element so that SplitList returns it
+// unchanged.
+func quoteElement(s string, first bool) string {
+	if s == "" {
+		return "{}"
+	}
+	plain := true
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\n', '\r', '\f', '\v', ';', '"', '$', '[', ']', '{', '}', '\\':
+			plain = false
+		}
+	}
+	if s[0] == '#' && first {
+		plain = false
+	}
+	if plain {
+		return s
+	}
+	if canBrace(s) {
+		return "{" + s + "}"
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case ' ', ';', '"', '$', '[', ']', '{', '}', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\v':
+			b.WriteString(`\v`)
+		case '#':
+			if i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// canBrace reports whether s can be quoted by enclosing it in braces.
+func canBrace(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 >= len(s) || s[i+1] == '\n' {
+				return false
+			}
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// Dict is an ordered Tcl dictionary.
+type Dict struct {
+	keys []string
+	m    map[string]string
+}
+
+// NewDict returns an empty dictionary.
+func NewDict() *Dict {
+	return &Dict{m: make(map[string]string)}
+}
+
+// ParseDict parses a Tcl dict value.
+func ParseDict(s string) (*Dict, error) {
+	elems, err := SplitList(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(elems)%2 != 0 {
+		return nil, fmt.Errorf("missing value to go with key")
+	}
+	d := &Dict{m: make(map[string]string, len(elems)/2)}
+	for n := 0; n < len(elems); n += 2 {
+		d.Set(elems[n], elems[n+1])
+	}
+	return d, nil
+}
+
+// Get returns the value stored under key.
+func (d *Dict) Get(key string) (string, bool) {
+	v, ok := d.m[key]
+	return v, ok
+}
+
+// Set stores value under key, keeping the original position of
+// existing keys.
+func (d *Dict) Set(key, value string) {
+	if _, ok := d.m[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.m[key] = value
+}
+
+// Delete removes key.
+func (d *Dict) Delete(key string) {
+	if _, ok := d.m[key]; !ok {
+		return
+	}
+	delete(d.m, key)
+	for n, k := range d.keys {
+		if k == key {
+			d.keys = append(d.keys[:n:n], d.keys[n+1:]...)
+			break
+		}
+	}
+}
+
+// Keys returns the keys in insertion order.
+func (d *Dict) Keys() []string {
+	return d.keys
+}
+
+// Len returns the number of entries.
+func (d *Dict) Len() int {
+	return len(d.keys)
+}
+
+// String formats the dictionary as a Tcl list.
+func (d *Dict) String() string {
+	elems := make([]string, 0, 2*len(d.keys))
+	for _, k := range d.keys {
+		elems = append(elems, k, d.m[k])
+	}
+	return FormatList(elems)
+}
+
+// parseIndex parses a list or string index such as 3, end, end-1 or 2+1
+// against a sequence of length n.
+func parseIndex(s string, n int) (int, error) {
+	t := strings.TrimSpace(s)
+	base := 0
+	if strings.HasPrefix(t, "end") {
+		base = n - 1
+		t = t[3:]
+		if t == "" {
+			return base, nil
+		}
+	}
+	if v, err := strconv.Atoi(t); err == nil {
+		if base != 0 || strings.HasPrefix(s, "end") {
+			if t[0] != '+' && t[0] != '-' {
+				return 0, badIndex(s)
+			}
+		}
+		return base + v, nil
+	}
+	// Forms like 1+2 or end-1-1 are rare but legal.
+	for k := 1; k < len(t); k++ {
+		if t[k] == '+' || t[k] == '-' {
+			a, errA := strconv.Atoi(t[:k])
+			b, errB := strconv.Atoi(t[k:])
+			if errA == nil && errB == nil {
+				return base + a + b, nil
+			}
+		}
+	}
+	return 0, badIndex(s)
+}
+
+func badIndex(s string) error {
+	return fmt.Errorf("bad index \"%s\": must be integer?[+-]integer? or end?[+-]integer?", s)
+}
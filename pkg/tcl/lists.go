@@ -0,0 +1,466 @@
+package tcl
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func registerLists(i *Interp) {
+	for name, fn := range map[string]CommandFunc{
+		"list":     cmdList,
+		"llength":  cmdLlength,
+		"lindex":   cmdLindex,
+		"lrange":   cmdLrange,
+		"lappend":  cmdLappend,
+		"linsert":  cmdLinsert,
+		"lreplace": cmdLreplace,
+		"lsearch":  cmdLsearch,
+		"lsort":    cmdLsort,
+		"lreverse": cmdLreverse,
+		"lassign":  cmdLassign,
+		"lset":     cmdLset,
+		"lrepeat":  cmdLrepeat,
+	} {
+		i.Register(name, fn)
+	}
+}
+
+func cmdList(i *Interp, args []string) (string, error) {
+	if err := i.checkList(args[1:]); err != nil {
+		return "", err
+	}
+	return FormatList(args[1:]), nil
+}
+
+func cmdLlength(i *Interp, args []string) (string, error) {
+	if len(args) != 2 {
+		return "", WrongArgs(args[0], "list")
+	}
+	elems, err := SplitList(args[1])
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(len(elems)), nil
+}
+
+func cmdLindex(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "list ?index ...?")
+	}
+	indices := args[2:]
+	if len(indices) == 1 {
+		var err error
+		if indices, err = SplitList(indices[0]); err != nil {
+			return "", err
+		}
+	}
+	v := args[1]
+	for _, idx := range indices {
+		elems, err := SplitList(v)
+		if err != nil {
+			return "", err
+		}
+		n, err := parseIndex(idx, len(elems))
+		if err != nil {
+			return "", err
+		}
+		if n < 0 || n >= len(elems) {
+			return "", nil
+		}
+		v = elems[n]
+	}
+	return v, nil
+}
+
+func cmdLrange(i *Interp, args []string) (string, error) {
+	if len(args) != 4 {
+		return "", WrongArgs(args[0], "list first last")
+	}
+	elems, err := SplitList(args[1])
+	if err != nil {
+		return "", err
+	}
+	first, err := parseIndex(args[2], len(elems))
+	if err != nil {
+		return "", err
+	}
+	last, err := parseIndex(args[3], len(elems))
+	if err != nil {
+		return "", err
+	}
+	first, last = max(first, 0), min(last, len(elems)-1)
+	if first > last {
+		return "", nil
+	}
+	return FormatList(elems[first : last+1]), nil
+}
+
+func cmdLappend(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "varName ?value ...?")
+	}
+	var elems []string
+	var size int64
+	if i.VarExists(args[1]) {
+		cur, err := i.GetVar(args[1])
+		if err != nil {
+			return "", err
+		}
+		if elems, err = SplitList(cur); err != nil {
+			return "", err
+		}
+		size = int64(len(cur))
+	}
+	// Each new element adds itself and a separator
+	for _, a := range args[2:] {
+		size += int64(len(a)) + 1
+	}
+	if err := i.checkSize(size); err != nil {
+		return "", err
+	}
+	res := FormatList(append(elems, args[2:]...))
+	return res, i.SetVar(args[1], res)
+}
+
+func cmdLinsert(i *Interp, args []string) (string, error) {
+	if len(args) < 3 {
+		return "", WrongArgs(args[0], "list index ?element ...?")
+	}
+	elems, err := SplitList(args[1])
+	if err != nil {
+		return "", err
+	}
+	n, err := parseIndex(args[2], len(elems)+1)
+	if err != nil {
+		return "", err
+	}
+	n = min(max(n, 0), len(elems))
+	out := append([]string{}, elems[:n]...)
+	out = append(out, args[3:]...)
+	out = append(out, elems[n:]...)
+	if err := i.checkList(out); err != nil {
+		return "", err
+	}
+	return FormatList(out), nil
+}
+
+func cmdLreplace(i *Interp, args []string) (string, error) {
+	if len(args) < 4 {
+		return "", WrongArgs(args[0], "list first last ?element ...?")
+	}
+	elems, err := SplitList(args[1])
+	if err != nil {
+		return "", err
+	}
+	first, err := parseIndex(args[2], len(elems))
+	if err != nil {
+		return "", err
+	}
+	last, err := parseIndex(args[3], len(elems))
+	if err != nil {
+		return "", err
+	}
+	first = min(max(first, 0), len(elems))
+	last = min(last, len(elems)-1)
+	if last < first {
+		last = first - 1
+	}
+	out := append([]string{}, elems[:first]...)
+	out = append(out, args[4:]...)
+	out = append(out, elems[last+1:]...)
+	if err := i.checkList(out); err != nil {
+		return "", err
+	}
+	return FormatList(out), nil
+}
+
+func cmdLsearch(i *Interp, args []string) (string, error) {
+	a := args[1:]
+	mode := "-glob"
+	all, inline, not, nocase := false, false, false, false
+	for len(a) > 2 && strings.HasPrefix(a[0], "-") {
+		switch a[0] {
+		case "-exact", "-glob", "-regexp":
+			mode = a[0]
+		case "-all":
+			all = true
+		case "-inline":
+			inline = true
+		case "-not":
+			not = true
+		case "-nocase":
+			nocase = true
+		case "--":
+			a = a[1:]
+			goto done
+		default:
+			return "", fmt.Errorf("bad option \"%s\": must be -all, -exact, -glob, -inline, -nocase, -not, or -regexp", a[0])
+		}
+		a = a[1:]
+	}
+done:
+	if len(a) != 2 {
+		return "", WrongArgs(args[0], "?-option value ...? list pattern")
+	}
+	elems, err := SplitList(a[0])
+	if err != nil {
+		return "", err
+	}
+	var found []string
+	for n, e := range elems {
+		ok, err := switchMatch(mode, nocase, a[1], e)
+		if err != nil {
+			return "", err
+		}
+		if ok == not {
+			continue
+		}
+		res := strconv.Itoa(n)
+		if inline {
+			res = e
+		}
+		if !all {
+			return res, nil
+		}
+		found = append(found, res)
+	}
+	if all {
+		return FormatList(found), nil
+	}
+	if inline {
+		return "", nil
+	}
+	return "-1", nil
+}
+
+func cmdLsort(i *Interp, args []string) (string, error) {
+	a := args[1:]
+	mode, decreasing, unique, nocase := "-ascii", false, false, false
+	index := ""
+	command := ""
+	for len(a) > 1 {
+		switch a[0] {
+		case "-ascii", "-dictionary", "-integer", "-real":
+			mode = a[0]
+		case "-increasing":
+			decreasing = false
+		case "-decreasing":
+			decreasing = true
+		case "-unique":
+			unique = true
+		case "-nocase":
+			nocase = true
+		case "-index", "-command":
+			if len(a) < 3 {
+				return "", fmt.Errorf("\"%s\" option must be followed by a value", a[0])
+			}
+			if a[0] == "-index" {
+				index = a[1]
+			} else {
+				command = a[1]
+			}
+			a = a[1:]
+		default:
+			return "", fmt.Errorf("bad option \"%s\": must be -ascii, -command, -decreasing, -dictionary, -increasing, -index, -integer, -nocase, -real, or -unique", a[0])
+		}
+		a = a[1:]
+	}
+	if len(a) != 1 {
+		return "", WrongArgs(args[0], "?-option value ...? list")
+	}
+	elems, err := SplitList(a[0])
+	if err != nil {
+		return "", err
+	}
+	keys := elems
+	if index != "" {
+		keys = make([]string, len(elems))
+		for n, e := range elems {
+			if keys[n], err = cmdLindex(i, []string{"lindex", e, index}); err != nil {
+				return "", err
+			}
+		}
+	}
+	var sortErr error
+	compare := func(x, y string) int {
+		if sortErr != nil {
+			return 0
+		}
+		switch {
+		case command != "":
+			res, err := i.EvalBody(FormatList([]string{command, x, y}))
+			if err != nil {
+				sortErr = err
+				return 0
+			}
+			n, err := toInt(res)
+			if err != nil {
+				sortErr = fmt.Errorf("-compare command returned non-integer result")
+			}
+			return int(n)
+		case mode == "-integer":
+			a, err := toInt(x)
+			if err != nil {
+				sortErr = err
+			}
+			b, err := toInt(y)
+			if err != nil {
+				sortErr = err
+			}
+			return cmpOrdered(a, b)
+		case mode == "-real":
+			a, err := toFloat(x)
+			if err != nil {
+				sortErr = err
+			}
+			b, err := toFloat(y)
+			if err != nil {
+				sortErr = err
+			}
+			return cmpOrdered(a, b)
+		case nocase || mode == "-dictionary":
+			return strings.Compare(strings.ToLower(x), strings.ToLower(y))
+		}
+		return strings.Compare(x, y)
+	}
+	order := make([]int, len(elems))
+	for n := range order {
+		order[n] = n
+	}
+	sort.SliceStable(order, func(x, y int) bool {
+		c := compare(keys[order[x]], keys[order[y]])
+		if decreasing {
+			return c > 0
+		}
+		return c < 0
+	})
+	if sortErr != nil {
+		return "", sortErr
+	}
+	out := make([]string, 0, len(elems))
+	for n, idx := range order {
+		if unique && n > 0 && compare(keys[order[n-1]], keys[idx]) == 0 {
+			out[len(out)-1] = elems[idx]
+			continue
+		}
+		out = append(out, elems[idx])
+	}
+	return FormatList(out), nil
+}
+
+func cmpOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func cmdLreverse(i *Interp, args []string) (string, error) {
+	if len(args) != 2 {
+		return "", WrongArgs(args[0], "list")
+	}
+	elems, err := SplitList(args[1])
+	if err != nil {
+		return "", err
+	}
+	for x, y := 0, len(elems)-1; x < y; x, y = x+1, y-1 {
+		elems[x], elems[y] = elems[y], elems[x]
+	}
+	return FormatList(elems), nil
+}
+
+func cmdLassign(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "list ?varName ...?")
+	}
+	elems, err := SplitList(args[1])
+	if err != nil {
+		return "", err
+	}
+	for n, name := range args[2:] {
+		v := ""
+		if n < len(elems) {
+			v = elems[n]
+		}
+		if err := i.SetVar(name, v); err != nil {
+			return "", err
+		}
+	}
+	if len(elems) > len(args)-2 {
+		return FormatList(elems[len(args)-2:]), nil
+	}
+	return "", nil
+}
+
+func cmdLset(i *Interp, args []string) (string, error) {
+	if len(args) < 3 {
+		return "", WrongArgs(args[0], "listVar ?index? ?index ...? value")
+	}
+	cur, err := i.GetVar(args[1])
+	if err != nil {
+		return "", err
+	}
+	indices := args[2 : len(args)-1]
+	if len(indices) == 1 {
+		if indices, err = SplitList(indices[0]); err != nil {
+			return "", err
+		}
+	}
+	res, err := lsetPath(cur, indices, args[len(args)-1])
+	if err != nil {
+		return "", err
+	}
+	return res, i.SetVar(args[1], res)
+}
+
+func lsetPath(list string, indices []string, v string) (string, error) {
+	if len(indices) == 0 {
+		return v, nil
+	}
+	elems, err := SplitList(list)
+	if err != nil {
+		return "", err
+	}
+	n, err := parseIndex(indices[0], len(elems))
+	if err != nil {
+		return "", err
+	}
+	if n < 0 || n > len(elems) {
+		return "", fmt.Errorf("list index out of range")
+	}
+	if n == len(elems) {
+		elems = append(elems, "")
+	}
+	if elems[n], err = lsetPath(elems[n], indices[1:], v); err != nil {
+		return "", err
+	}
+	return FormatList(elems), nil
+}
+
+func cmdLrepeat(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "count ?value ...?")
+	}
+	n, err := toInt(args[1])
+	if err != nil {
+		return "", err
+	}
+	if n < 0 {
+		return "", fmt.Errorf("bad count \"%d\": must be integer >= 0", n)
+	}
+	if n > 0 && len(args) > 2 {
+		if err := i.checkSize(repeatSize(int64(len(FormatList(args[2:])))+1, n)); err != nil {
+			return "", err
+		}
+	}
+	var out []string
+	for ; n > 0; n-- {
+		out = append(out, args[2:]...)
+	}
+	return FormatList(out), nil
+}
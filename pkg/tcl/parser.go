@@ -0,0 +1,489 @@
+package tcl
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type partKind int
+
+const (
+	partText partKind = iota // literal text
+	partVar                  // $name or $name(index)
+	partCmd                  // [script]
+)
+
+// part is one piece of a word: literal text, a variable reference or a
+// command substitution.
+type part struct {
+	kind   partKind
+	text   string  // literal text or variable name
+	index  []part  // array element index, only when hasIndex is set
+	script *Script // command substitution body
+	// hasIndex marks $name(index) references
+	hasIndex bool
+}
+
+// word is a single word of a command after parsing.
+type word struct {
+	parts   []part
+	literal bool   // no substitution required, value holds the text
+	value   string // literal value
+	braced  bool   // word was enclosed in braces
+	expand  bool   // word was prefixed with {*}
+	off     int    // offset of the word content in the source
+}
+
+// command is a single parsed command within a script.
+type command struct {
+	words []word
+	off   int // offset of the first character of the command
+	end   int // offset just past the last character of the command
+}
+
+// Script is a parsed Tcl script. A Script is immutable once parsed and
+// can be evaluated any number of times, by any number of interpreters.
+type Script struct {
+	src  string
+	cmds []command
+}
+
+// Source returns the text the script was parsed from.
+func (s *Script) Source() string {
+	return s.src
+}
+
+// Parse parses src into a Script without evaluating it.
+func Parse(src string) (*Script, error) {
+	p := &parser{src: src}
+	return p.parseScript(false)
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) errorf(off int, format string, args ...any) *Error {
+	line, col := position(p.src, off)
+	return &Error{
+		Message: sprintf(format, args...),
+		Line:    line,
+		Column:  col,
+	}
+}
+
+// parseScript parses commands until the end of input, or until the closing
+// bracket when parsing a nested command substitution.
+func (p *parser) parseScript(nested bool) (*Script, error) {
+	s := &Script{src: p.src}
+	start := p.pos
+	for {
+		p.skipSeparators()
+		if p.eof() {
+			if nested {
+				return nil, p.errorf(start-1, "missing close-bracket")
+			}
+			return s, nil
+		}
+		c := p.src[p.pos]
+		if nested && c == ']' {
+			p.pos++
+			return s, nil
+		}
+		if c == '#' {
+			p.skipComment()
+			continue
+		}
+		cmd, err := p.parseCommand(nested)
+		if err != nil {
+			return nil, err
+		}
+		if len(cmd.words) > 0 {
+			s.cmds = append(s.cmds, cmd)
+		}
+	}
+}
+
+// skipSeparators skips whitespace and command separators between commands.
+func (p *parser) skipSeparators() {
+	for !p.eof() {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ';' || c == '\f' || c == '\v':
+			p.pos++
+		case c == '\\' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '\n':
+			p.pos += 2
+		default:
+			return
+		}
+	}
+}
+
+// skipSpace skips whitespace between words of a single command.
+func (p *parser) skipSpace() {
+	for !p.eof() {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			p.pos++
+		case c == '\\' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '\n':
+			p.pos += 2
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) skipComment() {
+	for !p.eof() {
+		c := p.src[p.pos]
+		if c == '\\' && p.pos+1 < len(p.src) {
+			p.pos += 2
+			continue
+		}
+		p.pos++
+		if c == '\n' {
+			return
+		}
+	}
+}
+
+func (p *parser) atCommandEnd(nested bool) bool {
+	if p.eof() {
+		return true
+	}
+	c := p.src[p.pos]
+	return c == '\n' || c == ';' || (nested && c == ']')
+}
+
+func (p *parser) parseCommand(nested bool) (command, error) {
+	cmd := command{off: p.pos}
+	for {
+		p.skipSpace()
+		if p.atCommandEnd(nested) {
+			cmd.end = p.pos
+			return cmd, nil
+		}
+		w, err := p.parseWord(nested)
+		if err != nil {
+			return cmd, err
+		}
+		cmd.words = append(cmd.words, w)
+	}
+}
+
+func (p *parser) parseWord(nested bool) (word, error) {
+	w := word{off: p.pos}
+	if strings.HasPrefix(p.src[p.pos:], "{*}") && p.pos+3 < len(p.src) && !isSpace(p.src[p.pos+3]) {
+		w.expand = true
+		p.pos += 3
+		w.off = p.pos
+	}
+	switch p.src[p.pos] {
+	case '{':
+		text, err := p.parseBraced()
+		if err != nil {
+			return w, err
+		}
+		w.off++
+		w.literal, w.braced, w.value = true, true, text
+		if err := p.checkWordEnd(nested, "close-brace"); err != nil {
+			return w, err
+		}
+		return w, nil
+	case '"':
+		p.pos++
+		w.off++
+		parts, err := p.parseParts(func(c byte) bool { return c == '"' })
+		if err != nil {
+			return w, err
+		}
+		if p.eof() {
+			return w, p.errorf(w.off-1, "missing \"")
+		}
+		p.pos++
+		w.parts = parts
+		if err := p.checkWordEnd(nested, "close-quote"); err != nil {
+			return w, err
+		}
+	default:
+		parts, err := p.parseParts(func(c byte) bool {
+			return isSpace(c) || c == '\n' || c == ';' || (nested && c == ']')
+		})
+		if err != nil {
+			return w, err
+		}
+		w.parts = parts
+	}
+	switch {
+	case len(w.parts) == 0:
+		w.literal = true
+	case len(w.parts) == 1 && w.parts[0].kind == partText:
+		w.literal, w.value, w.parts = true, w.parts[0].text, nil
+	}
+	return w, nil
+}
+
+func (p *parser) checkWordEnd(nested bool, what string) error {
+	if p.eof() {
+		return nil
+	}
+	c := p.src[p.pos]
+	if isSpace(c) || c == '\n' || c == ';' || (nested && c == ']') {
+		return nil
+	}
+	if c == '\\' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '\n' {
+		return nil
+	}
+	return p.errorf(p.pos, "extra characters after %s", what)
+}
+
+// parseBraced parses a {braced} word and returns its literal content.
+// Backslash-newline sequences are the only substitution performed.
+func (p *parser) parseBraced() (string, error) {
+	start := p.pos
+	depth := 0
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				text := p.src[start+1 : p.pos]
+				p.pos++
+				if strings.Contains(text, "\\\n") {
+					text = collapseLineContinuations(text)
+				}
+				return text, nil
+			}
+		}
+		p.pos++
+	}
+	return "", p.errorf(start, "missing close-brace")
+}
+
+// parseParts parses literal text, variable references, command
+// substitutions and backslash sequences until stop reports true or the
+// input ends.
+func (p *parser) parseParts(stop func(c byte) bool) ([]part, error) {
+	var parts []part
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			parts = append(parts, part{kind: partText, text: text.String()})
+			text.Reset()
+		}
+	}
+	for !p.eof() {
+		c := p.src[p.pos]
+		if stop(c) {
+			break
+		}
+		switch c {
+		case '\\':
+			s, n := backslash(p.src[p.pos:])
+			text.WriteString(s)
+			p.pos += n
+		case '$':
+			v, ok, err := p.parseVar()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				text.WriteByte('$')
+				p.pos++
+				continue
+			}
+			flush()
+			parts = append(parts, v)
+		case '[':
+			p.pos++
+			s, err := p.parseScript(true)
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			parts = append(parts, part{kind: partCmd, script: s})
+		default:
+			text.WriteByte(c)
+			p.pos++
+		}
+	}
+	flush()
+	return parts, nil
+}
+
+// parseVar parses a variable reference at p.pos, which must be a '$'.
+// ok is false when the dollar sign is not followed by a variable name.
+func (p *parser) parseVar() (v part, ok bool, err error) {
+	start := p.pos
+	p.pos++
+	if p.eof() {
+		p.pos = start
+		return v, false, nil
+	}
+	if p.src[p.pos] == '{' {
+		end := strings.IndexByte(p.src[p.pos:], '}')
+		if end < 0 {
+			return v, false, p.errorf(start, "missing close-brace for variable name")
+		}
+		name := p.src[p.pos+1 : p.pos+end]
+		p.pos += end + 1
+		return part{kind: partVar, text: name}, true, nil
+	}
+	nameStart := p.pos
+	for !p.eof() {
+		c := p.src[p.pos]
+		if isVarChar(c) {
+			p.pos++
+			continue
+		}
+		if c == ':' && p.pos+1 < len(p.src) && p.src[p.pos+1] == ':' {
+			p.pos += 2
+			for !p.eof() && p.src[p.pos] == ':' {
+				p.pos++
+			}
+			continue
+		}
+		break
+	}
+	name := p.src[nameStart:p.pos]
+	if p.eof() || p.src[p.pos] != '(' {
+		if name == "" {
+			p.pos = start
+			return v, false, nil
+		}
+		return part{kind: partVar, text: name}, true, nil
+	}
+	// Array element reference; the index undergoes substitution.
+	p.pos++
+	idxStart := p.pos
+	index, err := p.parseParts(func(c byte) bool { return c == ')' })
+	if err != nil {
+		return v, false, err
+	}
+	if p.eof() {
+		return v, false, p.errorf(idxStart-1, "missing )")
+	}
+	p.pos++
+	return part{kind: partVar, text: name, index: index, hasIndex: true}, true, nil
+}
+
+func isVarChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isListSpace(c byte) bool {
+	return isSpace(c) || c == '\n'
+}
+
+// collapseLineContinuations replaces each backslash-newline and the
+// whitespace that follows it with a single space.
+func collapseLineContinuations(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			if s[i+1] == '\n' {
+				i += 2
+				for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+					i++
+				}
+				i--
+				b.WriteByte(' ')
+				continue
+			}
+			b.WriteByte(s[i])
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// backslash decodes the backslash sequence at the start of s, returning the
+// substituted text and the number of bytes consumed.
+func backslash(s string) (string, int) {
+	if len(s) < 2 {
+		return "\\", 1
+	}
+	switch c := s[1]; c {
+	case 'a':
+		return "\a", 2
+	case 'b':
+		return "\b", 2
+	case 'f':
+		return "\f", 2
+	case 'n':
+		return "\n", 2
+	case 'r':
+		return "\r", 2
+	case 't':
+		return "\t", 2
+	case 'v':
+		return "\v", 2
+	case '\n':
+		n := 2
+		for n < len(s) && (s[n] == ' ' || s[n] == '\t') {
+			n++
+		}
+		return " ", n
+	case 'x':
+		return hexEscape(s, 2, 2)
+	case 'u':
+		return hexEscape(s, 2, 4)
+	case 'U':
+		return hexEscape(s, 2, 8)
+	default:
+		if c >= '0' && c <= '7' {
+			n := 1
+			for n < 4 && n < len(s) && s[n] >= '0' && s[n] <= '7' {
+				n++
+			}
+			v, _ := strconv.ParseUint(s[1:n], 8, 32)
+			return string(rune(v & 0xff)), n
+		}
+		r, size := utf8.DecodeRuneInString(s[1:])
+		return string(r), 1 + size
+	}
+}
+
+func hexEscape(s string, start, max int) (string, int) {
+	n := start
+	for n < len(s) && n-start < max && isHex(s[n]) {
+		n++
+	}
+	if n == start {
+		return s[1:2], 2
+	}
+	v, _ := strconv.ParseUint(s[start:n], 16, 32)
+	return string(rune(v)), n
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// position converts a byte offset in src into a 1-based line and column.
+func position(src string, off int) (line, col int) {
+	if off > len(src) {
+		off = len(src)
+	}
+	if off < 0 {
+		off = 0
+	}
+	line = 1 + strings.Count(src[:off], "\n")
+	lineStart := strings.LastIndexByte(src[:off], '\n') + 1
+	col = 1 + utf8.RuneCountInString(src[lineStart:off])
+	return line, col
+}
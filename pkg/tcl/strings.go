@@ -0,0 +1,677 @@
+package tcl
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func registerStrings(i *Interp) {
+	i.Register("string", cmdString)
+	i.Register("format", cmdFormat)
+	i.Register("join", cmdJoin)
+	i.Register("split", cmdSplit)
+	i.Register("concat", cmdConcat)
+}
+
+// Concat joins arguments the way concat and eval do: each argument is
+// trimmed and the results are joined with single spaces.
+func Concat(args []string) string {
+	parts := make([]string, 0, len(args))
+	for _, a := range args {
+		if t := strings.TrimSpace(a); t != "" {
+			parts = append(parts, t)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func cmdConcat(i *Interp, args []string) (string, error) {
+	if err := i.checkList(args[1:]); err != nil {
+		return "", err
+	}
+	return Concat(args[1:]), nil
+}
+
+func cmdJoin(i *Interp, args []string) (string, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return "", WrongArgs(args[0], "list ?joinString?")
+	}
+	elems, err := SplitList(args[1])
+	if err != nil {
+		return "", err
+	}
+	sep := " "
+	if len(args) == 3 {
+		sep = args[2]
+	}
+	size := int64(len(sep)) * int64(max(len(elems)-1, 0))
+	for _, e := range elems {
+		size += int64(len(e))
+	}
+	if err := i.checkSize(size); err != nil {
+		return "", err
+	}
+	return strings.Join(elems, sep), nil
+}
+
+func cmdSplit(i *Interp, args []string) (string, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return "", WrongArgs(args[0], "string ?splitChars?")
+	}
+	s := args[1]
+	chars := " \t\n\r"
+	if len(args) == 3 {
+		chars = args[2]
+	}
+	if s == "" {
+		return "", nil
+	}
+	if chars == "" {
+		var elems []string
+		for _, r := range s {
+			elems = append(elems, string(r))
+		}
+		return FormatList(elems), nil
+	}
+	var elems []string
+	start := 0
+	for k, r := range s {
+		if strings.ContainsRune(chars, r) {
+			elems = append(elems, s[start:k])
+			start = k + utf8.RuneLen(r)
+		}
+	}
+	elems = append(elems, s[start:])
+	return FormatList(elems), nil
+}
+
+var stringSubcommands = "cat, compare, equal, first, index, is, last, length, map, match, range, repeat, replace, reverse, tolower, totitle, toupper, trim, trimleft, or trimright"
+
+func cmdString(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "subcommand ?arg ...?")
+	}
+	sub, a := args[1], args[2:]
+	usage := func(u string) error { return WrongArgs("string "+sub, u) }
+	switch sub {
+	case "length":
+		if len(a) != 1 {
+			return "", usage("string")
+		}
+		return strconv.Itoa(utf8.RuneCountInString(a[0])), nil
+	case "cat":
+		var size int64
+		for _, s := range a {
+			size += int64(len(s))
+		}
+		if err := i.checkSize(size); err != nil {
+			return "", err
+		}
+		return strings.Join(a, ""), nil
+	case "index":
+		if len(a) != 2 {
+			return "", usage("string charIndex")
+		}
+		r := []rune(a[0])
+		idx, err := parseIndex(a[1], len(r))
+		if err != nil {
+			return "", err
+		}
+		if idx < 0 || idx >= len(r) {
+			return "", nil
+		}
+		return string(r[idx]), nil
+	case "range":
+		if len(a) != 3 {
+			return "", usage("string first last")
+		}
+		r := []rune(a[0])
+		first, err := parseIndex(a[1], len(r))
+		if err != nil {
+			return "", err
+		}
+		last, err := parseIndex(a[2], len(r))
+		if err != nil {
+			return "", err
+		}
+		first, last = max(first, 0), min(last, len(r)-1)
+		if first > last {
+			return "", nil
+		}
+		return string(r[first : last+1]), nil
+	case "first", "last":
+		if len(a) != 2 && len(a) != 3 {
+			return "", usage("needleString haystackString ?startIndex?")
+		}
+		hay := []rune(a[1])
+		needle := []rune(a[0])
+		start := 0
+		if sub == "last" {
+			start = len(hay) - 1
+		}
+		if len(a) == 3 {
+			var err error
+			if start, err = parseIndex(a[2], len(hay)); err != nil {
+				return "", err
+			}
+		}
+		return strconv.Itoa(runeIndex(hay, needle, start, sub == "last")), nil
+	case "tolower", "toupper", "totitle":
+		if len(a) != 1 {
+			return "", usage("string")
+		}
+		switch sub {
+		case "tolower":
+			return strings.ToLower(a[0]), nil
+		case "toupper":
+			return strings.ToUpper(a[0]), nil
+		}
+		r := []rune(strings.ToLower(a[0]))
+		if len(r) > 0 {
+			r[0] = unicode.ToTitle(r[0])
+		}
+		return string(r), nil
+	case "trim", "trimleft", "trimright":
+		if len(a) != 1 && len(a) != 2 {
+			return "", usage("string ?chars?")
+		}
+		chars := " \t\n\r\v\f\x00"
+		if len(a) == 2 {
+			chars = a[1]
+		}
+		switch sub {
+		case "trimleft":
+			return strings.TrimLeft(a[0], chars), nil
+		case "trimright":
+			return strings.TrimRight(a[0], chars), nil
+		}
+		return strings.Trim(a[0], chars), nil
+	case "equal", "compare":
+		nocase, length := false, -1
+		for len(a) > 2 {
+			switch a[0] {
+			case "-nocase":
+				nocase = true
+				a = a[1:]
+			case "-length":
+				n, err := toInt(a[1])
+				if err != nil {
+					return "", err
+				}
+				length = int(n)
+				a = a[2:]
+			default:
+				return "", fmt.Errorf("bad option \"%s\": must be -nocase or -length", a[0])
+			}
+		}
+		if len(a) != 2 {
+			return "", usage("?-nocase? ?-length int? string1 string2")
+		}
+		x, y := a[0], a[1]
+		if length >= 0 {
+			x, y = runePrefix(x, length), runePrefix(y, length)
+		}
+		if nocase {
+			x, y = strings.ToLower(x), strings.ToLower(y)
+		}
+		if sub == "equal" {
+			return boolString(x == y), nil
+		}
+		return strconv.Itoa(strings.Compare(x, y)), nil
+	case "match":
+		nocase := false
+		if len(a) == 3 && a[0] == "-nocase" {
+			nocase = true
+			a = a[1:]
+		}
+		if len(a) != 2 {
+			return "", usage("?-nocase? pattern string")
+		}
+		return boolString(globMatch(a[0], a[1], nocase)), nil
+	case "map":
+		nocase := false
+		if len(a) == 3 && a[0] == "-nocase" {
+			nocase = true
+			a = a[1:]
+		}
+		if len(a) != 2 {
+			return "", usage("?-nocase? charMap string")
+		}
+		pairs, err := SplitList(a[0])
+		if err != nil {
+			return "", err
+		}
+		if len(pairs)%2 != 0 {
+			return "", fmt.Errorf("char map list unbalanced")
+		}
+		return stringMap(pairs, a[1], nocase, i.checkSize)
+	case "repeat":
+		if len(a) != 2 {
+			return "", usage("string count")
+		}
+		n, err := toInt(a[1])
+		if err != nil {
+			return "", err
+		}
+		if n <= 0 || a[0] == "" {
+			return "", nil
+		}
+		if err := i.checkSize(repeatSize(int64(len(a[0])), n)); err != nil {
+			return "", err
+		}
+		return strings.Repeat(a[0], int(n)), nil
+	case "reverse":
+		if len(a) != 1 {
+			return "", usage("string")
+		}
+		r := []rune(a[0])
+		for x, y := 0, len(r)-1; x < y; x, y = x+1, y-1 {
+			r[x], r[y] = r[y], r[x]
+		}
+		return string(r), nil
+	case "replace":
+		if len(a) != 3 && len(a) != 4 {
+			return "", usage("string first last ?string?")
+		}
+		r := []rune(a[0])
+		first, err := parseIndex(a[1], len(r))
+		if err != nil {
+			return "", err
+		}
+		last, err := parseIndex(a[2], len(r))
+		if err != nil {
+			return "", err
+		}
+		if first > last || first >= len(r) || last < 0 {
+			return a[0], nil
+		}
+		first, last = max(first, 0), min(last, len(r)-1)
+		repl := ""
+		if len(a) == 4 {
+			repl = a[3]
+		}
+		return string(r[:first]) + repl + string(r[last+1:]), nil
+	case "is":
+		strict := false
+		if len(a) == 3 && a[1] == "-strict" {
+			strict = true
+			a = []string{a[0], a[2]}
+		}
+		if len(a) != 2 {
+			return "", usage("class ?-strict? string")
+		}
+		return stringIs(a[0], a[1], strict)
+	}
+	return "", fmt.Errorf("unknown or ambiguous subcommand \"%s\": must be %s", sub, stringSubcommands)
+}
+
+func runePrefix(s string, n int) string {
+	r := []rune(s)
+	if n < len(r) {
+		return string(r[:n])
+	}
+	return s
+}
+
+func runeIndex(hay, needle []rune, start int, last bool) int {
+	if len(needle) == 0 {
+		return -1
+	}
+	if last {
+		for k := min(start, len(hay)-len(needle)); k >= 0; k-- {
+			if string(hay[k:k+len(needle)]) == string(needle) {
+				return k
+			}
+		}
+		return -1
+	}
+	for k := max(start, 0); k+len(needle) <= len(hay); k++ {
+		if string(hay[k:k+len(needle)]) == string(needle) {
+			return k
+		}
+	}
+	return -1
+}
+
+// repeatSize returns n times size, saturating instead of overflowing.
+func repeatSize(size, n int64) int64 {
+	if size > 0 && n > math.MaxInt64/size {
+		return math.MaxInt64
+	}
+	return size * n
+}
+
+// addSize returns a plus b, saturating instead of overflowing.
+func addSize(a, b int64) int64 {
+	if b > math.MaxInt64-a {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// stringMap replaces keys with values, scanning s once from left to
+// right and trying keys in the order given. check is called with the
+// size of the result before each replacement is written.
+func stringMap(pairs []string, s string, nocase bool, check func(int64) error) (string, error) {
+	var b strings.Builder
+	cmp := s
+	if nocase {
+		cmp = strings.ToLower(s)
+	}
+outer:
+	for k := 0; k < len(s); {
+		for n := 0; n < len(pairs); n += 2 {
+			key := pairs[n]
+			if nocase {
+				key = strings.ToLower(key)
+			}
+			if key != "" && strings.HasPrefix(cmp[k:], key) {
+				if err := check(int64(b.Len() + len(pairs[n+1]))); err != nil {
+					return "", err
+				}
+				b.WriteString(pairs[n+1])
+				k += len(key)
+				continue outer
+			}
+		}
+		_, size := utf8.DecodeRuneInString(s[k:])
+		b.WriteString(s[k : k+size])
+		k += size
+	}
+	return b.String(), nil
+}
+
+func stringIs(class, s string, strict bool) (string, error) {
+	if s == "" {
+		return boolString(!strict), nil
+	}
+	all := func(f func(rune) bool) string {
+		for _, r := range s {
+			if !f(r) {
+				return "0"
+			}
+		}
+		return "1"
+	}
+	switch class {
+	case "integer", "wide", "entier":
+		_, ok := ParseInt(s)
+		return boolString(ok), nil
+	case "double":
+		_, ok := ParseFloat(s)
+		return boolString(ok), nil
+	case "boolean":
+		_, ok := ParseBool(s)
+		if _, isNum := ParseFloat(s); isNum {
+			ok = false
+		}
+		return boolString(ok), nil
+	case "true", "false":
+		b, ok := ParseBool(s)
+		return boolString(ok && b == (class == "true")), nil
+	case "alpha":
+		return all(unicode.IsLetter), nil
+	case "digit":
+		return all(unicode.IsDigit), nil
+	case "alnum":
+		return all(func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }), nil
+	case "space":
+		return all(unicode.IsSpace), nil
+	case "upper":
+		return all(unicode.IsUpper), nil
+	case "lower":
+		return all(unicode.IsLower), nil
+	case "punct":
+		return all(unicode.IsPunct), nil
+	case "xdigit":
+		return all(func(r rune) bool { return r < utf8.RuneSelf && isHex(byte(r)) }), nil
+	case "ascii":
+		return all(func(r rune) bool { return r < utf8.RuneSelf }), nil
+	case "list":
+		_, err := SplitList(s)
+		return boolString(err == nil), nil
+	case "dict":
+		_, err := ParseDict(s)
+		return boolString(err == nil), nil
+	}
+	return "", fmt.Errorf("bad class \"%s\": must be alnum, alpha, ascii, boolean, dict, digit, double, entier, false, integer, list, lower, punct, space, true, upper, wide, or xdigit", class)
+}
+
+// Match reports whether s matches pattern by the rules of string match
+func Match(pattern, s string) bool {
+	return globMatch(pattern, s, false)
+}
+
+// globMatch implements Tcl's string match: * and ? wildcards, [chars]
+// sets and ranges, and backslash escapes.
+func globMatch(pattern, s string, nocase bool) bool {
+	if nocase {
+		pattern, s = strings.ToLower(pattern), strings.ToLower(s)
+	}
+	p, str := []rune(pattern), []rune(s)
+	return globRunes(p, str)
+}
+
+func globRunes(p, s []rune) bool {
+	for len(p) > 0 {
+		switch p[0] {
+		case '*':
+			for len(p) > 0 && p[0] == '*' {
+				p = p[1:]
+			}
+			if len(p) == 0 {
+				return true
+			}
+			for k := 0; k <= len(s); k++ {
+				if globRunes(p, s[k:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end := 1
+			matched := false
+			for end < len(p) && p[end] != ']' {
+				lo := p[end]
+				hi := lo
+				if end+2 < len(p) && p[end+1] == '-' && p[end+2] != ']' {
+					hi = p[end+2]
+					end += 2
+				}
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				if s[0] >= lo && s[0] <= hi {
+					matched = true
+				}
+				end++
+			}
+			if !matched {
+				return false
+			}
+			p = p[min(end, len(p)-1):]
+		case '\\':
+			if len(p) > 1 {
+				p = p[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != p[0] {
+				return false
+			}
+		}
+		p, s = p[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// compileRegexp compiles a Tcl regular expression with Go's RE2 engine.
+func compileRegexp(pattern string, nocase bool) (*regexp.Regexp, error) {
+	if nocase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't compile regular expression pattern: %v", err)
+	}
+	return re, nil
+}
+
+func cmdFormat(i *Interp, args []string) (string, error) {
+	if len(args) < 2 {
+		return "", WrongArgs(args[0], "formatString ?arg ...?")
+	}
+	return formatValue(args[1], args[2:], i.checkSize)
+}
+
+// Format implements the format command: printf style conversions with
+// Tcl's argument coercion rules.
+func Format(format string, args []string) (string, error) {
+	return formatValue(format, args, func(int64) error { return nil })
+}
+
+// formatValue implements Format. check is called with the size of the
+// result before each conversion is written, with field widths and
+// precisions counted in.
+func formatValue(format string, args []string, check func(int64) error) (string, error) {
+	var b strings.Builder
+	next := 0
+	nextArg := func() (string, error) {
+		if next >= len(args) {
+			return "", fmt.Errorf("not enough arguments for all format specifiers")
+		}
+		next++
+		return args[next-1], nil
+	}
+	for k := 0; k < len(format); k++ {
+		c := format[k]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		k++
+		if k >= len(format) {
+			return "", fmt.Errorf("format string ended in middle of field specifier")
+		}
+		if format[k] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		spec := "%"
+		// fields adds up the width and precision of the conversion
+		var fields int64
+		for k < len(format) && strings.IndexByte("-+ 0#", format[k]) >= 0 {
+			spec += format[k : k+1]
+			k++
+		}
+		for _, field := range []bool{false, true} {
+			if field {
+				if k >= len(format) || format[k] != '.' {
+					break
+				}
+				spec += "."
+				k++
+			}
+			if k < len(format) && format[k] == '*' {
+				a, err := nextArg()
+				if err != nil {
+					return "", err
+				}
+				n, err := toInt(a)
+				if err != nil {
+					return "", err
+				}
+				spec += strconv.FormatInt(n, 10)
+				// A negative width left-justifies the field
+				if n < 0 {
+					n = -max(n, -math.MaxInt64)
+				}
+				fields = addSize(fields, n)
+				k++
+				continue
+			}
+			start := k
+			for k < len(format) && format[k] >= '0' && format[k] <= '9' {
+				spec += format[k : k+1]
+				k++
+			}
+			if k > start {
+				n, err := strconv.ParseInt(format[start:k], 10, 64)
+				if err != nil {
+					n = math.MaxInt64
+				}
+				fields = addSize(fields, n)
+			}
+		}
+		// Size modifiers have no meaning for 64-bit Go integers.
+		for k < len(format) && strings.IndexByte("hlLjzt", format[k]) >= 0 {
+			k++
+		}
+		if k >= len(format) {
+			return "", fmt.Errorf("format string ended in middle of field specifier")
+		}
+		verb := format[k]
+		a, err := nextArg()
+		if err != nil {
+			return "", err
+		}
+		if err := check(addSize(int64(b.Len()+len(a)), fields)); err != nil {
+			return "", err
+		}
+		switch verb {
+		case 'd', 'i', 'u':
+			n, err := formatInt(a)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(fmt.Sprintf(spec+"d", n))
+		case 'x', 'X', 'o', 'b':
+			n, err := formatInt(a)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(fmt.Sprintf(spec+string(verb), n))
+		case 'c':
+			n, err := toInt(a)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(fmt.Sprintf(spec+"c", rune(n)))
+		case 's':
+			b.WriteString(fmt.Sprintf(spec+"s", a))
+		case 'f', 'e', 'E', 'g', 'G':
+			f, err := toFloat(a)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(fmt.Sprintf(spec+string(verb), f))
+		default:
+			return "", fmt.Errorf("bad field specifier \"%c\"", verb)
+		}
+	}
+	return b.String(), nil
+}
+
+// formatInt accepts integers and, like Tcl, floating point values that
+// are truncated toward zero.
+func formatInt(s string) (int64, error) {
+	if n, ok := ParseInt(s); ok {
+		return n, nil
+	}
+	if f, ok := ParseFloat(s); ok {
+		return int64(f), nil
+	}
+	return 0, fmt.Errorf("expected integer but got \"%s\"", s)
+}
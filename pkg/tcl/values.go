@@ -0,0 +1,133 @@
+package tcl
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseInt parses a Tcl integer: an optional sign followed by decimal
+// digits or a 0x, 0o or 0b prefixed number. Surrounding whitespace is
+// ignored.
+func ParseInt(s string) (int64, bool) {
+	t := strings.TrimSpace(s)
+	if t == "" {
+		return 0, false
+	}
+	neg := false
+	switch t[0] {
+	case '-':
+		neg = true
+		t = t[1:]
+	case '+':
+		t = t[1:]
+	}
+	base := 10
+	if len(t) > 2 && t[0] == '0' {
+		switch t[1] {
+		case 'x', 'X':
+			base, t = 16, t[2:]
+		case 'o', 'O':
+			base, t = 8, t[2:]
+		case 'b', 'B':
+			base, t = 2, t[2:]
+		}
+	}
+	if t == "" || t[0] == '+' || t[0] == '-' {
+		return 0, false
+	}
+	u, err := strconv.ParseUint(t, base, 64)
+	if err != nil || u > math.MaxInt64+1 || (u == math.MaxInt64+1 && !neg) {
+		return 0, false
+	}
+	if neg {
+		return -int64(u), true
+	}
+	return int64(u), true
+}
+
+// ParseFloat parses a Tcl floating point value.
+func ParseFloat(s string) (float64, bool) {
+	t := strings.TrimSpace(s)
+	if t == "" {
+		return 0, false
+	}
+	if i, ok := ParseInt(t); ok {
+		return float64(i), true
+	}
+	f, err := strconv.ParseFloat(t, 64)
+	if err != nil {
+		// Accept overflowing values as +/-Inf, like strtod.
+		if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+			return f, true
+		}
+		return 0, false
+	}
+	return f, true
+}
+
+// ParseBool parses a Tcl boolean: a number, or one of true, false, yes,
+// no, on and off in any case.
+func ParseBool(s string) (bool, bool) {
+	t := strings.ToLower(strings.TrimSpace(s))
+	switch t {
+	case "true", "yes", "on":
+		return true, true
+	case "false", "no", "off":
+		return false, true
+	}
+	if i, ok := ParseInt(t); ok {
+		return i != 0, true
+	}
+	if f, ok := ParseFloat(t); ok {
+		return f != 0, true
+	}
+	return false, false
+}
+
+// FormatFloat formats f the way Tcl does, always keeping a decimal point
+// or exponent so the value reads back as a float.
+func FormatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func toInt(s string) (int64, error) {
+	if i, ok := ParseInt(s); ok {
+		return i, nil
+	}
+	return 0, fmt.Errorf("expected integer but got \"%s\"", s)
+}
+
+func toFloat(s string) (float64, error) {
+	if f, ok := ParseFloat(s); ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("expected floating-point number but got \"%s\"", s)
+}
+
+func toBool(s string) (bool, error) {
+	if b, ok := ParseBool(s); ok {
+		return b, nil
+	}
+	return false, fmt.Errorf("expected boolean value but got \"%s\"", s)
+}
+
+func boolString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
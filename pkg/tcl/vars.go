@@ -0,0 +1,208 @@
+package tcl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// variable is a scalar or array variable. Links created by global and
+// upvar share the same *variable between frames.
+type variable struct {
+	value   string
+	array   map[string]string
+	isArray bool
+	defined bool
+}
+
+// frame holds the local variables of a proc invocation, or the globals.
+type frame struct {
+	vars map[string]*variable
+	proc *procedure
+	args []string
+}
+
+func newFrame() *frame {
+	return &frame{vars: make(map[string]*variable)}
+}
+
+func (i *Interp) current() *frame {
+	return i.frames[len(i.frames)-1]
+}
+
+func (i *Interp) global() *frame {
+	return i.frames[0]
+}
+
+// splitVarName splits "name(index)" into its parts.
+func splitVarName(name string) (base, index string, hasIndex bool) {
+	if strings.HasSuffix(name, ")") {
+		if open := strings.IndexByte(name, '('); open > 0 {
+			return name[:open], name[open+1 : len(name)-1], true
+		}
+	}
+	return name, "", false
+}
+
+// resolve finds the frame and name a variable reference refers to.
+func (i *Interp) resolve(name string) (*frame, string) {
+	if strings.HasPrefix(name, "::") {
+		return i.global(), strings.TrimLeft(name, ":")
+	}
+	return i.current(), name
+}
+
+func (i *Interp) lookup(name string, create bool) *variable {
+	f, name := i.resolve(name)
+	v, ok := f.vars[name]
+	if !ok && create {
+		v = &variable{}
+		f.vars[name] = v
+	}
+	return v
+}
+
+// GetVar returns the value of a variable in the current frame. name may
+// refer to an array element as "name(index)".
+func (i *Interp) GetVar(name string) (string, error) {
+	base, index, hasIndex := splitVarName(name)
+	return i.getVar(base, index, hasIndex)
+}
+
+// SetVar sets a variable in the current frame, creating it if necessary.
+func (i *Interp) SetVar(name, value string) error {
+	base, index, hasIndex := splitVarName(name)
+	return i.setVar(base, index, hasIndex, value)
+}
+
+// SetGlobal sets a global variable regardless of the current frame.
+func (i *Interp) SetGlobal(name, value string) error {
+	return i.SetVar("::"+name, value)
+}
+
+// VarExists reports whether a variable or array element is set.
+func (i *Interp) VarExists(name string) bool {
+	base, index, hasIndex := splitVarName(name)
+	v := i.lookup(base, false)
+	if v == nil || !v.defined {
+		return false
+	}
+	if !hasIndex {
+		return true
+	}
+	if !v.isArray {
+		return false
+	}
+	_, ok := v.array[index]
+	return ok
+}
+
+func (i *Interp) getVar(name, index string, hasIndex bool) (string, error) {
+	v := i.lookup(name, false)
+	if v == nil || !v.defined {
+		if hasIndex {
+			return "", fmt.Errorf("can't read \"%s(%s)\": no such variable", name, index)
+		}
+		return "", fmt.Errorf("can't read \"%s\": no such variable", name)
+	}
+	if hasIndex {
+		if !v.isArray {
+			return "", fmt.Errorf("can't read \"%s(%s)\": variable isn't array", name, index)
+		}
+		val, ok := v.array[index]
+		if !ok {
+			return "", fmt.Errorf("can't read \"%s(%s)\": no such element in array", name, index)
+		}
+		return val, nil
+	}
+	if v.isArray {
+		return "", fmt.Errorf("can't read \"%s\": variable is array", name)
+	}
+	return v.value, nil
+}
+
+func (i *Interp) setVar(name, index string, hasIndex bool, value string) error {
+	if err := i.checkSize(int64(len(value))); err != nil {
+		return err
+	}
+	v := i.lookup(name, true)
+	if hasIndex {
+		if v.defined && !v.isArray {
+			return fmt.Errorf("can't set \"%s(%s)\": variable isn't array", name, index)
+		}
+		if !v.isArray {
+			v.isArray, v.array = true, make(map[string]string)
+		}
+		v.array[index] = value
+		v.defined = true
+		return nil
+	}
+	if v.isArray {
+		return fmt.Errorf("can't set \"%s\": variable is array", name)
+	}
+	v.value, v.defined = value, true
+	return nil
+}
+
+// UnsetVar removes a variable or array element.
+func (i *Interp) UnsetVar(name string) error {
+	base, index, hasIndex := splitVarName(name)
+	f, local := i.resolve(base)
+	v, ok := f.vars[local]
+	if !ok || !v.defined {
+		return fmt.Errorf("can't unset \"%s\": no such variable", name)
+	}
+	if hasIndex {
+		if !v.isArray {
+			return fmt.Errorf("can't unset \"%s\": variable isn't array", name)
+		}
+		if _, ok := v.array[index]; !ok {
+			return fmt.Errorf("can't unset \"%s\": no such element in array", name)
+		}
+		delete(v.array, index)
+		return nil
+	}
+	// Clear the shared variable too so that links made by upvar and global
+	// observe the unset.
+	*v = variable{}
+	delete(f.vars, local)
+	return nil
+}
+
+// link makes local name in the current frame refer to the variable other
+// in frame target.
+func (i *Interp) link(target *frame, other, local string) error {
+	if _, _, hasIndex := splitVarName(local); hasIndex {
+		return fmt.Errorf("bad variable name \"%s\": can't create a scalar variable that looks like an array element", local)
+	}
+	if strings.HasPrefix(other, "::") {
+		target, other = i.global(), strings.TrimLeft(other, ":")
+	}
+	v, ok := target.vars[other]
+	if !ok {
+		v = &variable{}
+		target.vars[other] = v
+	}
+	cur := i.current()
+	if existing, ok := cur.vars[local]; ok && existing != v && existing.defined {
+		return fmt.Errorf("variable \"%s\" already exists", local)
+	}
+	cur.vars[local] = v
+	return nil
+}
+
+// varNames returns the sorted names of defined variables in f matching
+// pattern.
+func varNames(f *frame, pattern string) []string {
+	var names []string
+	for name, v := range f.vars {
+		if !v.defined {
+			continue
+		}
+		if pattern == "" || globMatch(pattern, name, false) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,68 @@
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	"github.com/ytjohn/toolmin/pkg/auth"
+	"github.com/ytjohn/toolmin/pkg/engine"
+	"github.com/ytjohn/toolmin/pkg/secrets"
+	"github.com/ytjohn/toolmin/pkg/server/middleware"
+)
+
+// NewTestAPI creates a huma test API wired with the same database, token
+// service, engine, secrets and auth middleware as the server
+func NewTestAPI(t *testing.T, db *sql.DB) (humatest.TestAPI, *auth.TokenService) {
+	t.Helper()
+
+	tokenService, err := auth.NewTokenService(db)
+	if err != nil {
+		t.Fatalf("failed to create token service: %v", err)
+	}
+
+	config := huma.DefaultConfig("toolmin", "test")
+	config.Components.SecuritySchemes = map[string]*huma.SecurityScheme{
+		"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+	}
+	secretsService, err := secrets.New(context.Background(), db, "test passphrase")
+	if err != nil {
+		t.Fatalf("failed to create secrets service: %v", err)
+	}
+
+	_, api := humatest.New(t, config)
+	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+		ctx = huma.WithValue(ctx, appdb.DbContextKey, db)
+		ctx = huma.WithValue(ctx, middleware.TokenServiceKey, tokenService)
+		ctx = huma.WithValue(ctx, middleware.EngineKey, engine.New(db, secretsService, nil))
+		ctx = huma.WithValue(ctx, middleware.SecretsKey, secretsService)
+		next(ctx)
+	})
+	api.UseMiddleware(middleware.WithAuth)
+
+	return api, tokenService
+}
+
+// CreateTestUser creates a user with the given role and returns an
+// "Authorization: Bearer ..." header to pass to humatest requests
+func CreateTestUser(t *testing.T, db *sql.DB, tokenService *auth.TokenService, role string) string {
+	t.Helper()
+
+	user, err := appdb.New(db).CreateUser(context.Background(), appdb.CreateUserParams{
+		Username: role,
+		Email:    role + "@example.com",
+		Password: "unused",
+		Role:     role,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	token, err := tokenService.CreateAccessToken(user.ID)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	return "Authorization: Bearer " + token
+}
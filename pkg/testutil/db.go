@@ -4,12 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"os"
-	"path/filepath"
-	"runtime"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
 	appdb "github.com/ytjohn/toolmin/pkg/appdb"
+	appsql "github.com/ytjohn/toolmin/pkg/appdb/sql"
 	"github.com/ytjohn/toolmin/pkg/server/middleware"
 )
 
@@ -19,24 +18,10 @@ type TestDB struct {
 	t *testing.T
 }
 
-// getProjectRoot returns the absolute path to the project root
-func getProjectRoot() (string, error) {
-	// Get the current file's path
-	_, currentFile, _, _ := runtime.Caller(0)
-	// Go up two directories (from pkg/testutil to project root)
-	projectRoot := filepath.Join(filepath.Dir(currentFile), "..", "..")
-	return filepath.Abs(projectRoot)
-}
-
 // NewTestDB creates a new test database
 func NewTestDB(t *testing.T) *TestDB {
 	t.Helper()
 
-	projectRoot, err := getProjectRoot()
-	if err != nil {
-		t.Fatalf("failed to get project root: %v", err)
-	}
-
 	// Create a temporary file for SQLite
 	f, err := os.CreateTemp("", "test-*.db")
 	if err != nil {
@@ -58,23 +43,8 @@ func NewTestDB(t *testing.T) *TestDB {
 	}
 
 	// Initialize schema
-	schema := []string{
-		"database/schema/auth.sql",
-		"database/schema/members.sql",
-		"database/schema/positions.sql",
-		"database/schema/officer_roles.sql",
-		"database/schema/ares_details.sql",
-	}
-
-	for _, file := range schema {
-		fullPath := filepath.Join(projectRoot, file)
-		content, err := os.ReadFile(fullPath)
-		if err != nil {
-			t.Fatalf("failed to read schema file %s: %v", fullPath, err)
-		}
-		if _, err := db.Exec(string(content)); err != nil {
-			t.Fatalf("failed to execute schema %s: %v", file, err)
-		}
+	if err := appsql.Migrate(db); err != nil {
+		t.Fatalf("failed to initialize schema: %v", err)
 	}
 
 	return &TestDB{DB: db, t: t}